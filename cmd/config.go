@@ -0,0 +1,119 @@
+package cmd
+
+import (
+	"danmaku-tool/cmd/flags"
+	"danmaku-tool/configs"
+	"danmaku-tool/internal/config"
+	"danmaku-tool/internal/danmaku"
+	"danmaku-tool/internal/service"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+)
+
+func configCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "config",
+		Short: "validate, show or init config file",
+	}
+	cmd.AddCommand(configValidateCmd(), configShowCmd(), configInitCmd())
+	return cmd
+}
+
+func configValidateCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:           "validate",
+		Short:         "validate config file",
+		SilenceUsage:  true,
+		SilenceErrors: true,
+	}
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		path := config.ResolvePath(flags.ConfigPath)
+		file, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		issues := config.Validate(file, config.Schema{
+			Platforms:   danmaku.GetPlatforms(),
+			Persists:    danmaku.GetSerializerTypes(),
+			MatchModes:  []string{danmaku.Equals, danmaku.Contains, danmaku.Ignore},
+			DandanModes: service.GetDandanSourceModes(),
+		})
+		var errCount int
+		for _, issue := range issues {
+			if issue.Level == config.LevelError {
+				errCount++
+			}
+			fmt.Println(issue.String())
+		}
+		if errCount > 0 {
+			return fmt.Errorf("%s: %d error(s), %d warning(s)", path, errCount, len(issues)-errCount)
+		}
+		fmt.Printf("%s: ok, %d warning(s)\n", path, len(issues))
+		return nil
+	}
+	return cmd
+}
+
+func configShowCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:           "show",
+		Short:         "show effective config with secrets masked",
+		SilenceUsage:  true,
+		SilenceErrors: true,
+	}
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		config.Init(flags.ConfigPath, flags.Debug)
+		content, err := config.MarshalMasked(config.GetConfig())
+		if err != nil {
+			return err
+		}
+		fmt.Printf("# %s\n", config.ConfPath)
+		for _, env := range config.GetConfig().EnvOverrides() {
+			fmt.Printf("# overridden by %s\n", env)
+		}
+		fmt.Print(string(content))
+		return nil
+	}
+	return cmd
+}
+
+func configInitCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:           "init [path]",
+		Short:         "write a commented config template",
+		Args:          cobra.MaximumNArgs(1),
+		SilenceUsage:  true,
+		SilenceErrors: true,
+	}
+	var force bool
+	cmd.Flags().BoolVarP(&force, "force", "f", false, "overwrite existing file")
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		path := config.ResolvePath(flags.ConfigPath)
+		if len(args) > 0 {
+			path = args[0]
+		}
+		if path == "" {
+			return fmt.Errorf("config path is unknown")
+		}
+		if _, err := os.Stat(path); err == nil && !force {
+			return fmt.Errorf("%s already exists, use --force to overwrite", path)
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return err
+		}
+		// 配置中包含cookie以及token
+		if err := os.WriteFile(path, configs.Example, 0600); err != nil {
+			return err
+		}
+		fmt.Println("config template written to", path)
+		return nil
+	}
+	return cmd
+}
+
+func init() {
+	rootCmd.AddCommand(configCmd())
+}
@@ -13,6 +13,9 @@ func Init() {
 	config.Init(flags.ConfigPath, flags.Debug)
 	// init logger
 	utils.InitLogger(flags.Debug, flags.JsonLogger)
+	if overrides := config.GetConfig().EnvOverrides(); len(overrides) > 0 {
+		utils.InfoLog("init", "config overridden by env", "env", overrides)
+	}
 	// initializers
 	for _, init := range danmaku.GetInitializers() {
 		if i, ok := init.(danmaku.Initializer); ok {
@@ -44,4 +47,12 @@ func Release() {
 			utils.ErrorLog("release", err.Error())
 		}
 	}
+	// 其余需要释放资源的initializer 例如api缓存
+	for _, init := range danmaku.GetInitializers() {
+		if re, ok := init.(danmaku.Finalizer); ok && init != any(mode) {
+			if err := re.Finalize(); err != nil {
+				utils.ErrorLog("release", err.Error())
+			}
+		}
+	}
 }
@@ -0,0 +1,93 @@
+package cmd
+
+import (
+	"danmaku-tool/internal/config"
+	"danmaku-tool/internal/danmaku"
+	"danmaku-tool/internal/refresh"
+	"danmaku-tool/internal/utils"
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+const refreshCmdC = "refresh_cmd"
+
+func refreshCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:           "refresh",
+		Short:         "re-scrape tracked danmaku on schedule and merge new comments into saved files",
+		SilenceUsage:  true,
+		SilenceErrors: true,
+	}
+	var list, force bool
+	cmd.Flags().BoolVar(&list, "list", false, "list tracked scrapes and next refresh time")
+	cmd.Flags().BoolVar(&force, "force", false, "refresh all tracked scrapes ignoring schedule")
+
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		Init()
+		store, err := refresh.Open(refresh.DefaultPath(), refresh.KindScrape)
+		if err != nil {
+			return err
+		}
+		schedule := refresh.Schedule(config.GetConfig().Refresh)
+		if list {
+			for _, t := range store.List() {
+				aired := "unknown"
+				if !t.AiredAt.IsZero() {
+					aired = t.AiredAt.Format(time.DateTime)
+				}
+				fmt.Printf("%-10s %-30s aired %s, first scraped %s, next refresh %s\n", t.Platform, t.Id,
+					aired, t.FirstSeen.Format(time.DateTime), t.Next(schedule).Format(time.DateTime))
+			}
+			return nil
+		}
+
+		now := time.Now()
+		targets := store.Due(now, schedule)
+		if force {
+			targets = store.List()
+		}
+		if len(targets) == 0 {
+			utils.InfoLog(refreshCmdC, "nothing to refresh")
+			return nil
+		}
+		danmaku.SetMergeExisting(true)
+		for _, t := range targets {
+			scraper := danmaku.GetScraper(t.Platform)
+			if scraper == nil {
+				utils.WarnLog(refreshCmdC, "platform not available, skipped", "platform", t.Platform, "id", t.Id)
+				continue
+			}
+			start := time.Now()
+			if err = scraper.Scrape(t.Id); err != nil {
+				utils.ErrorLog(refreshCmdC, err.Error(), "platform", t.Platform, "id", t.Id)
+				continue
+			}
+			store.Done(t, now, schedule)
+			utils.InfoLog(refreshCmdC, "refresh done", "platform", t.Platform, "id", t.Id, "cost_ms", time.Since(start).Milliseconds())
+		}
+		return store.Save()
+	}
+	return cmd
+}
+
+// trackScrape 记录抓取过的弹幕 用于refresh命令定时刷新
+func trackScrape(platform, id string) {
+	if !config.GetConfig().Refresh.Enable {
+		return
+	}
+	store, err := refresh.Open(refresh.DefaultPath(), refresh.KindScrape)
+	if err != nil {
+		utils.ErrorLog(refreshCmdC, err.Error())
+		return
+	}
+	store.Track(platform, id, danmaku.AirTime(platform, id), refresh.Schedule(config.GetConfig().Refresh))
+	if err = store.Save(); err != nil {
+		utils.ErrorLog(refreshCmdC, err.Error())
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(refreshCmd())
+}
@@ -0,0 +1,80 @@
+package cmd
+
+import (
+	"context"
+	"danmaku-tool/internal/config"
+	"danmaku-tool/internal/danmaku"
+	"danmaku-tool/internal/utils"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+const (
+	reloadC             = "config_reload"
+	configWatchInterval = 10 * time.Second
+)
+
+// Reload 重新加载配置文件 替换配置后重新初始化平台 解析失败则继续使用原配置
+func Reload() {
+	old, conf, err := config.Reload()
+	if err != nil {
+		utils.ErrorLog(reloadC, "config reload failed, keep current config", "error", err)
+		return
+	}
+	changes := config.Diff(old, conf)
+	if len(changes) == 0 {
+		utils.InfoLog(reloadC, "config not changed")
+		return
+	}
+	for _, c := range changes {
+		utils.InfoLog(reloadC, "config changed", "diff", c)
+	}
+	if old.Server.Port != conf.Server.Port || old.Server.Timeout != conf.Server.Timeout {
+		utils.WarnLog(reloadC, "server port and timeout changes take effect after restart")
+	}
+	danmaku.ReloadPlatforms()
+	utils.InfoLog(reloadC, "config reloaded", "changes", len(changes))
+}
+
+// watchConfig 收到 SIGHUP 或者配置文件修改时间变化时重新加载配置
+func watchConfig(ctx context.Context) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	ticker := time.NewTicker(configWatchInterval)
+	defer ticker.Stop()
+
+	lastModified := configModTime()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+			utils.InfoLog(reloadC, "SIGHUP received")
+			lastModified = configModTime()
+			Reload()
+		case <-ticker.C:
+			modified := configModTime()
+			if modified.IsZero() || modified.Equal(lastModified) {
+				continue
+			}
+			lastModified = modified
+			utils.InfoLog(reloadC, "config file modified", "path", config.ConfPath)
+			Reload()
+		}
+	}
+}
+
+func configModTime() time.Time {
+	if config.ConfPath == "" {
+		return time.Time{}
+	}
+	info, err := os.Stat(config.ConfPath)
+	if err != nil {
+		return time.Time{}
+	}
+	return info.ModTime()
+}
@@ -13,30 +13,60 @@ import (
 
 func scraperCmd() *cobra.Command {
 	cmd := &cobra.Command{
-		Use:   "scrape <id>",
-		Short: "scrape danmaku from id",
+		Use:   "scrape <id|url>",
+		Short: "scrape danmaku from id or platform url",
+		Args:  cobra.ExactArgs(1),
 	}
 
 	platform := flags.FProperty[string]{Flag: "platform", Register: &flags.PlatformCompletion{}, Options: danmaku.GetPlatforms()}
-	cmd.Flags().StringVar(&platform.Value, platform.Flag, "", `danmaku platform: 
+	cmd.Flags().StringVar(&platform.Value, platform.Flag, "", `danmaku platform, optional when scraping from url: 
 `+strings.Join(platform.Options, "\n"))
+	var force bool
+	var maxAge time.Duration
+	cmd.Flags().BoolVar(&force, "force", false, "refetch every episode and overwrite existing files")
+	cmd.Flags().DurationVar(&maxAge, "max-age", 24*time.Hour, "skip episodes fetched within this duration, see manifest.json in the save path")
 
 	cmd.RunE = func(cmd *cobra.Command, args []string) error {
 		Init()
-		id := args[0]
+		id := strings.TrimSpace(args[0])
 		if id == "" {
 			return fmt.Errorf("id is empty")
 		}
+		platformName := platform.Value
+		// 粘贴的网页链接 解析出平台以及id
+		if danmaku.IsURL(id) {
+			resolved, realId, err := danmaku.ResolveURL(id)
+			if err != nil {
+				return err
+			}
+			if platformName != "" && platformName != string(resolved) {
+				return fmt.Errorf("url belongs to %s, conflicts with --platform %s", resolved, platformName)
+			}
+			utils.InfoLog(scrapeCmdC, "url resolved", "platform", resolved, "id", realId)
+			platformName, id = string(resolved), realId
+		} else if platformName == "" {
+			return fmt.Errorf("--platform is required when scraping by id")
+		}
 
-		var p = danmaku.GetScraper(platform.Value)
+		var p = danmaku.GetScraper(platformName)
 		if p == nil {
-			return fmt.Errorf("unsupported platform: %s", platform.Value)
+			return fmt.Errorf("unsupported platform: %s", platformName)
+		}
+		// 增量抓取 跳过最近抓取过的单集 新出现的弹幕合并到已有文件
+		if force {
+			danmaku.SetSkipFresh(0)
+			danmaku.SetRewrite(true)
+		} else {
+			danmaku.SetSkipFresh(maxAge)
+			danmaku.SetMergeExisting(true)
 		}
 		start := time.Now()
 		err := p.Scrape(id)
 		utils.DebugLog(scrapeCmdC, "scrape cmd done", "cost_ms", time.Since(start).Milliseconds())
 		if err != nil {
 			utils.ErrorLog(scrapeCmdC, err.Error())
+		} else {
+			trackScrape(platformName, id)
 		}
 
 		return nil
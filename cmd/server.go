@@ -3,8 +3,12 @@ package cmd
 import (
 	"context"
 	"danmaku-tool/internal/api"
+	"danmaku-tool/internal/api/admin"
 	"danmaku-tool/internal/api/dandan"
+	"danmaku-tool/internal/api/health"
+	"danmaku-tool/internal/api/ui"
 	"danmaku-tool/internal/config"
+	"danmaku-tool/internal/metrics"
 	"danmaku-tool/internal/utils"
 	"errors"
 	"log/slog"
@@ -60,6 +64,18 @@ func serverCmd() *cobra.Command {
 			api.ResponseJSON(w, http.StatusOK, map[string]string{"version": config.Version})
 		})
 
+		// admin api
+		admin.RegisterRoute(r)
+
+		// web ui
+		ui.RegisterRoute(r)
+
+		// health check
+		health.RegisterRoute(r)
+
+		// prometheus metrics
+		r.Get("/metrics", metrics.Handler)
+
 		// dandan api
 		dandan.RegisterRoute(r)
 
@@ -71,6 +87,11 @@ func serverCmd() *cobra.Command {
 			WriteTimeout: defaultWriteTimeout * time.Second,
 		}
 
+		// 配置热加载
+		watchCtx, stopWatch := context.WithCancel(context.Background())
+		defer stopWatch()
+		go watchConfig(watchCtx)
+
 		quit := make(chan os.Signal, 1)
 		signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 		go func() {
@@ -124,6 +145,14 @@ func LoggerMiddleware(next http.Handler) http.Handler {
 
 		next.ServeHTTP(ww, r)
 
+		// 使用路由模板作为label 避免路径参数导致label数量无限增长
+		route := "unmatched"
+		if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePattern() != "" {
+			route = rctx.RoutePattern()
+		}
+		metrics.HttpRequests.Inc(r.Method, route, strconv.Itoa(ww.status))
+		metrics.HttpRequestDuration.Observe(time.Since(start).Seconds(), r.Method, route)
+
 		utils.InfoLog(webServerC, "request completed",
 			slog.String("http_method", r.Method),
 			slog.String("path", r.URL.Path),
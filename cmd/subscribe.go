@@ -0,0 +1,135 @@
+package cmd
+
+import (
+	"danmaku-tool/internal/config"
+	"danmaku-tool/internal/danmaku"
+	"danmaku-tool/internal/subscribe"
+	"danmaku-tool/internal/utils"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+const watchCmdC = "watch_cmd"
+
+func subscribeCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "subscribe <platform> <id>",
+		Short: "subscribe a series, new episodes are scraped by watch",
+		Long: `subscribe a series, new episodes are scraped by watch.
+id is the series id of the platform, same as the media id of web ui, e.g. bilibili season id.`,
+		Args:          cobra.RangeArgs(0, 2),
+		SilenceUsage:  true,
+		SilenceErrors: true,
+	}
+	var list, remove, skipExisting bool
+	cmd.Flags().BoolVar(&list, "list", false, "list subscriptions")
+	cmd.Flags().BoolVar(&remove, "remove", false, "remove subscription")
+	cmd.Flags().BoolVar(&skipExisting, "skip-existing", false, "mark published episodes as fetched, only scrape episodes published later")
+
+	cmd.ValidArgsFunction = func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if len(args) == 0 {
+			return danmaku.GetPlatforms(), cobra.ShellCompDirectiveNoFileComp
+		}
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		Init()
+		watchlist := subscribe.NewWatchlist(subscribe.DefaultPath())
+		if list {
+			subs, err := watchlist.List()
+			if err != nil {
+				return err
+			}
+			for _, s := range subs {
+				lastCheck := "never"
+				if !s.LastCheck.IsZero() {
+					lastCheck = s.LastCheck.Format(time.DateTime)
+				}
+				fmt.Printf("%-10s %-20s %s, %d episode(s) fetched, last check %s\n", s.Platform, s.Id, s.Title, len(s.Fetched), lastCheck)
+			}
+			return nil
+		}
+		if len(args) != 2 {
+			return fmt.Errorf("platform and id are required")
+		}
+		platform, id := args[0], args[1]
+		if remove {
+			found, err := watchlist.Remove(platform, id)
+			if err != nil {
+				return err
+			}
+			if !found {
+				return fmt.Errorf("%s %s is not subscribed", platform, id)
+			}
+			fmt.Printf("unsubscribed %s %s\n", platform, id)
+			return nil
+		}
+
+		media, err := subscribe.FetchMedia(platform, id)
+		if err != nil {
+			return err
+		}
+		sub := &subscribe.Subscription{Platform: platform, Id: id, Title: media.Title, AddedAt: time.Now()}
+		if skipExisting {
+			for _, ep := range media.Episodes {
+				sub.Fetched = append(sub.Fetched, ep.Id)
+			}
+		}
+		if err = watchlist.Add(sub); err != nil {
+			return err
+		}
+		fmt.Printf("subscribed %s %s: %s, %d episode(s) published\n", platform, id, media.Title, len(media.Episodes))
+		return nil
+	}
+	return cmd
+}
+
+func watchCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:           "watch",
+		Short:         "check subscriptions periodically and scrape new episodes",
+		SilenceUsage:  true,
+		SilenceErrors: true,
+	}
+	var once bool
+	var interval time.Duration
+	cmd.Flags().BoolVar(&once, "once", false, "check once and exit")
+	cmd.Flags().DurationVar(&interval, "interval", 0, "check interval, default watch.interval in config or 1h")
+
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		Init()
+		watchlist := subscribe.NewWatchlist(subscribe.DefaultPath())
+		if once {
+			count, err := watchlist.Check()
+			if err != nil {
+				return err
+			}
+			utils.InfoLog(watchCmdC, "watch check done", "new", count)
+			return nil
+		}
+		if interval <= 0 {
+			interval = subscribe.Interval(config.GetConfig().Watch)
+		}
+		stop := make(chan struct{})
+		go func() {
+			quit := make(chan os.Signal, 1)
+			signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+			<-quit
+			close(stop)
+		}()
+		utils.InfoLog(watchCmdC, "watch started", "interval", interval.String())
+		subscribe.Watch(watchlist, interval, stop)
+		return nil
+	}
+	return cmd
+}
+
+func init() {
+	rootCmd.AddCommand(subscribeCmd(), watchCmd())
+}
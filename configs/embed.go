@@ -0,0 +1,8 @@
+package configs
+
+import _ "embed"
+
+// Example 配置文件模板 用于 config init
+//
+//go:embed config.example.yaml
+var Example []byte
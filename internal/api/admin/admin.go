@@ -0,0 +1,310 @@
+package admin
+
+import (
+	"crypto/subtle"
+	"danmaku-tool/internal/api"
+	"danmaku-tool/internal/api/dandan"
+	"danmaku-tool/internal/config"
+	"danmaku-tool/internal/danmaku"
+	"danmaku-tool/internal/service"
+	"danmaku-tool/internal/utils"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+const adminApiC = "admin_api"
+
+func RegisterRoute(route *chi.Mux) {
+	route.Route("/admin", func(r chi.Router) {
+		r.Use(AdminTokenMiddleware)
+
+		r.Get("/mappings", ListMappings)
+		r.Get("/mappings/{id}", GetMapping)
+		r.Delete("/mappings/{id}", DeleteMapping)
+		r.Put("/mappings/{id}", Remap)
+		r.Post("/mappings/{id}/rematch", Rematch)
+
+		r.Delete("/cache/{id}", PurgeCache)
+
+		r.Get("/search", Search)
+		r.Get("/media", MediaInfo)
+		r.Get("/danmaku/preview", PreviewDanmaku)
+		r.Get("/danmaku/download", DownloadDanmaku)
+
+		r.Get("/bindings", ListBindings)
+		r.Post("/bindings", Bind)
+		r.Delete("/bindings", Unbind)
+
+		r.Get("/jobs", ListJobs)
+		r.Post("/jobs", SubmitJob)
+		r.Get("/jobs/{id}", GetJob)
+		r.Delete("/jobs/{id}", CancelJob)
+
+		r.Get("/platforms", ListPlatforms)
+		r.Post("/platforms/{platform}/enable", EnablePlatform)
+		r.Post("/platforms/{platform}/disable", DisablePlatform)
+	})
+}
+
+// AdminTokenMiddleware 管理接口鉴权 未配置 admin-token 则不启用管理接口
+func AdminTokenMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		adminToken := config.GetConfig().Server.AdminToken
+		if adminToken == "" {
+			api.ResponseJSON(w, http.StatusNotFound, map[string]string{"message": "admin api is disabled"})
+			return
+		}
+		token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if subtle.ConstantTimeCompare([]byte(token), []byte(adminToken)) != 1 {
+			api.ResponseJSON(w, http.StatusUnauthorized, map[string]string{"message": "Unauthorized"})
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func mappingAdmin(w http.ResponseWriter) service.MappingAdmin {
+	m, ok := service.GetDandanSourceMode().(service.MappingAdmin)
+	if !ok {
+		api.ResponseJSON(w, http.StatusNotImplemented, map[string]string{"message": "current mode does not support mapping management"})
+		return nil
+	}
+	return m
+}
+
+func idParam(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		api.ResponseJSON(w, http.StatusBadRequest, map[string]string{"message": "invalid id"})
+		return 0, false
+	}
+	return id, true
+}
+
+type MappingListResult struct {
+	Total int                     `json:"total"`
+	Items []*service.MappingEntry `json:"items"`
+}
+
+// ListMappings ?keyword=标题或者平台id&platform=&offset=&limit=
+func ListMappings(w http.ResponseWriter, r *http.Request) {
+	m := mappingAdmin(w)
+	if m == nil {
+		return
+	}
+	q := r.URL.Query()
+	offset, _ := strconv.Atoi(q.Get("offset"))
+	limit, _ := strconv.Atoi(q.Get("limit"))
+	if limit <= 0 {
+		limit = 50
+	}
+	items, total := m.ListMappings(service.MappingQuery{
+		Keyword:  q.Get("keyword"),
+		Platform: q.Get("platform"),
+		Offset:   offset,
+		Limit:    limit,
+	})
+	api.ResponseJSON(w, http.StatusOK, MappingListResult{Total: total, Items: items})
+}
+
+func GetMapping(w http.ResponseWriter, r *http.Request) {
+	m := mappingAdmin(w)
+	if m == nil {
+		return
+	}
+	id, ok := idParam(w, r)
+	if !ok {
+		return
+	}
+	e, found := m.GetMapping(id)
+	if !found {
+		api.ResponseJSON(w, http.StatusNotFound, map[string]string{"message": "mapping not found"})
+		return
+	}
+	api.ResponseJSON(w, http.StatusOK, e)
+}
+
+func DeleteMapping(w http.ResponseWriter, r *http.Request) {
+	m := mappingAdmin(w)
+	if m == nil {
+		return
+	}
+	id, ok := idParam(w, r)
+	if !ok {
+		return
+	}
+	if !m.DeleteMapping(id) {
+		api.ResponseJSON(w, http.StatusNotFound, map[string]string{"message": "mapping not found"})
+		return
+	}
+	dandan.PurgeCache(strconv.FormatInt(id, 10))
+	utils.InfoLog(adminApiC, "mapping deleted", "id", id)
+	api.ResponseJSON(w, http.StatusOK, nil)
+}
+
+type RemapParam struct {
+	Platform  string `json:"platform"`
+	SeasonId  string `json:"seasonId"`
+	EpisodeId string `json:"episodeId"`
+}
+
+func Remap(w http.ResponseWriter, r *http.Request) {
+	m := mappingAdmin(w)
+	if m == nil {
+		return
+	}
+	id, ok := idParam(w, r)
+	if !ok {
+		return
+	}
+	var param RemapParam
+	if err := api.DecodeJSONBody(w, r, &param); err != nil {
+		return
+	}
+	if err := m.Remap(id, param.Platform, param.SeasonId, param.EpisodeId); err != nil {
+		api.ResponseJSON(w, http.StatusBadRequest, map[string]string{"message": err.Error()})
+		return
+	}
+	dandan.PurgeCache(strconv.FormatInt(id, 10))
+	utils.InfoLog(adminApiC, "mapping remapped", "id", id, "platform", param.Platform, "ssId", param.SeasonId, "epId", param.EpisodeId)
+	e, _ := m.GetMapping(id)
+	api.ResponseJSON(w, http.StatusOK, e)
+}
+
+type RematchParam struct {
+	Title string `json:"title"` // 为空则使用映射记录的标题
+}
+
+type EpisodeResult struct {
+	Id        string `json:"id"`
+	EpisodeId string `json:"episodeId"`
+	Title     string `json:"title"`
+}
+
+type MediaResult struct {
+	Platform string          `json:"platform"`
+	Id       string          `json:"id"`
+	Title    string          `json:"title"`
+	Type     string          `json:"type"`
+	TypeDesc string          `json:"typeDesc"`
+	Cover    string          `json:"cover"`
+	Year     int             `json:"year"`
+	Episodes []EpisodeResult `json:"episodes"`
+}
+
+func toMediaResult(media []*danmaku.Media) []MediaResult {
+	var result = make([]MediaResult, 0, len(media))
+	for _, v := range media {
+		item := MediaResult{
+			Platform: string(v.Platform),
+			Id:       v.Id,
+			Title:    v.Title,
+			Type:     string(v.Type),
+			TypeDesc: v.TypeDesc,
+			Cover:    v.Cover,
+			Year:     v.Year,
+			Episodes: make([]EpisodeResult, 0, len(v.Episodes)),
+		}
+		for _, ep := range v.Episodes {
+			item.Episodes = append(item.Episodes, EpisodeResult{Id: ep.Id, EpisodeId: ep.EpisodeId, Title: ep.Title})
+		}
+		result = append(result, item)
+	}
+	return result
+}
+
+// Rematch 重新搜索候选剧集 选择后通过 PUT /admin/mappings/{id} 重新映射
+func Rematch(w http.ResponseWriter, r *http.Request) {
+	m := mappingAdmin(w)
+	if m == nil {
+		return
+	}
+	id, ok := idParam(w, r)
+	if !ok {
+		return
+	}
+	var param RematchParam
+	if r.ContentLength > 0 {
+		if err := api.DecodeJSONBody(w, r, &param); err != nil {
+			return
+		}
+	}
+	media, err := m.Rematch(id, param.Title)
+	if err != nil {
+		api.ResponseJSON(w, http.StatusBadRequest, map[string]string{"message": err.Error()})
+		return
+	}
+	api.ResponseJSON(w, http.StatusOK, toMediaResult(media))
+}
+
+// PurgeCache id为dandan api的episodeId
+func PurgeCache(w http.ResponseWriter, r *http.Request) {
+	id, ok := idParam(w, r)
+	if !ok {
+		return
+	}
+	dandan.PurgeCache(strconv.FormatInt(id, 10))
+	api.ResponseJSON(w, http.StatusOK, nil)
+}
+
+type PlatformState struct {
+	Platform    string     `json:"platform"`
+	Enabled     bool       `json:"enabled"`
+	Priority    int        `json:"priority"`
+	LastSuccess *time.Time `json:"lastSuccess,omitempty"`
+	LastFailure *time.Time `json:"lastFailure,omitempty"`
+}
+
+type lastRequest interface {
+	LastSuccess() time.Time
+	LastFailure() time.Time
+}
+
+func ListPlatforms(w http.ResponseWriter, _ *http.Request) {
+	scrapers := danmaku.GetScrapers()
+	var result = make([]PlatformState, 0, len(scrapers))
+	for _, s := range scrapers {
+		state := PlatformState{
+			Platform: string(s.Platform()),
+			Enabled:  danmaku.PlatformEnabled(s.Platform()),
+		}
+		if conf := config.GetPlatformConfig(string(s.Platform())); conf != nil {
+			state.Priority = conf.Priority
+		}
+		if lr, ok := s.(lastRequest); ok {
+			if t := lr.LastSuccess(); !t.IsZero() {
+				state.LastSuccess = &t
+			}
+			if t := lr.LastFailure(); !t.IsZero() {
+				state.LastFailure = &t
+			}
+		}
+		result = append(result, state)
+	}
+	api.ResponseJSON(w, http.StatusOK, result)
+}
+
+func EnablePlatform(w http.ResponseWriter, r *http.Request) {
+	setPlatformEnabled(w, r, true)
+}
+
+func DisablePlatform(w http.ResponseWriter, r *http.Request) {
+	setPlatformEnabled(w, r, false)
+}
+
+func setPlatformEnabled(w http.ResponseWriter, r *http.Request, enabled bool) {
+	platform := chi.URLParam(r, "platform")
+	for _, s := range danmaku.GetScrapers() {
+		if string(s.Platform()) == platform {
+			danmaku.SetPlatformEnabled(s.Platform(), enabled)
+			utils.InfoLog(adminApiC, "platform state changed", "platform", platform, "enabled", enabled)
+			api.ResponseJSON(w, http.StatusOK, nil)
+			return
+		}
+	}
+	api.ResponseJSON(w, http.StatusNotFound, map[string]string{"message": "platform not found"})
+}
@@ -0,0 +1,76 @@
+package admin
+
+import (
+	"danmaku-tool/internal/api"
+	"danmaku-tool/internal/jobs"
+	"errors"
+	"net/http"
+)
+
+/*
+	后台抓取任务 提交后立即返回 通过任务详情查看每一集的进度
+*/
+
+func jobError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, jobs.ErrDisabled):
+		api.ResponseJSON(w, http.StatusServiceUnavailable, map[string]string{"message": err.Error()})
+	case errors.Is(err, jobs.ErrNotFound):
+		api.ResponseJSON(w, http.StatusNotFound, map[string]string{"message": err.Error()})
+	case errors.Is(err, jobs.ErrFinished):
+		api.ResponseJSON(w, http.StatusConflict, map[string]string{"message": err.Error()})
+	default:
+		api.ResponseJSON(w, http.StatusBadRequest, map[string]string{"message": err.Error()})
+	}
+}
+
+// SubmitJob body: {"platform":"","id":""} 或者 {"title":"","season":1}
+func SubmitJob(w http.ResponseWriter, r *http.Request) {
+	var param jobs.Request
+	if err := api.DecodeJSONBody(w, r, &param); err != nil {
+		return
+	}
+	job, err := jobs.Submit(param)
+	if err != nil {
+		jobError(w, err)
+		return
+	}
+	api.ResponseJSON(w, http.StatusAccepted, job)
+}
+
+// ListJobs ?status=queued|running|done|failed|canceled
+func ListJobs(w http.ResponseWriter, r *http.Request) {
+	result, err := jobs.List(r.URL.Query().Get("status"))
+	if err != nil {
+		jobError(w, err)
+		return
+	}
+	api.ResponseJSON(w, http.StatusOK, result)
+}
+
+func GetJob(w http.ResponseWriter, r *http.Request) {
+	id, ok := idParam(w, r)
+	if !ok {
+		return
+	}
+	job, err := jobs.Get(id)
+	if err != nil {
+		jobError(w, err)
+		return
+	}
+	api.ResponseJSON(w, http.StatusOK, job)
+}
+
+// CancelJob 执行中的任务停止抓取当前单集
+func CancelJob(w http.ResponseWriter, r *http.Request) {
+	id, ok := idParam(w, r)
+	if !ok {
+		return
+	}
+	job, err := jobs.Cancel(id)
+	if err != nil {
+		jobError(w, err)
+		return
+	}
+	api.ResponseJSON(w, http.StatusOK, job)
+}
@@ -0,0 +1,223 @@
+package admin
+
+import (
+	"danmaku-tool/internal/api"
+	"danmaku-tool/internal/danmaku"
+	"danmaku-tool/internal/utils"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+/*
+	web ui 使用的接口 剧集搜索、单集信息、弹幕预览、文件名绑定以及弹幕下载
+*/
+
+// Search ?title=&platform= 跨平台搜索剧集 platform为空则搜索所有已启用平台
+func Search(w http.ResponseWriter, r *http.Request) {
+	title := strings.TrimSpace(r.URL.Query().Get("title"))
+	if title == "" {
+		api.ResponseJSON(w, http.StatusBadRequest, map[string]string{"message": "title is required"})
+		return
+	}
+	media := danmaku.MatchMedia(danmaku.MatchParam{
+		Title:    title,
+		Mode:     danmaku.Search,
+		SeasonId: -1,
+	})
+	platform := r.URL.Query().Get("platform")
+	if platform != "" {
+		var filtered = make([]*danmaku.Media, 0, len(media))
+		for _, m := range media {
+			if string(m.Platform) == platform {
+				filtered = append(filtered, m)
+			}
+		}
+		media = filtered
+	}
+	api.ResponseJSON(w, http.StatusOK, toMediaResult(media))
+}
+
+// MediaInfo ?platform=&id= 获取剧集以及所有单集信息
+func MediaInfo(w http.ResponseWriter, r *http.Request) {
+	platform, id := r.URL.Query().Get("platform"), r.URL.Query().Get("id")
+	s := danmaku.GetMediaService(platform)
+	if s == nil {
+		api.ResponseJSON(w, http.StatusBadRequest, map[string]string{"message": "unknown or disabled platform"})
+		return
+	}
+	media, err := s.Media(id)
+	if err != nil {
+		utils.ErrorLog(adminApiC, err.Error(), "platform", platform, "id", id)
+		api.ResponseJSON(w, http.StatusBadGateway, map[string]string{"message": err.Error()})
+		return
+	}
+	api.ResponseJSON(w, http.StatusOK, toMediaResult([]*danmaku.Media{media})[0])
+}
+
+func getDanmaku(w http.ResponseWriter, r *http.Request) (danmaku.Platform, string, []*danmaku.StandardDanmaku, bool) {
+	platform, id := r.URL.Query().Get("platform"), r.URL.Query().Get("id")
+	s := danmaku.GetScraper(platform)
+	if s == nil {
+		api.ResponseJSON(w, http.StatusBadRequest, map[string]string{"message": "unknown or disabled platform"})
+		return "", "", nil, false
+	}
+	data, err := s.GetDanmaku(id)
+	if err != nil {
+		utils.ErrorLog(adminApiC, err.Error(), "platform", platform, "id", id)
+		api.ResponseJSON(w, http.StatusBadGateway, map[string]string{"message": err.Error()})
+		return "", "", nil, false
+	}
+	return s.Platform(), id, data, true
+}
+
+type PreviewResult struct {
+	Total         int             `json:"total"`     // 原始弹幕数量
+	Processed     int             `json:"processed"` // 过滤合并后的数量
+	BucketSeconds int             `json:"bucketSeconds"`
+	Density       []int           `json:"density"` // 每个时间段的弹幕数量 过滤合并后
+	Samples       []PreviewSample `json:"samples"`
+}
+
+type PreviewSample struct {
+	Time    float64 `json:"time"` // 秒
+	Mode    int     `json:"mode"`
+	Color   int     `json:"color"`
+	Content string  `json:"content"`
+}
+
+const (
+	defaultBucketSeconds = 10
+	defaultSampleSize    = 100
+)
+
+// PreviewDanmaku ?platform=&id=&bucket=10&samples=100 弹幕密度以及抽样弹幕
+func PreviewDanmaku(w http.ResponseWriter, r *http.Request) {
+	platform, _, data, ok := getDanmaku(w, r)
+	if !ok {
+		return
+	}
+	bucket, _ := strconv.Atoi(r.URL.Query().Get("bucket"))
+	if bucket <= 0 {
+		bucket = defaultBucketSeconds
+	}
+	sampleSize, _ := strconv.Atoi(r.URL.Query().Get("samples"))
+	if sampleSize <= 0 {
+		sampleSize = defaultSampleSize
+	}
+
+	total := len(data)
+	data = danmaku.ProcessDanmaku(platform, data, 0)
+	result := PreviewResult{
+		Total:         total,
+		Processed:     len(data),
+		BucketSeconds: bucket,
+		Density:       []int{},
+		Samples:       make([]PreviewSample, 0, min(sampleSize, len(data))),
+	}
+	bucketMills := int64(bucket) * 1000
+	for _, d := range data {
+		i := int(d.OffsetMills / bucketMills)
+		if i < 0 {
+			continue
+		}
+		for len(result.Density) <= i {
+			result.Density = append(result.Density, 0)
+		}
+		result.Density[i]++
+	}
+	// 等间隔抽样
+	step := max(len(data)/sampleSize, 1)
+	for i := 0; i < len(data) && len(result.Samples) < sampleSize; i += step {
+		d := data[i]
+		result.Samples = append(result.Samples, PreviewSample{
+			Time:    float64(d.OffsetMills) / 1000,
+			Mode:    d.Mode,
+			Color:   d.Color,
+			Content: d.Content,
+		})
+	}
+	api.ResponseJSON(w, http.StatusOK, result)
+}
+
+// DownloadDanmaku ?platform=&id=&format=xml|ass 按照平台配置过滤合并后下载
+func DownloadDanmaku(w http.ResponseWriter, r *http.Request) {
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = danmaku.XMLSerializer
+	}
+	serializer := danmaku.GetSerializer(format)
+	if serializer == nil {
+		api.ResponseJSON(w, http.StatusBadRequest, map[string]string{"message": "unsupported format"})
+		return
+	}
+	platform, id, data, ok := getDanmaku(w, r)
+	if !ok {
+		return
+	}
+	ssId := r.URL.Query().Get("seasonId")
+	serializerData := &danmaku.SerializerData{
+		Platform:  platform,
+		Data:      danmaku.ProcessDanmaku(platform, data, 0),
+		SeasonId:  ssId,
+		EpisodeId: id,
+	}
+	content, err := serializer.Marshal(serializerData)
+	if err != nil {
+		api.ResponseJSON(w, http.StatusInternalServerError, map[string]string{"message": err.Error()})
+		return
+	}
+	filename := strings.NewReplacer("/", "_", "\\", "_", "\"", "_").Replace(string(platform) + "_" + id + "." + format)
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Disposition", "attachment; filename=\""+filename+"\"")
+	_, _ = w.Write(content)
+}
+
+func ListBindings(w http.ResponseWriter, _ *http.Request) {
+	m := mappingAdmin(w)
+	if m == nil {
+		return
+	}
+	api.ResponseJSON(w, http.StatusOK, m.ListBindings())
+}
+
+type BindParam struct {
+	FileName  string `json:"fileName"`
+	Platform  string `json:"platform"`
+	SeasonId  string `json:"seasonId"`
+	EpisodeId string `json:"episodeId"`
+	Title     string `json:"title"`
+}
+
+// Bind 手动绑定文件名到平台单集 dandan api match 时优先使用
+func Bind(w http.ResponseWriter, r *http.Request) {
+	m := mappingAdmin(w)
+	if m == nil {
+		return
+	}
+	var param BindParam
+	if err := api.DecodeJSONBody(w, r, &param); err != nil {
+		return
+	}
+	id, err := m.Bind(param.FileName, param.Platform, param.SeasonId, param.EpisodeId, param.Title)
+	if err != nil {
+		api.ResponseJSON(w, http.StatusBadRequest, map[string]string{"message": err.Error()})
+		return
+	}
+	utils.InfoLog(adminApiC, "file name bound", "fileName", param.FileName, "id", id)
+	e, _ := m.GetMapping(id)
+	api.ResponseJSON(w, http.StatusOK, e)
+}
+
+// Unbind ?fileName=
+func Unbind(w http.ResponseWriter, r *http.Request) {
+	m := mappingAdmin(w)
+	if m == nil {
+		return
+	}
+	if !m.Unbind(r.URL.Query().Get("fileName")) {
+		api.ResponseJSON(w, http.StatusNotFound, map[string]string{"message": "binding not found"})
+		return
+	}
+	api.ResponseJSON(w, http.StatusOK, nil)
+}
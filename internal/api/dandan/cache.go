@@ -2,63 +2,181 @@ package dandan
 
 import (
 	"bytes"
+	"danmaku-tool/internal/cache"
+	"danmaku-tool/internal/config"
 	"danmaku-tool/internal/danmaku"
+	"danmaku-tool/internal/metrics"
+	"danmaku-tool/internal/service"
 	"danmaku-tool/internal/utils"
+	"fmt"
 	"net/http"
-	"path"
+	"slices"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
-	"github.com/dgraph-io/ristretto/v2"
+	"github.com/go-chi/chi/v5"
 )
 
-var cache *ristretto.Cache[string, []byte]
+var apiCache cache.Cache
 
 func init() {
 	danmaku.RegisterInitializer(&DanmakuCache{})
+	// 依赖缓存 需要在缓存之后初始化
+	danmaku.RegisterInitializer(&CommentRefresher{})
 }
 
 type DanmakuCache struct{}
 
 func (d *DanmakuCache) ServerInit() error {
-	c, err := ristretto.NewCache(&ristretto.Config[string, []byte]{
-		NumCounters: 1e7,     // number of keys to track frequency of (10M).
-		MaxCost:     1 << 29, // maximum cost of cache 512M
-		BufferItems: 64,      // number of keys per Get buffer.
-	})
+	c, err := cache.New(config.GetConfig().Server.Cache)
 	if err != nil {
 		return err
 	}
-	cache = c
+	if c == nil {
+		utils.InfoLog(dandanApiCacheC, "api cache disabled")
+		return nil
+	}
+	apiCache = c
+	metrics.NewCounterFunc("danmaku_cache_hits_total", "Number of api cache hits.", func() float64 {
+		return float64(c.Stats().Hits)
+	})
+	metrics.NewCounterFunc("danmaku_cache_misses_total", "Number of api cache misses.", func() float64 {
+		return float64(c.Stats().Misses)
+	})
+	metrics.NewCounterFunc("danmaku_cache_evictions_total", "Number of api cache evictions.", func() float64 {
+		return float64(c.Stats().Evictions)
+	})
+	metrics.NewGaugeFunc("danmaku_cache_cost_bytes", "Current size of the api cache in bytes.", func() float64 {
+		return float64(c.Stats().Size)
+	})
+	metrics.NewGaugeFunc("danmaku_cache_keys", "Current number of keys in the api cache.", func() float64 {
+		return float64(c.Stats().Keys)
+	})
 	return nil
 }
 
+func (d *DanmakuCache) Finalize() error {
+	if apiCache == nil {
+		return nil
+	}
+	return apiCache.Close()
+}
+
 const dandanApiCacheC = "dandan_api_cache"
 
-func CacheMiddleware(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		var cacheKey = ""
-		if strings.Contains(r.URL.Path, "/comment/") {
-			// episodeId
-			cacheKey = path.Base(r.URL.Path)
-			if cachedData, found := cache.Get(cacheKey); found {
+const (
+	RouteComment = "comment"
+	RouteSearch  = "search"
+	RouteBangumi = "bangumi"
+)
+
+// 未配置时的默认缓存 搜索和番剧信息默认不缓存
+var defaultRouteCache = map[string]config.RouteCacheConfig{
+	RouteComment: {Ttl: 3600},
+}
+
+// cacheKeys 各接口的缓存key 需要包含影响输出的参数
+var cacheKeys = map[string]func(r *http.Request) string{
+	RouteComment: func(r *http.Request) string {
+		query := r.URL.Query()
+		from, _ := strconv.ParseInt(query.Get("from"), 10, 64)
+		convert, _ := strconv.ParseBool(query.Get("chConvert"))
+		withRelated, _ := strconv.ParseBool(query.Get("withRelated"))
+		param := service.CommentParam{From: from, Convert: convert, WithRelated: withRelated}
+		recordCommentVariant(param)
+		return commentKey(chi.URLParam(r, "id"), param)
+	},
+	RouteSearch: func(r *http.Request) string {
+		keyword := strings.ToLower(strings.TrimSpace(r.URL.Query().Get("keyword")))
+		return RouteSearch + ":" + keyword
+	},
+	RouteBangumi: func(r *http.Request) string {
+		return RouteBangumi + ":" + chi.URLParam(r, "id")
+	},
+}
+
+func commentKey(id string, param service.CommentParam) string {
+	return fmt.Sprintf("%s:%s?from=%d&chConvert=%t&withRelated=%t", RouteComment, id, param.From, param.Convert, param.WithRelated)
+}
+
+const maxCommentVariants = 4
+
+// commentVariants 最近请求的弹幕接口参数组合 预取时按照这些参数写入缓存
+var commentVariants struct {
+	sync.Mutex
+	list []service.CommentParam
+}
+
+func recordCommentVariant(param service.CommentParam) {
+	commentVariants.Lock()
+	defer commentVariants.Unlock()
+	list := slices.DeleteFunc(commentVariants.list, func(p service.CommentParam) bool { return p == param })
+	commentVariants.list = slices.Insert(list, 0, param)[:min(len(list)+1, maxCommentVariants)]
+}
+
+func getCommentVariants() []service.CommentParam {
+	commentVariants.Lock()
+	defer commentVariants.Unlock()
+	if len(commentVariants.list) == 0 {
+		return []service.CommentParam{{}}
+	}
+	return slices.Clone(commentVariants.list)
+}
+
+func routeCacheConfig(route string) config.RouteCacheConfig {
+	if conf, ok := config.GetConfig().Server.Cache.Routes[route]; ok {
+		return conf
+	}
+	return defaultRouteCache[route]
+}
+
+// PurgeCache 清除单集弹幕缓存 id为dandan api的episodeId 包含所有参数组合
+func PurgeCache(episodeId string) {
+	if apiCache == nil {
+		return
+	}
+	count := apiCache.DelPrefix(RouteComment + ":" + episodeId + "?")
+	utils.InfoLog(dandanApiCacheC, "cache purged", "episodeId", episodeId, "keys", count)
+}
+
+// CacheMiddleware 缓存接口响应 route 为 comment search bangumi
+func CacheMiddleware(route string) func(http.Handler) http.Handler {
+	keyFunc := cacheKeys[route]
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			conf := routeCacheConfig(route)
+			if apiCache == nil || keyFunc == nil || conf.Ttl <= 0 {
+				next.ServeHTTP(w, r)
+				return
+			}
+			cacheKey := keyFunc(r)
+			if cachedData, found := apiCache.Get(cacheKey); found {
+				w.Header().Set("Content-Type", "application/json")
 				_, _ = w.Write(cachedData)
 				utils.DebugLog(dandanApiCacheC, "cache loaded", "cacheKey", cacheKey)
 				return
 			}
-		}
 
-		rr := &responseRecorder{ResponseWriter: w}
-		next.ServeHTTP(rr, r)
+			rr := &responseRecorder{ResponseWriter: w}
+			next.ServeHTTP(rr, r)
 
-		if cacheKey != "" && rr.statusCode == http.StatusOK {
-			cacheData := rr.body.Bytes()
-			success := cache.SetWithTTL(cacheKey, cacheData, int64(len(cacheData)), time.Second*3600) // 1h to expire
-			if !success {
-				utils.ErrorLog(dandanApiCacheC, "cache set failed", "cacheKey", cacheKey)
+			if rr.statusCode == http.StatusOK && rr.body != nil {
+				setCache(conf, cacheKey, rr.body.Bytes())
 			}
-		}
-	})
+		})
+	}
+}
+
+func setCache(conf config.RouteCacheConfig, cacheKey string, cacheData []byte) {
+	if conf.MaxEntrySize > 0 && len(cacheData) > conf.MaxEntrySize<<10 {
+		utils.DebugLog(dandanApiCacheC, "cache skipped, entry too large", "cacheKey", cacheKey, "size", len(cacheData))
+		return
+	}
+	if !apiCache.Set(cacheKey, cacheData, time.Duration(conf.Ttl)*time.Second) {
+		utils.ErrorLog(dandanApiCacheC, "cache set failed", "cacheKey", cacheKey)
+	}
 }
 
 type responseRecorder struct {
@@ -61,6 +61,7 @@ func CommentHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	api.ResponseJSON(w, http.StatusOK, comment)
+	trackComment(numId)
 }
 
 func MatchHandler(w http.ResponseWriter, r *http.Request) {
@@ -89,6 +90,7 @@ func MatchHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	api.ResponseJSON(w, http.StatusOK, result)
+	Prefetch(result.Prefetch)
 }
 
 func SearchAnime(w http.ResponseWriter, r *http.Request) {
@@ -24,7 +24,6 @@ func RegisterRoute(route *chi.Mux) {
 		})
 		d.Use(dandanOptions.Handler)
 		d.Use(middleware.Timeout(time.Duration(1e9 * timeout)))
-		d.Use(CacheMiddleware)
 	})
 	dandanRoute.Route("/api/v1/{token}/api/v2", apiRoute())
 	dandanRoute.Route("/api/v1/{token}", apiRoute())
@@ -33,10 +32,11 @@ func RegisterRoute(route *chi.Mux) {
 func apiRoute() func(r chi.Router) {
 	return func(r chi.Router) {
 		r.Use(TokenValidatorMiddleware)
-		r.Get("/comment/{id}", CommentHandler)
+		// 缓存在token校验之后 避免未授权请求命中缓存
+		r.With(CacheMiddleware(RouteComment)).Get("/comment/{id}", CommentHandler)
 		r.Post("/match", MatchHandler)
-		r.Get("/search/anime", SearchAnime)
-		r.Get("/bangumi/{id}", AnimeInfo)
+		r.With(CacheMiddleware(RouteSearch)).Get("/search/anime", SearchAnime)
+		r.With(CacheMiddleware(RouteBangumi)).Get("/bangumi/{id}", AnimeInfo)
 	}
 }
 
@@ -0,0 +1,121 @@
+package dandan
+
+import (
+	"bytes"
+	"danmaku-tool/internal/config"
+	"danmaku-tool/internal/danmaku"
+	"danmaku-tool/internal/metrics"
+	"danmaku-tool/internal/service"
+	"danmaku-tool/internal/utils"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"sync"
+)
+
+/*
+	match成功后在后台预取匹配剧集以及之后几集的弹幕 写入弹幕接口缓存
+	全局固定数量的worker 队列满时直接丢弃 平台接近限流时跳过
+	缓存key中的参数组合使用最近的弹幕请求参数
+*/
+
+const prefetchC = "prefetch"
+
+const (
+	defaultPrefetchWorkers = 2
+	prefetchQueueSize      = 64
+)
+
+const (
+	prefetchDone    = "done"
+	prefetchCached  = "cached"
+	prefetchSkipped = "skipped" // 平台接近限流
+	prefetchDropped = "dropped" // 队列已满
+	prefetchError   = "error"
+)
+
+var prefetcher struct {
+	once    sync.Once
+	queue   chan service.PrefetchItem
+	pending sync.Map // episodeId 避免重复入队
+}
+
+// Prefetch 提交预取任务 不阻塞 未开启缓存或者弹幕接口不缓存时忽略
+func Prefetch(items []service.PrefetchItem) {
+	if len(items) == 0 || apiCache == nil || routeCacheConfig(RouteComment).Ttl <= 0 {
+		return
+	}
+	prefetcher.once.Do(startPrefetchWorkers)
+	for _, item := range items {
+		if _, loaded := prefetcher.pending.LoadOrStore(item.EpisodeId, struct{}{}); loaded {
+			continue
+		}
+		select {
+		case prefetcher.queue <- item:
+		default:
+			prefetcher.pending.Delete(item.EpisodeId)
+			metrics.Prefetches.Inc(prefetchDropped)
+			utils.DebugLog(prefetchC, "prefetch queue full", "id", item.EpisodeId)
+		}
+	}
+}
+
+// startPrefetchWorkers 第一次预取时启动 worker数量修改需要重启
+func startPrefetchWorkers() {
+	workers := config.GetConfig().Server.Prefetch.Workers
+	if workers <= 0 {
+		workers = defaultPrefetchWorkers
+	}
+	prefetcher.queue = make(chan service.PrefetchItem, prefetchQueueSize)
+	for i := 0; i < workers; i++ {
+		go func() {
+			for item := range prefetcher.queue {
+				prefetch(item)
+				prefetcher.pending.Delete(item.EpisodeId)
+			}
+		}()
+	}
+	utils.InfoLog(prefetchC, "prefetch workers started", "workers", workers)
+}
+
+func prefetch(item service.PrefetchItem) {
+	result, err := warmComment(item, false)
+	metrics.Prefetches.Inc(result)
+	if err != nil {
+		utils.WarnLog(prefetchC, "prefetch failed", "platform", item.Platform, "id", item.EpisodeId, "error", err)
+		return
+	}
+	utils.DebugLog(prefetchC, "prefetch "+result, "platform", item.Platform, "id", item.EpisodeId)
+}
+
+// warmComment 获取弹幕并按照最近的请求参数写入缓存 force 为false时跳过已经缓存的参数组合
+func warmComment(item service.PrefetchItem, force bool) (string, error) {
+	id := strconv.FormatInt(item.EpisodeId, 10)
+	var result = prefetchCached
+	for _, param := range getCommentVariants() {
+		cacheKey := commentKey(id, param)
+		if !force && apiCache.Has(cacheKey) {
+			continue
+		}
+		if danmaku.NearRateLimit(item.Platform) {
+			return prefetchSkipped, nil
+		}
+		mode := service.GetDandanSourceMode()
+		if mode == nil {
+			return prefetchError, fmt.Errorf("no available source")
+		}
+		param.Id = item.EpisodeId
+		comment, err := mode.GetDanmaku(param)
+		if err != nil {
+			return prefetchError, err
+		}
+		// 与 api.ResponseJSON 输出保持一致
+		var buf bytes.Buffer
+		if err = json.NewEncoder(&buf).Encode(comment); err != nil {
+			return prefetchError, err
+		}
+		setCache(routeCacheConfig(RouteComment), cacheKey, buf.Bytes())
+		result = prefetchDone
+	}
+	return result, nil
+}
@@ -0,0 +1,114 @@
+package dandan
+
+import (
+	"danmaku-tool/internal/config"
+	"danmaku-tool/internal/danmaku"
+	"danmaku-tool/internal/metrics"
+	"danmaku-tool/internal/refresh"
+	"danmaku-tool/internal/service"
+	"danmaku-tool/internal/utils"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// server模式定时刷新最近请求过的单集弹幕缓存 开关以及间隔修改需要重启
+
+const refreshC = "comment_refresh"
+
+var refreshStore *refresh.Store
+
+type CommentRefresher struct{}
+
+func (c *CommentRefresher) ServerInit() error {
+	conf := config.GetConfig().Refresh
+	if !conf.Enable {
+		return nil
+	}
+	if apiCache == nil || routeCacheConfig(RouteComment).Ttl <= 0 {
+		utils.WarnLog(refreshC, "comment cache disabled, refresh has no effect")
+		return nil
+	}
+	store, err := refresh.Open(refresh.DefaultPath(), refresh.KindEpisode)
+	if err != nil {
+		return err
+	}
+	refreshStore = store
+	go refreshLoop(refresh.Interval(conf))
+	utils.InfoLog(refreshC, "comment refresh started", "targets", len(store.List()))
+	return nil
+}
+
+func (c *CommentRefresher) Finalize() error {
+	if refreshStore == nil {
+		return nil
+	}
+	return refreshStore.Save()
+}
+
+// 正在获取播出时间的单集 避免并发请求重复获取
+var pendingTracks sync.Map
+
+// trackComment 记录请求过的单集 播出时间需要请求平台 在后台获取 不阻塞弹幕接口
+func trackComment(episodeId int64) {
+	if refreshStore == nil {
+		return
+	}
+	admin, ok := service.GetDandanSourceMode().(service.MappingAdmin)
+	if !ok {
+		return
+	}
+	entry, found := admin.GetMapping(episodeId)
+	if !found {
+		return
+	}
+	id := strconv.FormatInt(episodeId, 10)
+	if refreshStore.Tracked(entry.Platform, id) {
+		return
+	}
+	if _, loaded := pendingTracks.LoadOrStore(episodeId, true); loaded {
+		return
+	}
+	go func() {
+		defer pendingTracks.Delete(episodeId)
+		aired := danmaku.AirTime(entry.Platform, entry.EpisodeId)
+		refreshStore.Track(entry.Platform, id, aired, refresh.Schedule(config.GetConfig().Refresh))
+	}()
+}
+
+func refreshLoop(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		refreshDue()
+	}
+}
+
+func refreshDue() {
+	now := time.Now()
+	schedule := refresh.Schedule(config.GetConfig().Refresh)
+	for _, t := range refreshStore.Due(now, schedule) {
+		id, err := strconv.ParseInt(t.Id, 10, 64)
+		if err != nil {
+			refreshStore.Remove(t)
+			continue
+		}
+		result, err := warmComment(service.PrefetchItem{EpisodeId: id, Platform: t.Platform}, true)
+		metrics.Refreshes.Inc(result)
+		switch {
+		case result == prefetchSkipped:
+			// 接近限流 下次检查时重试
+			utils.DebugLog(refreshC, "platform near rate limit, refresh skipped", "platform", t.Platform, "id", t.Id)
+		case err != nil:
+			// 失败不重试 等待下一个刷新时间点
+			refreshStore.Done(t, now, schedule)
+			utils.WarnLog(refreshC, "refresh failed", "platform", t.Platform, "id", t.Id, "error", err)
+		default:
+			refreshStore.Done(t, now, schedule)
+			utils.InfoLog(refreshC, "comment refreshed", "platform", t.Platform, "id", t.Id, "stage", t.Stage+1)
+		}
+	}
+	if err := refreshStore.Save(); err != nil {
+		utils.ErrorLog(refreshC, err.Error())
+	}
+}
@@ -0,0 +1,195 @@
+package health
+
+import (
+	"danmaku-tool/internal/api"
+	"danmaku-tool/internal/config"
+	"danmaku-tool/internal/danmaku"
+	"danmaku-tool/internal/service"
+	"danmaku-tool/internal/utils"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+/*
+	健康检查
+	/healthz 进程存活即返回200
+	/readyz  返回id映射数据加载状态、emby连通性以及各平台探测结果，平台探测在后台定时执行并缓存结果
+	         所有已启用平台均探测失败时返回503
+*/
+
+const healthC = "health"
+
+const (
+	probeInterval = 5 * time.Minute
+	probeTimeout  = 30 * time.Second // 单个平台探测超时 避免慢的平台拖延其他平台的结果
+)
+
+func init() {
+	danmaku.RegisterInitializer(&prober{})
+}
+
+type PlatformStatus struct {
+	Platform    string     `json:"platform"`
+	Ok          bool       `json:"ok"`
+	Error       string     `json:"error,omitempty"`
+	LastSuccess *time.Time `json:"lastSuccess,omitempty"`
+	CheckedAt   time.Time  `json:"checkedAt"`
+}
+
+type EmbyStatus struct {
+	Enabled bool   `json:"enabled"`
+	Ok      bool   `json:"ok"`
+	Error   string `json:"error,omitempty"`
+	Version string `json:"version,omitempty"`
+}
+
+type ReadyResult struct {
+	Status    string                 `json:"status"`
+	Mapping   *service.MappingStatus `json:"mapping,omitempty"`
+	Emby      EmbyStatus             `json:"emby"`
+	Platforms []PlatformStatus       `json:"platforms"`
+}
+
+type prober struct {
+	lock      sync.RWMutex
+	platforms []PlatformStatus
+	emby      EmbyStatus
+}
+
+var defaultProber *prober
+
+func (p *prober) ServerInit() error {
+	defaultProber = p
+	go func() {
+		ticker := time.NewTicker(probeInterval)
+		defer ticker.Stop()
+		for {
+			p.probe()
+			<-ticker.C
+		}
+	}()
+	return nil
+}
+
+type lastRequest interface {
+	LastSuccess() time.Time
+	LastFailure() time.Time
+}
+
+// probe 各平台以及emby并发探测
+func (p *prober) probe() {
+	var start = time.Now()
+	var scrapers = danmaku.GetEnabledScrapers()
+	var platforms = make([]PlatformStatus, len(scrapers))
+	var wg sync.WaitGroup
+	for i, s := range scrapers {
+		wg.Add(1)
+		go func(i int, s danmaku.Scraper) {
+			defer wg.Done()
+			platforms[i] = probePlatform(s)
+		}(i, s)
+	}
+
+	var emby = EmbyStatus{Enabled: config.EmbyEnabled()}
+	if emby.Enabled {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			info, err := danmaku.PingEmby()
+			if err != nil {
+				emby.Error = err.Error()
+				utils.WarnLog(healthC, "emby probe failed", "error", err)
+			} else {
+				emby.Ok = true
+				emby.Version = info.Version
+			}
+		}()
+	}
+	wg.Wait()
+
+	p.lock.Lock()
+	p.platforms = platforms
+	p.emby = emby
+	p.lock.Unlock()
+	utils.DebugLog(healthC, "probe done", "cost_ms", time.Since(start).Milliseconds())
+}
+
+func probePlatform(s danmaku.Scraper) PlatformStatus {
+	status := PlatformStatus{Platform: string(s.Platform()), Ok: true}
+	if pr, ok := s.(danmaku.Prober); ok {
+		// 超时后探测继续在后台执行 结果丢弃
+		done := make(chan error, 1)
+		go func() { done <- pr.Probe() }()
+		select {
+		case err := <-done:
+			if err != nil {
+				status.Ok = false
+				status.Error = err.Error()
+			}
+		case <-time.After(probeTimeout):
+			status.Ok = false
+			status.Error = "probe timeout after " + probeTimeout.String()
+		}
+	}
+	if lr, ok := s.(lastRequest); ok {
+		success, failure := lr.LastSuccess(), lr.LastFailure()
+		if !success.IsZero() {
+			status.LastSuccess = &success
+		}
+		// 没有探测实现的平台 以最近一次请求结果为准
+		if status.Ok && failure.After(success) {
+			status.Ok = false
+			status.Error = "last request failed at " + failure.Format(time.RFC3339)
+		}
+	}
+	status.CheckedAt = time.Now()
+	if !status.Ok {
+		utils.WarnLog(healthC, "platform probe failed", "platform", status.Platform, "error", status.Error)
+	}
+	return status
+}
+
+func RegisterRoute(r *chi.Mux) {
+	r.Get("/healthz", Healthz)
+	r.Get("/readyz", Readyz)
+}
+
+func Healthz(w http.ResponseWriter, _ *http.Request) {
+	api.ResponseJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+func Readyz(w http.ResponseWriter, _ *http.Request) {
+	var result = ReadyResult{Status: "ok", Platforms: []PlatformStatus{}}
+	if defaultProber != nil {
+		defaultProber.lock.RLock()
+		result.Platforms = append(result.Platforms, defaultProber.platforms...)
+		result.Emby = defaultProber.emby
+		defaultProber.lock.RUnlock()
+	}
+
+	var status = http.StatusOK
+	if store, ok := service.GetDandanSourceMode().(service.MappingStore); ok {
+		mapping := store.MappingStatus()
+		result.Mapping = &mapping
+		if !mapping.Loaded {
+			status = http.StatusServiceUnavailable
+		}
+	}
+
+	var failed int
+	for _, p := range result.Platforms {
+		if !p.Ok {
+			failed++
+		}
+	}
+	if len(result.Platforms) > 0 && failed == len(result.Platforms) {
+		status = http.StatusServiceUnavailable
+	}
+	if status != http.StatusOK {
+		result.Status = "fail"
+	}
+	api.ResponseJSON(w, status, result)
+}
@@ -0,0 +1,218 @@
+const $ = (id) => document.getElementById(id);
+
+const tokenInput = $("token");
+tokenInput.value = localStorage.getItem("admin-token") || "";
+tokenInput.addEventListener("change", () => {
+    localStorage.setItem("admin-token", tokenInput.value);
+    loadPlatforms();
+    loadBindings();
+});
+
+async function request(path, options = {}) {
+    const headers = Object.assign({"Authorization": "Bearer " + tokenInput.value}, options.headers || {});
+    const resp = await fetch(path, Object.assign({}, options, {headers}));
+    if (!resp.ok) {
+        let message = resp.statusText;
+        try {
+            message = (await resp.json()).message || message;
+        } catch (e) {
+        }
+        throw new Error(message);
+    }
+    return resp;
+}
+
+async function requestJSON(path, options) {
+    return (await request(path, options)).json();
+}
+
+function el(tag, attrs = {}, ...children) {
+    const node = document.createElement(tag);
+    for (const [k, v] of Object.entries(attrs)) {
+        if (k.startsWith("on")) {
+            node.addEventListener(k.substring(2), v);
+        } else {
+            node.setAttribute(k, v);
+        }
+    }
+    for (const c of children) {
+        node.append(c);
+    }
+    return node;
+}
+
+function fail(e) {
+    alert(e.message);
+}
+
+async function loadPlatforms() {
+    const select = $("search-platform");
+    select.length = 1;
+    try {
+        const platforms = await requestJSON("/admin/platforms");
+        for (const p of platforms.filter(p => p.enabled)) {
+            select.append(el("option", {value: p.platform}, p.platform));
+        }
+    } catch (e) {
+        // token 未配置时忽略
+    }
+}
+
+$("search-form").addEventListener("submit", async (event) => {
+    event.preventDefault();
+    const params = new URLSearchParams({title: $("search-title").value, platform: $("search-platform").value});
+    const list = $("search-result");
+    list.replaceChildren(el("li", {class: "muted"}, "搜索中..."));
+    try {
+        const media = await requestJSON("/admin/search?" + params);
+        list.replaceChildren();
+        if (media.length === 0) {
+            list.append(el("li", {class: "muted"}, "没有结果"));
+        }
+        for (const m of media) {
+            list.append(el("li", {class: "clickable", onclick: () => showMedia(m.platform, m.id)},
+                el("span", {class: "tag"}, m.platform),
+                `${m.title} ${m.year > 0 ? "(" + m.year + ")" : ""} 共${m.episodes.length}集`));
+        }
+    } catch (e) {
+        list.replaceChildren();
+        fail(e);
+    }
+});
+
+async function showMedia(platform, id) {
+    try {
+        const media = await requestJSON("/admin/media?" + new URLSearchParams({platform, id}));
+        $("media-panel").hidden = false;
+        $("media-title").textContent = `[${media.platform}] ${media.title}`;
+        $("media-desc").textContent = `${media.typeDesc || media.type} id: ${media.id}`;
+        const tbody = $("episodes");
+        tbody.replaceChildren();
+        for (const ep of media.episodes) {
+            tbody.append(el("tr", {},
+                el("td", {}, ep.episodeId),
+                el("td", {}, ep.title),
+                el("td", {}, ep.id),
+                el("td", {},
+                    el("button", {onclick: () => preview(media, ep)}, "预览"),
+                    el("button", {onclick: () => bind(media, ep)}, "绑定"),
+                    el("button", {onclick: () => download(media, ep, "xml")}, "xml"),
+                    el("button", {onclick: () => download(media, ep, "ass")}, "ass"))));
+        }
+    } catch (e) {
+        fail(e);
+    }
+}
+
+async function preview(media, ep) {
+    $("preview-panel").hidden = false;
+    $("preview-title").textContent = `${media.title} ${ep.title}`;
+    $("preview-summary").textContent = "加载中...";
+    try {
+        const result = await requestJSON("/admin/danmaku/preview?" + new URLSearchParams({platform: media.platform, id: ep.id}));
+        $("preview-summary").textContent =
+            `原始弹幕 ${result.total} 条，过滤合并后 ${result.processed} 条，每 ${result.bucketSeconds} 秒密度如下`;
+        drawDensity(result.density);
+        const list = $("samples");
+        list.replaceChildren();
+        for (const s of result.samples) {
+            const color = "#" + s.color.toString(16).padStart(6, "0");
+            list.append(el("li", {},
+                el("span", {class: "tag"}, formatTime(s.time)),
+                el("span", {style: `color: ${color === "#ffffff" ? "inherit" : color}`}, s.content)));
+        }
+    } catch (e) {
+        $("preview-summary").textContent = "";
+        fail(e);
+    }
+}
+
+function drawDensity(density) {
+    const canvas = $("density");
+    const ctx = canvas.getContext("2d");
+    ctx.clearRect(0, 0, canvas.width, canvas.height);
+    if (density.length === 0) {
+        return;
+    }
+    const maxValue = Math.max(...density, 1);
+    const width = canvas.width / density.length;
+    ctx.fillStyle = "#3451b2";
+    density.forEach((v, i) => {
+        const h = v / maxValue * (canvas.height - 10);
+        ctx.fillRect(i * width, canvas.height - h, Math.max(width - 1, 1), h);
+    });
+}
+
+function formatTime(seconds) {
+    const m = Math.floor(seconds / 60);
+    const s = Math.floor(seconds % 60);
+    return `${m}:${String(s).padStart(2, "0")}`;
+}
+
+async function bind(media, ep) {
+    const fileName = prompt("绑定的文件名（播放器匹配时使用的文件名，比如 xxx S01E01.mkv）");
+    if (!fileName) {
+        return;
+    }
+    try {
+        await requestJSON("/admin/bindings", {
+            method: "POST",
+            headers: {"Content-Type": "application/json"},
+            body: JSON.stringify({
+                fileName,
+                platform: media.platform,
+                seasonId: media.id,
+                episodeId: ep.id,
+                title: media.title,
+            }),
+        });
+        loadBindings();
+    } catch (e) {
+        fail(e);
+    }
+}
+
+async function unbind(fileName) {
+    try {
+        await request("/admin/bindings?" + new URLSearchParams({fileName}), {method: "DELETE"});
+        loadBindings();
+    } catch (e) {
+        fail(e);
+    }
+}
+
+async function loadBindings() {
+    const list = $("bindings");
+    try {
+        const bindings = await requestJSON("/admin/bindings");
+        list.replaceChildren();
+        if (bindings.length === 0) {
+            list.append(el("li", {class: "muted"}, "暂无绑定"));
+        }
+        for (const b of bindings) {
+            const m = b.mapping || {};
+            list.append(el("li", {},
+                el("span", {class: "tag"}, m.platform || "-"),
+                `${b.fileName} → ${m.title || ""} ${m.episodeId || ""} `,
+                el("button", {onclick: () => unbind(b.fileName)}, "解绑")));
+        }
+    } catch (e) {
+        list.replaceChildren(el("li", {class: "muted"}, "请先输入 admin-token"));
+    }
+}
+
+async function download(media, ep, format) {
+    try {
+        const params = new URLSearchParams({platform: media.platform, id: ep.id, seasonId: media.id, format});
+        const resp = await request("/admin/danmaku/download?" + params);
+        const blob = await resp.blob();
+        const a = el("a", {href: URL.createObjectURL(blob), download: `${media.title} ${ep.title}.${format}`});
+        a.click();
+        URL.revokeObjectURL(a.href);
+    } catch (e) {
+        fail(e);
+    }
+}
+
+loadPlatforms();
+loadBindings();
@@ -0,0 +1,22 @@
+package ui
+
+import (
+	"embed"
+	"io/fs"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+)
+
+/*
+	内嵌的管理页面 静态页面本身无需鉴权，页面调用的 /admin 接口需要 admin-token
+*/
+
+//go:embed static
+var static embed.FS
+
+func RegisterRoute(route *chi.Mux) {
+	sub, _ := fs.Sub(static, "static")
+	route.Get("/ui", http.RedirectHandler("/ui/", http.StatusMovedPermanently).ServeHTTP)
+	route.Handle("/ui/*", http.StripPrefix("/ui/", http.FileServer(http.FS(sub))))
+}
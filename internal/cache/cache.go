@@ -0,0 +1,126 @@
+package cache
+
+import (
+	"danmaku-tool/internal/config"
+	"danmaku-tool/internal/utils"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+/*
+	api响应缓存 支持内存、磁盘（gzip压缩）以及两级缓存
+	两级缓存优先读取内存 未命中则读取磁盘并回填内存 写入时同时写入两级
+	后台定时清理过期数据 磁盘缓存超过容量时按照最近访问时间淘汰
+*/
+
+const cacheC = "cache"
+
+const (
+	TypeMemory = "memory"
+	TypeDisk   = "disk"
+	TypeTiered = "tiered"
+	TypeNone   = "none"
+)
+
+const (
+	defaultMemorySizeInMB   = 512
+	defaultDiskSizeInMB     = 2048
+	defaultEvictionInterval = 600 // 秒
+	defaultDirName          = "cache"
+)
+
+// Cache 缓存后端
+type Cache interface {
+	Get(key string) ([]byte, bool)
+	// Has 是否存在未过期的key 不计入命中统计
+	Has(key string) bool
+	Set(key string, value []byte, ttl time.Duration) bool
+	Del(key string)
+	// DelPrefix 删除指定前缀的所有key 返回删除数量
+	DelPrefix(prefix string) int
+	Stats() Stats
+	// evict 清理过期数据 后台定时执行
+	evict()
+	Close() error
+}
+
+type Stats struct {
+	Hits      uint64
+	Misses    uint64
+	Evictions uint64
+	Keys      int
+	Size      int64 // 字节 磁盘缓存为压缩后大小
+}
+
+// New 根据配置创建缓存 type 为 none 时返回nil
+func New(conf config.CacheConfig) (Cache, error) {
+	memorySize := conf.MemorySize
+	if memorySize <= 0 {
+		memorySize = defaultMemorySizeInMB
+	}
+	diskSize := conf.DiskSize
+	if diskSize <= 0 {
+		diskSize = defaultDiskSizeInMB
+	}
+	dir := conf.Dir
+	if dir == "" {
+		dir = filepath.Join(filepath.Dir(config.ConfPath), defaultDirName)
+	}
+
+	var c Cache
+	var err error
+	switch conf.Type {
+	case TypeNone:
+		return nil, nil
+	case "", TypeMemory:
+		c, err = newMemoryCache(memorySize << 20)
+	case TypeDisk:
+		c, err = newDiskCache(dir, diskSize<<20)
+	case TypeTiered:
+		c, err = newTieredCache(memorySize<<20, dir, diskSize<<20)
+	default:
+		return nil, fmt.Errorf("unsupported cache type: %s", conf.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	interval := conf.EvictionInterval
+	if interval <= 0 {
+		interval = defaultEvictionInterval
+	}
+	return startEviction(c, time.Duration(interval)*time.Second), nil
+}
+
+// evictor 后台定时清理 Close 时停止
+type evictor struct {
+	Cache
+	stop chan struct{}
+	once sync.Once
+}
+
+func startEviction(c Cache, interval time.Duration) Cache {
+	e := &evictor{Cache: c, stop: make(chan struct{})}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-e.stop:
+				return
+			case <-ticker.C:
+				start := time.Now()
+				e.evict()
+				utils.DebugLog(cacheC, "cache eviction done", "keys", e.Stats().Keys, "cost_ms", time.Since(start).Milliseconds())
+			}
+		}
+	}()
+	return e
+}
+
+func (e *evictor) Close() error {
+	e.once.Do(func() { close(e.stop) })
+	return e.Cache.Close()
+}
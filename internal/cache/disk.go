@@ -0,0 +1,289 @@
+package cache
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"danmaku-tool/internal/utils"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+/*
+	磁盘缓存 每个key一个gzip文件 文件名为key的sha256
+	文件内容第一行为json格式的元信息（key 过期时间） 之后为缓存数据
+	启动时扫描目录重建索引 重启后缓存依旧有效
+*/
+
+type diskEntry struct {
+	file     string
+	size     int64 // 文件大小
+	expireAt time.Time
+	accessAt time.Time
+}
+
+type diskHeader struct {
+	Key      string `json:"key"`
+	ExpireAt int64  `json:"expireAt"` // unix ms
+}
+
+type diskCache struct {
+	dir     string
+	maxSize int64
+
+	lock    sync.Mutex
+	entries map[string]*diskEntry
+	size    int64
+
+	hits, misses, evictions atomic.Uint64
+}
+
+func newDiskCache(dir string, maxSize int64) (*diskCache, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	d := &diskCache{dir: dir, maxSize: maxSize, entries: map[string]*diskEntry{}}
+	d.load()
+	utils.InfoLog(cacheC, "disk cache loaded", "dir", dir, "keys", len(d.entries), "size", d.size)
+	return d, nil
+}
+
+// load 扫描目录重建索引 删除过期以及损坏的文件
+func (d *diskCache) load() {
+	now := time.Now()
+	_ = filepath.WalkDir(d.dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() || !strings.HasSuffix(path, ".gz") {
+			return nil
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return nil
+		}
+		header, err := readHeader(path)
+		if err != nil || now.After(time.UnixMilli(header.ExpireAt)) {
+			_ = os.Remove(path)
+			return nil
+		}
+		d.entries[header.Key] = &diskEntry{
+			file:     path,
+			size:     info.Size(),
+			expireAt: time.UnixMilli(header.ExpireAt),
+			accessAt: info.ModTime(),
+		}
+		d.size += info.Size()
+		return nil
+	})
+}
+
+func readHeader(path string) (*diskHeader, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer utils.SafeClose(f)
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, err
+	}
+	line, err := bufio.NewReader(gz).ReadBytes('\n')
+	if err != nil {
+		return nil, err
+	}
+	var header diskHeader
+	if err = json.Unmarshal(line, &header); err != nil {
+		return nil, err
+	}
+	return &header, nil
+}
+
+func (d *diskCache) filePath(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	name := hex.EncodeToString(sum[:])
+	return filepath.Join(d.dir, name[:2], name+".gz")
+}
+
+func (d *diskCache) Get(key string) ([]byte, bool) {
+	d.lock.Lock()
+	entry, ok := d.entries[key]
+	if ok && time.Now().After(entry.expireAt) {
+		d.remove(key)
+		ok = false
+	}
+	if !ok {
+		d.lock.Unlock()
+		d.misses.Add(1)
+		return nil, false
+	}
+	entry.accessAt = time.Now()
+	file := entry.file
+	d.lock.Unlock()
+
+	value, err := readValue(file)
+	if err != nil {
+		utils.WarnLog(cacheC, "disk cache read failed", "key", key, "error", err)
+		d.Del(key)
+		d.misses.Add(1)
+		return nil, false
+	}
+	d.hits.Add(1)
+	return value, true
+}
+
+func (d *diskCache) Has(key string) bool {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	entry, ok := d.entries[key]
+	return ok && time.Now().Before(entry.expireAt)
+}
+
+func readValue(path string) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer utils.SafeClose(f)
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, err
+	}
+	reader := bufio.NewReader(gz)
+	// 跳过元信息
+	if _, err = reader.ReadBytes('\n'); err != nil {
+		return nil, err
+	}
+	return io.ReadAll(reader)
+}
+
+func (d *diskCache) Set(key string, value []byte, ttl time.Duration) bool {
+	expireAt := time.Now().Add(ttl)
+	header, _ := json.Marshal(diskHeader{Key: key, ExpireAt: expireAt.UnixMilli()})
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	_, _ = gz.Write(header)
+	_, _ = gz.Write([]byte{'\n'})
+	_, _ = gz.Write(value)
+	if err := gz.Close(); err != nil {
+		return false
+	}
+	size := int64(buf.Len())
+	if size > d.maxSize {
+		return false
+	}
+
+	file := d.filePath(key)
+	if err := writeFile(file, buf.Bytes()); err != nil {
+		utils.WarnLog(cacheC, "disk cache write failed", "key", key, "error", err)
+		return false
+	}
+
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	if old, ok := d.entries[key]; ok {
+		d.size -= old.size
+	}
+	d.entries[key] = &diskEntry{file: file, size: size, expireAt: expireAt, accessAt: time.Now()}
+	d.size += size
+	d.shrink()
+	return true
+}
+
+// writeFile 先写入临时文件再重命名 避免读取到写了一半的文件
+func writeFile(file string, content []byte) error {
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		return err
+	}
+	tmp := file + ".tmp"
+	if err := os.WriteFile(tmp, content, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, file)
+}
+
+// shrink 超过容量时按照最近访问时间淘汰 需要持有锁
+func (d *diskCache) shrink() {
+	if d.size <= d.maxSize {
+		return
+	}
+	var keys = make([]string, 0, len(d.entries))
+	for k := range d.entries {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return d.entries[keys[i]].accessAt.Before(d.entries[keys[j]].accessAt)
+	})
+	for _, k := range keys {
+		if d.size <= d.maxSize {
+			break
+		}
+		d.remove(k)
+		d.evictions.Add(1)
+	}
+}
+
+// remove 删除索引以及文件 需要持有锁
+func (d *diskCache) remove(key string) {
+	entry, ok := d.entries[key]
+	if !ok {
+		return
+	}
+	delete(d.entries, key)
+	d.size -= entry.size
+	_ = os.Remove(entry.file)
+}
+
+func (d *diskCache) Del(key string) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	d.remove(key)
+}
+
+func (d *diskCache) DelPrefix(prefix string) int {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	var count int
+	for k := range d.entries {
+		if strings.HasPrefix(k, prefix) {
+			d.remove(k)
+			count++
+		}
+	}
+	return count
+}
+
+func (d *diskCache) Stats() Stats {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	return Stats{
+		Hits:      d.hits.Load(),
+		Misses:    d.misses.Load(),
+		Evictions: d.evictions.Load(),
+		Keys:      len(d.entries),
+		Size:      d.size,
+	}
+}
+
+func (d *diskCache) evict() {
+	now := time.Now()
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	for k, entry := range d.entries {
+		if now.After(entry.expireAt) {
+			d.remove(k)
+			d.evictions.Add(1)
+		}
+	}
+}
+
+func (d *diskCache) Close() error {
+	return nil
+}
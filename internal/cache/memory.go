@@ -0,0 +1,100 @@
+package cache
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dgraph-io/ristretto/v2"
+)
+
+// memoryCache 基于ristretto 额外维护key索引用于前缀删除
+type memoryCache struct {
+	cache *ristretto.Cache[string, []byte]
+
+	lock sync.Mutex
+	keys map[string]time.Time // key -> 过期时间
+}
+
+func newMemoryCache(maxCost int64) (*memoryCache, error) {
+	c, err := ristretto.NewCache(&ristretto.Config[string, []byte]{
+		NumCounters: 1e7,     // number of keys to track frequency of (10M).
+		MaxCost:     maxCost, // maximum cost of cache
+		BufferItems: 64,      // number of keys per Get buffer.
+		Metrics:     true,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &memoryCache{cache: c, keys: map[string]time.Time{}}, nil
+}
+
+func (m *memoryCache) Get(key string) ([]byte, bool) {
+	return m.cache.Get(key)
+}
+
+func (m *memoryCache) Has(key string) bool {
+	_, ok := m.cache.GetTTL(key)
+	return ok
+}
+
+func (m *memoryCache) Set(key string, value []byte, ttl time.Duration) bool {
+	if !m.cache.SetWithTTL(key, value, int64(len(value)), ttl) {
+		return false
+	}
+	m.lock.Lock()
+	m.keys[key] = time.Now().Add(ttl)
+	m.lock.Unlock()
+	return true
+}
+
+func (m *memoryCache) Del(key string) {
+	m.cache.Del(key)
+	m.lock.Lock()
+	delete(m.keys, key)
+	m.lock.Unlock()
+}
+
+func (m *memoryCache) DelPrefix(prefix string) int {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	var count int
+	for k := range m.keys {
+		if strings.HasPrefix(k, prefix) {
+			m.cache.Del(k)
+			delete(m.keys, k)
+			count++
+		}
+	}
+	return count
+}
+
+func (m *memoryCache) Stats() Stats {
+	m.lock.Lock()
+	keys := len(m.keys)
+	m.lock.Unlock()
+	return Stats{
+		Hits:      m.cache.Metrics.Hits(),
+		Misses:    m.cache.Metrics.Misses(),
+		Evictions: m.cache.Metrics.KeysEvicted(),
+		Keys:      keys,
+		Size:      int64(m.cache.Metrics.CostAdded() - m.cache.Metrics.CostEvicted()),
+	}
+}
+
+// evict 过期数据由ristretto清理 这里只清理索引
+func (m *memoryCache) evict() {
+	now := time.Now()
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	for k, expireAt := range m.keys {
+		if now.After(expireAt) {
+			delete(m.keys, k)
+		}
+	}
+}
+
+func (m *memoryCache) Close() error {
+	m.cache.Close()
+	return nil
+}
@@ -0,0 +1,92 @@
+package cache
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// tieredCache 内存 + 磁盘两级缓存
+type tieredCache struct {
+	memory *memoryCache
+	disk   *diskCache
+
+	hits, misses atomic.Uint64
+}
+
+// tieredMemoryTTL 磁盘命中回填内存时使用的过期时间 不超过磁盘缓存剩余时间
+const tieredMemoryTTL = 10 * time.Minute
+
+func newTieredCache(memorySize int64, dir string, diskSize int64) (*tieredCache, error) {
+	memory, err := newMemoryCache(memorySize)
+	if err != nil {
+		return nil, err
+	}
+	disk, err := newDiskCache(dir, diskSize)
+	if err != nil {
+		return nil, err
+	}
+	return &tieredCache{memory: memory, disk: disk}, nil
+}
+
+func (t *tieredCache) Get(key string) ([]byte, bool) {
+	if value, ok := t.memory.Get(key); ok {
+		t.hits.Add(1)
+		return value, true
+	}
+	value, ok := t.disk.Get(key)
+	if !ok {
+		t.misses.Add(1)
+		return nil, false
+	}
+	t.hits.Add(1)
+	ttl := tieredMemoryTTL
+	t.disk.lock.Lock()
+	if entry, found := t.disk.entries[key]; found {
+		ttl = min(ttl, time.Until(entry.expireAt))
+	}
+	t.disk.lock.Unlock()
+	if ttl > 0 {
+		t.memory.Set(key, value, ttl)
+	}
+	return value, true
+}
+
+func (t *tieredCache) Has(key string) bool {
+	return t.memory.Has(key) || t.disk.Has(key)
+}
+
+func (t *tieredCache) Set(key string, value []byte, ttl time.Duration) bool {
+	m := t.memory.Set(key, value, ttl)
+	d := t.disk.Set(key, value, ttl)
+	return m || d
+}
+
+func (t *tieredCache) Del(key string) {
+	t.memory.Del(key)
+	t.disk.Del(key)
+}
+
+func (t *tieredCache) DelPrefix(prefix string) int {
+	return max(t.memory.DelPrefix(prefix), t.disk.DelPrefix(prefix))
+}
+
+func (t *tieredCache) Stats() Stats {
+	memory, disk := t.memory.Stats(), t.disk.Stats()
+	return Stats{
+		Hits:      t.hits.Load(),
+		Misses:    t.misses.Load(),
+		Evictions: memory.Evictions + disk.Evictions,
+		Keys:      disk.Keys,
+		Size:      memory.Size + disk.Size,
+	}
+}
+
+func (t *tieredCache) evict() {
+	t.memory.evict()
+	t.disk.evict()
+}
+
+func (t *tieredCache) Close() error {
+	_ = t.memory.Close()
+	return t.disk.Close()
+}
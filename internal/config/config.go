@@ -1,8 +1,10 @@
 package config
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
+	"sync/atomic"
 
 	"gopkg.in/yaml.v3"
 )
@@ -12,24 +14,62 @@ var (
 	ConfPath string
 )
 
-var danmakuConfig *DanmakuConfig
+// 配置热加载时整体替换 读取方通过 GetConfig 获取当前配置
+var danmakuConfig atomic.Pointer[DanmakuConfig]
 
 func Init(path string, debug bool) {
-	if danmakuConfig != nil {
+	if danmakuConfig.Load() != nil {
 		return
 	}
 	var file = loadDefaultConfig(path)
 	if file == nil {
 		panic("danmaku config file load failed")
 	}
-	if err := yaml.Unmarshal(file, &danmakuConfig); err != nil {
+	conf, err := parse(file)
+	if err != nil {
 		panic(err.Error())
 	}
-	danmakuConfig.Debug = debug
+	conf.Debug = debug
+	danmakuConfig.Store(conf)
+}
+
+// Reload 重新读取配置文件并替换当前配置 解析失败则保留原配置
+// 返回替换前后的配置用于比较差异
+func Reload() (*DanmakuConfig, *DanmakuConfig, error) {
+	old := danmakuConfig.Load()
+	if ConfPath == "" {
+		return old, old, fmt.Errorf("config file path is unknown")
+	}
+	file, err := os.ReadFile(ConfPath)
+	if err != nil {
+		return old, old, err
+	}
+	conf, err := parse(file)
+	if err != nil {
+		return old, old, err
+	}
+	// debug 由命令行参数决定
+	conf.Debug = old.Debug
+	danmakuConfig.Store(conf)
+	return old, conf, nil
+}
+
+// parse 解析yaml后使用环境变量覆盖
+func parse(file []byte) (*DanmakuConfig, error) {
+	var conf DanmakuConfig
+	if err := yaml.Unmarshal(file, &conf); err != nil {
+		return nil, err
+	}
+	overrides, err := applyEnv(&conf)
+	if err != nil {
+		return nil, err
+	}
+	conf.envOverrides = overrides
+	return &conf, nil
 }
 
 func GetConfig() *DanmakuConfig {
-	return danmakuConfig
+	return danmakuConfig.Load()
 }
 
 const configPathEnv = "DANMAKU_TOOL_CONFIG"
@@ -44,24 +84,30 @@ func loadFromPath(path string) []byte {
 }
 
 func loadDefaultConfig(path string) []byte {
+	if p := ResolvePath(path); p != "" {
+		return loadFromPath(p)
+	}
+	return nil
+}
+
+// ResolvePath 配置文件路径 优先级：命令行参数 > 环境变量 > ~/.config/danmaku-tool/config.yaml > 可执行文件目录
+func ResolvePath(path string) string {
 	// load from cmd parameter
 	if path != "" {
-		return loadFromPath(path)
+		return path
 	}
 	// load from env
 	if p := os.Getenv(configPathEnv); p != "" {
-		return loadFromPath(p)
+		return p
 	}
 	if home, _ := os.UserHomeDir(); home != "" {
 		// load from user home .config/danmaku-tool/config.yaml
-		CfgPath := filepath.Join(home, ".config", "danmaku-tool", "config.yaml")
-		return loadFromPath(CfgPath)
+		return filepath.Join(home, ".config", "danmaku-tool", "config.yaml")
 	}
 	if execPath, _ := os.Executable(); execPath != "" {
-		CfgPath := filepath.Join(filepath.Dir(execPath), "config.yaml")
-		return loadFromPath(CfgPath)
+		return filepath.Join(filepath.Dir(execPath), "config.yaml")
 	}
-	return nil
+	return ""
 }
 
 func GetPlatformConfig(platform string) *PlatformConfig {
@@ -76,6 +122,7 @@ func GetPlatformConfig(platform string) *PlatformConfig {
 type DanmakuConfig struct {
 	Debug         bool             `yaml:"debug"`
 	SavePath      string           `yaml:"save-path"`
+	SaveTemplate  string           `yaml:"save-template"` // 弹幕文件相对 save-path 的路径模板 不含后缀 为空则为 {platform}/{剧集id}/{单集id}
 	DandanMode    string           `yaml:"dandan-mode"`
 	DandanTimeout int              `yaml:"dandan-timeout"`
 	UA            string           `yaml:"ua"`
@@ -83,6 +130,32 @@ type DanmakuConfig struct {
 	Emby          EmbyConfig       `yaml:"emby"`
 	Server        ServerConfig     `yaml:"server"`
 	Tokenizer     TokenizerConfig  `yaml:"tokenizer"`
+	Filter        FilterConfig     `yaml:"filter"`
+	Refresh       RefreshConfig    `yaml:"refresh"`
+	Watch         WatchConfig      `yaml:"watch"`
+
+	envOverrides []string // 生效的环境变量
+}
+
+// SaveTemplateFields save-template 支持的字段
+var SaveTemplateFields = []string{"platform", "title", "year", "season", "ep", "ep_title", "media_id", "ep_id"}
+
+// EnvOverrides 覆盖了配置文件的环境变量名
+func (c *DanmakuConfig) EnvOverrides() []string {
+	return c.envOverrides
+}
+
+// RefreshConfig 弹幕定时刷新 server模式刷新最近请求的单集缓存 refresh命令刷新抓取过的弹幕文件
+type RefreshConfig struct {
+	Enable   bool  `yaml:"enable"`   // 记录请求以及抓取过的弹幕 server模式开启后台刷新
+	Schedule []int `yaml:"schedule"` // 播出（未知则首次请求或者抓取）之后的刷新时间点 单位小时 默认 1 6 24 168
+	Interval int   `yaml:"interval"` // server模式检查间隔 单位秒 默认300
+}
+
+// WatchConfig 剧集订阅 定时检查新的单集并抓取弹幕
+type WatchConfig struct {
+	Enable   bool `yaml:"enable"`   // server模式是否开启后台检查 watch命令不受影响
+	Interval int  `yaml:"interval"` // 检查间隔 单位分钟 默认60
 }
 
 type TokenizerConfig struct {
@@ -95,28 +168,119 @@ type TokenizerConfig struct {
 	} `yaml:"blacklist"`
 }
 
+// FilterConfig 弹幕内容过滤 作用于弹幕文件保存和dandan api
+type FilterConfig struct {
+	Enable bool `yaml:"enable"`
+	// 关键词或者正则黑名单 platform 生效的平台 默认为全平台
+	Blacklist []struct {
+		Keyword  string `yaml:"keyword"`
+		Regex    string `yaml:"regex"`
+		Platform string `yaml:"platform"`
+	} `yaml:"blacklist"`
+	Spam      SpamFilterConfig `yaml:"spam"`
+	MinLength int              `yaml:"min-length"` // 最小长度 <=0 不限制
+	MaxLength int              `yaml:"max-length"` // 最大长度 <=0 不限制
+	DropModes []int            `yaml:"drop-modes"` // 需要过滤的弹幕类型 1滚动 4底部 5顶部
+}
+
+// SpamFilterConfig 垃圾弹幕规则
+type SpamFilterConfig struct {
+	RepeatChars int  `yaml:"repeat-chars"` // 同一字符连续重复次数达到则过滤 <=0 不启用
+	Punctuation bool `yaml:"punctuation"`  // 纯标点符号
+	Url         bool `yaml:"url"`          // 网址
+	Contact     bool `yaml:"contact"`      // QQ 手机号 微信号
+}
+
 type EmbyConfig struct {
-	Url   string `yaml:"url"`
-	User  string `yaml:"user"`
-	Token string `yaml:"token"`
+	Url   string      `yaml:"url"`
+	User  string      `yaml:"user"`
+	Token string      `yaml:"token"`
+	Proxy ProxyConfig `yaml:"proxy"`
+}
+
+// ProxyConfig 请求代理 url 为空则不使用代理
+type ProxyConfig struct {
+	Url      string   `yaml:"url"`      // http://host:port https:// socks5:// socks5h:// 可以包含认证信息
+	Username string   `yaml:"username"` // 可选 优先于url中的认证信息
+	Password string   `yaml:"password"`
+	NoProxy  []string `yaml:"no-proxy"` // 不走代理的域名（包含子域名）、IP、CIDR
 }
 
 func EmbyEnabled() bool {
-	return danmakuConfig.Emby.User != "" && danmakuConfig.Emby.Url != "" && danmakuConfig.Emby.Token != ""
+	emby := GetConfig().Emby
+	return emby.User != "" && emby.Url != "" && emby.Token != ""
 }
 
 type ServerConfig struct {
 	Port    int      `yaml:"port"`    // can be overwritten by cli parameter
 	Timeout int      `yaml:"timeout"` // 全局api超时时间
 	Tokens  []string `yaml:"tokens"`  // token配置
+	// 管理接口token 为空则不启用管理接口
+	AdminToken string `yaml:"admin-token"`
+	// api响应缓存
+	Cache CacheConfig `yaml:"cache"`
+	// match之后预取弹幕
+	Prefetch PrefetchConfig `yaml:"prefetch"`
+	// 管理接口提交的抓取任务
+	Jobs JobsConfig `yaml:"jobs"`
+}
+
+// JobsConfig 后台抓取任务 修改需要重启
+type JobsConfig struct {
+	Workers int `yaml:"workers"` // 同时执行的任务数 默认2
+	History int `yaml:"history"` // 保留的已结束任务数量 默认100
+}
+
+// PrefetchConfig match成功后在后台预取匹配剧集以及之后几集的弹幕写入缓存
+type PrefetchConfig struct {
+	Enable   bool `yaml:"enable"`
+	Episodes int  `yaml:"episodes"` // 预取之后的集数 <=0 默认1 电影只预取本身
+	Workers  int  `yaml:"workers"`  // 全局预取并发数 默认2
+}
+
+type CacheConfig struct {
+	Type             string                      `yaml:"type"`              // memory disk tiered none 默认memory
+	Dir              string                      `yaml:"dir"`               // 磁盘缓存目录 默认为配置文件目录下的cache
+	MemorySize       int64                       `yaml:"memory-size"`       // 内存缓存容量 单位MB 默认512
+	DiskSize         int64                       `yaml:"disk-size"`         // 磁盘缓存容量 单位MB 默认2048
+	EvictionInterval int                         `yaml:"eviction-interval"` // 过期清理间隔 单位秒 默认600
+	Routes           map[string]RouteCacheConfig `yaml:"routes"`            // 各接口缓存配置 key为 comment search bangumi
+}
+
+type RouteCacheConfig struct {
+	Ttl          int `yaml:"ttl"`            // 过期时间 单位秒 <=0 不缓存
+	MaxEntrySize int `yaml:"max-entry-size"` // 单条缓存最大大小 单位KB <=0 不限制
 }
 
 type PlatformConfig struct {
-	Name                string   `yaml:"name"`
-	Priority            int      `yaml:"priority"`
-	Cookie              string   `yaml:"cookie"`
-	MaxWorker           int      `yaml:"max-worker"`
-	Timeout             int64    `yaml:"timeout"` // in seconds
-	MergeDanmakuInMills int64    `yaml:"merge-danmaku-in-mills"`
-	Persists            []string `yaml:"persists"`
+	Name                 string   `yaml:"name"`
+	Priority             int      `yaml:"priority"`
+	Cookie               string   `yaml:"cookie"`
+	MaxWorker            int      `yaml:"max-worker"`
+	Timeout              int64    `yaml:"timeout"` // in seconds
+	MergeDanmakuInMills  int64    `yaml:"merge-danmaku-in-mills"`
+	MergeAnnotation      string   `yaml:"merge-annotation"`        // 合并后标注重复次数的模板 {content} {count} 默认 "{content} ×{count}" none则不标注
+	MergeMinCount        int      `yaml:"merge-min-count"`         // 重复次数达到该值才标注 默认2
+	DensityMaxPerWindow  int      `yaml:"density-max-per-window"`  // 弹幕密度控制 时间窗口内最多保留的弹幕数量 <=0 不启用
+	DensityWindowInMills int64    `yaml:"density-window-in-mills"` // 弹幕密度控制 滑动时间窗口大小 默认1000ms
+	Qps                  float64  `yaml:"qps"`                     // 每秒请求数 <=0 不限流
+	Burst                int      `yaml:"burst"`                   // 限流突发请求数 默认为qps向上取整
+	MaxRetries           int      `yaml:"max-retries"`             // 单个请求最大重试次数 默认2 <0 不重试
+	RetryBudget          int      `yaml:"retry-budget"`            // 每分钟最多重试次数 默认30
+	Persists             []string `yaml:"persists"`
+	// 请求代理 比如港澳台剧集使用香港代理
+	Proxy ProxyConfig `yaml:"proxy"`
+	// 以下为 dandan 平台使用 兼容dandan api的服务地址以及官方api签名信息
+	Url       string `yaml:"url"`
+	AppId     string `yaml:"app-id"`
+	AppSecret string `yaml:"app-secret"`
+	// 外部插件平台 name 不能与内置平台重复
+	Plugin *PluginConfig `yaml:"plugin"`
+}
+
+// PluginConfig 外部插件 command 和 url 二选一 优先使用 command
+type PluginConfig struct {
+	Command string   `yaml:"command"` // 可执行文件路径
+	Args    []string `yaml:"args"`    // 可执行文件参数
+	Url     string   `yaml:"url"`     // 本地http服务地址
 }
@@ -0,0 +1,91 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Diff 比较两份配置 返回变更项 key 为 yaml 路径 平台按照 name 区分 敏感字段只提示变更不输出内容
+func Diff(oldConf, newConf *DanmakuConfig) []string {
+	oldValues, newValues := flatten(oldConf), flatten(newConf)
+	var keys []string
+	for k := range oldValues {
+		keys = append(keys, k)
+	}
+	for k := range newValues {
+		if _, ok := oldValues[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	var result []string
+	for _, k := range keys {
+		o, inOld := oldValues[k]
+		n, inNew := newValues[k]
+		switch {
+		case !inOld:
+			result = append(result, fmt.Sprintf("+ %s: %s", k, maskValue(k, n)))
+		case !inNew:
+			result = append(result, fmt.Sprintf("- %s: %s", k, maskValue(k, o)))
+		case o != n:
+			if isSecret(k) {
+				result = append(result, fmt.Sprintf("~ %s: changed", k))
+			} else {
+				// url中的密码同样隐藏 比如代理地址
+				result = append(result, fmt.Sprintf("~ %s: %s -> %s", k, maskValue(k, o), maskValue(k, n)))
+			}
+		}
+	}
+	return result
+}
+
+// flatten 将配置展开为 yaml路径 -> 值
+func flatten(conf *DanmakuConfig) map[string]string {
+	var result = map[string]string{}
+	if conf == nil {
+		return result
+	}
+	content, err := yaml.Marshal(conf)
+	if err != nil {
+		return result
+	}
+	var node map[string]any
+	if err = yaml.Unmarshal(content, &node); err != nil {
+		return result
+	}
+	flattenValue("", node, result)
+	return result
+}
+
+func flattenValue(prefix string, value any, result map[string]string) {
+	switch v := value.(type) {
+	case map[string]any:
+		for k, item := range v {
+			flattenValue(joinKey(prefix, k), item, result)
+		}
+	case []any:
+		for i, item := range v {
+			// 平台配置使用 name 作为key 避免调整顺序导致全部变更
+			key := prefix + "[" + strconv.Itoa(i) + "]"
+			if m, ok := item.(map[string]any); ok && prefix == "platforms" {
+				if name, ok := m["name"].(string); ok && name != "" {
+					key = prefix + "." + name
+				}
+			}
+			flattenValue(key, item, result)
+		}
+	default:
+		result[prefix] = fmt.Sprint(v)
+	}
+}
+
+func joinKey(prefix, key string) string {
+	if prefix == "" {
+		return key
+	}
+	return prefix + "." + key
+}
@@ -0,0 +1,140 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+/*
+	环境变量覆盖配置 在yaml解析之后执行 用于docker等场景下注入cookie、token等敏感信息
+
+	变量名为 DANMAKU_ 加上 yaml 路径 大写 - 和 . 替换为 _ 比如：
+	server.tokens -> DANMAKU_SERVER_TOKENS 列表使用逗号分隔
+	emby.token -> DANMAKU_EMBY_TOKEN
+	平台配置使用平台名称 platforms[name=bilibili].cookie -> DANMAKU_PLATFORMS_BILIBILI_COOKIE
+	变量名加上 _FILE 后缀则从文件读取 比如 DANMAKU_EMBY_TOKEN_FILE=/run/secrets/emby_token 同时配置时优先使用变量值
+
+	只支持字符串、数字、布尔以及字符串/数字列表 平台只能覆盖yaml中已经配置的平台
+*/
+
+const envPrefix = "DANMAKU"
+
+// applyEnv 使用环境变量覆盖配置 返回生效的变量名
+func applyEnv(conf *DanmakuConfig) ([]string, error) {
+	var applied []string
+	err := applyEnvValue(reflect.ValueOf(conf).Elem(), envPrefix, &applied)
+	return applied, err
+}
+
+func applyEnvValue(v reflect.Value, key string, applied *[]string) error {
+	switch v.Kind() {
+	case reflect.Struct:
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			name := strings.Split(field.Tag.Get("yaml"), ",")[0]
+			if !field.IsExported() || name == "" || name == "-" {
+				continue
+			}
+			if err := applyEnvValue(v.Field(i), envKey(key, name), applied); err != nil {
+				return err
+			}
+		}
+		return nil
+	case reflect.Pointer:
+		if v.IsNil() {
+			return nil
+		}
+		return applyEnvValue(v.Elem(), key, applied)
+	case reflect.Slice:
+		// 平台配置 按照名称区分
+		if v.Type().Elem() == reflect.TypeOf(PlatformConfig{}) {
+			for i := 0; i < v.Len(); i++ {
+				p := v.Index(i)
+				name := p.FieldByName("Name").String()
+				if name == "" {
+					continue
+				}
+				if err := applyEnvValue(p, envKey(key, name), applied); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+	}
+
+	value, ok, err := lookupEnv(key)
+	if err != nil || !ok {
+		return err
+	}
+	if err = setValue(v, value); err != nil {
+		return fmt.Errorf("env %s: %w", key, err)
+	}
+	*applied = append(*applied, key)
+	return nil
+}
+
+func envKey(prefix, name string) string {
+	return prefix + "_" + strings.ToUpper(strings.NewReplacer("-", "_", ".", "_").Replace(name))
+}
+
+// lookupEnv 优先使用变量值 其次读取 _FILE 指定的文件
+func lookupEnv(key string) (string, bool, error) {
+	if value, ok := os.LookupEnv(key); ok {
+		return value, true, nil
+	}
+	path, ok := os.LookupEnv(key + "_FILE")
+	if !ok {
+		return "", false, nil
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return "", false, fmt.Errorf("env %s_FILE: %w", key, err)
+	}
+	return strings.TrimRight(string(content), "\r\n"), true, nil
+}
+
+func setValue(v reflect.Value, value string) error {
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(value)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return err
+		}
+		v.SetInt(i)
+	case reflect.Float64:
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+	case reflect.Slice:
+		var items []string
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		slice := reflect.MakeSlice(v.Type(), len(items), len(items))
+		for i, item := range items {
+			if err := setValue(slice.Index(i), item); err != nil {
+				return err
+			}
+		}
+		v.Set(slice)
+	default:
+		return fmt.Errorf("unsupported type %s", v.Type())
+	}
+	return nil
+}
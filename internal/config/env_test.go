@@ -0,0 +1,139 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"testing"
+)
+
+func TestApplyEnv(t *testing.T) {
+	secret := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(secret, []byte("from-file\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name        string
+		env         map[string]string
+		check       func(c *DanmakuConfig) any
+		want        any
+		wantApplied []string
+		wantErr     bool
+	}{
+		{
+			name:        "string",
+			env:         map[string]string{"DANMAKU_SAVE_PATH": "/data"},
+			check:       func(c *DanmakuConfig) any { return c.SavePath },
+			want:        "/data",
+			wantApplied: []string{"DANMAKU_SAVE_PATH"},
+		},
+		{
+			name:        "bool",
+			env:         map[string]string{"DANMAKU_DEBUG": "true"},
+			check:       func(c *DanmakuConfig) any { return c.Debug },
+			want:        true,
+			wantApplied: []string{"DANMAKU_DEBUG"},
+		},
+		{
+			name:        "nested int",
+			env:         map[string]string{"DANMAKU_SERVER_PORT": "9000"},
+			check:       func(c *DanmakuConfig) any { return c.Server.Port },
+			want:        9000,
+			wantApplied: []string{"DANMAKU_SERVER_PORT"},
+		},
+		{
+			name:        "list",
+			env:         map[string]string{"DANMAKU_SERVER_TOKENS": "a, b,,c"},
+			check:       func(c *DanmakuConfig) any { return c.Server.Tokens },
+			want:        []string{"a", "b", "c"},
+			wantApplied: []string{"DANMAKU_SERVER_TOKENS"},
+		},
+		{
+			name:        "dash in name",
+			env:         map[string]string{"DANMAKU_EMBY_PROXY_NO_PROXY": "lan,10.0.0.0/8"},
+			check:       func(c *DanmakuConfig) any { return c.Emby.Proxy.NoProxy },
+			want:        []string{"lan", "10.0.0.0/8"},
+			wantApplied: []string{"DANMAKU_EMBY_PROXY_NO_PROXY"},
+		},
+		{
+			name:        "platform",
+			env:         map[string]string{"DANMAKU_PLATFORMS_BILIBILI_COOKIE": "SESSDATA=x", "DANMAKU_PLATFORMS_BILIBILI_QPS": "2.5"},
+			check:       func(c *DanmakuConfig) any { return []any{c.Platforms[0].Cookie, c.Platforms[0].Qps} },
+			want:        []any{"SESSDATA=x", 2.5},
+			wantApplied: []string{"DANMAKU_PLATFORMS_BILIBILI_COOKIE", "DANMAKU_PLATFORMS_BILIBILI_QPS"},
+		},
+		{
+			name:  "platform not configured",
+			env:   map[string]string{"DANMAKU_PLATFORMS_MGTV_COOKIE": "x"},
+			check: func(c *DanmakuConfig) any { return len(c.Platforms) },
+			want:  1,
+		},
+		{
+			name:        "secret file",
+			env:         map[string]string{"DANMAKU_EMBY_TOKEN_FILE": secret},
+			check:       func(c *DanmakuConfig) any { return c.Emby.Token },
+			want:        "from-file",
+			wantApplied: []string{"DANMAKU_EMBY_TOKEN"},
+		},
+		{
+			name:        "value over file",
+			env:         map[string]string{"DANMAKU_EMBY_TOKEN": "from-env", "DANMAKU_EMBY_TOKEN_FILE": secret},
+			check:       func(c *DanmakuConfig) any { return c.Emby.Token },
+			want:        "from-env",
+			wantApplied: []string{"DANMAKU_EMBY_TOKEN"},
+		},
+		{
+			name:    "invalid number",
+			env:     map[string]string{"DANMAKU_SERVER_PORT": "port"},
+			wantErr: true,
+		},
+		{
+			name:    "missing file",
+			env:     map[string]string{"DANMAKU_EMBY_TOKEN_FILE": filepath.Join(t.TempDir(), "missing")},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+			conf := &DanmakuConfig{
+				SavePath:  "./danmaku",
+				Server:    ServerConfig{Port: 8089},
+				Platforms: []PlatformConfig{{Name: "bilibili", Cookie: "old"}},
+			}
+			applied, err := applyEnv(conf)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if got := tt.check(conf); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+			slices.Sort(applied)
+			if !slices.Equal(applied, tt.wantApplied) {
+				t.Errorf("applied %v, want %v", applied, tt.wantApplied)
+			}
+		})
+	}
+}
+
+func TestEnvKey(t *testing.T) {
+	tests := []struct {
+		prefix, name, want string
+	}{
+		{prefix: "DANMAKU", name: "save-path", want: "DANMAKU_SAVE_PATH"},
+		{prefix: "DANMAKU_SERVER", name: "admin-token", want: "DANMAKU_SERVER_ADMIN_TOKEN"},
+		{prefix: "DANMAKU_PLATFORMS", name: "plugin.demo", want: "DANMAKU_PLATFORMS_PLUGIN_DEMO"},
+	}
+	for _, tt := range tests {
+		if got := envKey(tt.prefix, tt.name); got != tt.want {
+			t.Errorf("envKey(%q, %q) = %q, want %q", tt.prefix, tt.name, got, tt.want)
+		}
+	}
+}
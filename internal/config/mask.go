@@ -0,0 +1,88 @@
+package config
+
+import (
+	"net/url"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// secretKeys 输出配置时需要隐藏的字段
+var secretKeys = []string{"cookie", "token", "tokens", "admin-token", "app-secret", "password"}
+
+const maskedValue = "******"
+
+func isSecret(key string) bool {
+	for i := len(key) - 1; i >= 0; i-- {
+		if key[i] == '.' {
+			key = key[i+1:]
+			break
+		}
+	}
+	// tokens[0]
+	for i := range key {
+		if key[i] == '[' {
+			key = key[:i]
+			break
+		}
+	}
+	return slices.Contains(secretKeys, key)
+}
+
+func maskValue(key, value string) string {
+	if isSecret(key) && value != "" {
+		return maskedValue
+	}
+	return redactUrl(value)
+}
+
+// redactUrl 隐藏url中的密码 比如代理地址
+func redactUrl(value string) string {
+	if !strings.Contains(value, "@") {
+		return value
+	}
+	if u, err := url.Parse(value); err == nil && u.User != nil {
+		return u.Redacted()
+	}
+	return value
+}
+
+// MarshalMasked 输出 yaml 格式配置 敏感字段使用 ****** 代替
+func MarshalMasked(conf *DanmakuConfig) ([]byte, error) {
+	var node yaml.Node
+	if err := node.Encode(conf); err != nil {
+		return nil, err
+	}
+	maskNode(&node)
+	return yaml.Marshal(&node)
+}
+
+func maskNode(node *yaml.Node) {
+	if node.Kind != yaml.MappingNode {
+		for _, n := range node.Content {
+			maskNode(n)
+		}
+		return
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := node.Content[i], node.Content[i+1]
+		if !slices.Contains(secretKeys, key.Value) {
+			if value.Kind == yaml.ScalarNode {
+				value.Value = redactUrl(value.Value)
+			}
+			maskNode(value)
+			continue
+		}
+		if value.Kind == yaml.ScalarNode && value.Value != "" {
+			value.Value = maskedValue
+		}
+		if value.Kind == yaml.SequenceNode {
+			for _, item := range value.Content {
+				if item.Kind == yaml.ScalarNode && item.Value != "" {
+					item.Value = maskedValue
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,250 @@
+package config
+
+import (
+	"bytes"
+	"danmaku-tool/internal/utils"
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Schema 配置校验时可选值 由调用方提供 避免循环依赖
+type Schema struct {
+	Platforms   []string // 内置平台
+	Persists    []string // 弹幕文件类型
+	MatchModes  []string // tokenizer 匹配模式
+	DandanModes []string // dandan api 模式
+}
+
+const (
+	LevelError = "error"
+	LevelWarn  = "warn"
+)
+
+// Issue 配置问题 Path 为 yaml 路径
+type Issue struct {
+	Level   string
+	Path    string
+	Message string
+}
+
+func (i Issue) String() string {
+	if i.Path == "" {
+		return fmt.Sprintf("[%s] %s", i.Level, i.Message)
+	}
+	return fmt.Sprintf("[%s] %s: %s", i.Level, i.Path, i.Message)
+}
+
+// Validate 校验配置文件 包括未知字段、平台名称、弹幕文件类型、正则、超时时间以及token 环境变量覆盖后再校验
+func Validate(file []byte, schema Schema) []Issue {
+	var issues []Issue
+	var conf DanmakuConfig
+	decoder := yaml.NewDecoder(bytes.NewReader(file))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&conf); err != nil {
+		var typeErr *yaml.TypeError
+		if !errors.As(err, &typeErr) {
+			// 语法错误 无法继续校验
+			return []Issue{{Level: LevelError, Message: err.Error()}}
+		}
+		// 未知字段或者类型错误 其余字段依旧会被解析
+		for _, e := range typeErr.Errors {
+			issues = append(issues, Issue{Level: LevelError, Message: e})
+		}
+	}
+	if _, err := applyEnv(&conf); err != nil {
+		issues = append(issues, Issue{Level: LevelError, Message: err.Error()})
+	}
+	return append(issues, validate(&conf, schema)...)
+}
+
+func validate(conf *DanmakuConfig, schema Schema) []Issue {
+	var issues []Issue
+	addError := func(path, format string, args ...any) {
+		issues = append(issues, Issue{Level: LevelError, Path: path, Message: fmt.Sprintf(format, args...)})
+	}
+	addWarn := func(path, format string, args ...any) {
+		issues = append(issues, Issue{Level: LevelWarn, Path: path, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if len(schema.DandanModes) > 0 && !slices.Contains(schema.DandanModes, conf.DandanMode) {
+		addError("dandan-mode", "unknown mode %q, available: %s", conf.DandanMode, strings.Join(schema.DandanModes, ", "))
+	}
+	if conf.DandanTimeout < 0 {
+		addError("dandan-timeout", "must not be negative")
+	}
+	if conf.Server.Timeout < 0 {
+		addError("server.timeout", "must not be negative")
+	}
+	if conf.Server.Port < 0 || conf.Server.Port > 65535 {
+		addError("server.port", "invalid port %d", conf.Server.Port)
+	}
+	if len(conf.Server.Tokens) == 0 {
+		addWarn("server.tokens", "no token configured, dandan api is not accessible in server mode")
+	}
+	for i, t := range conf.Server.Tokens {
+		if strings.TrimSpace(t) == "" {
+			addError(fmt.Sprintf("server.tokens[%d]", i), "token is empty")
+		}
+	}
+
+	validateCache(conf.Server.Cache, addError)
+	if conf.Server.Prefetch.Episodes < 0 {
+		addError("server.prefetch.episodes", "must not be negative")
+	}
+	if conf.Server.Prefetch.Workers < 0 {
+		addError("server.prefetch.workers", "must not be negative")
+	}
+	if conf.Server.Prefetch.Enable && conf.Server.Cache.Type == "none" {
+		addWarn("server.prefetch", "prefetch has no effect when cache is disabled")
+	}
+	if conf.Server.Jobs.Workers < 0 {
+		addError("server.jobs.workers", "must not be negative")
+	}
+	if conf.Server.Jobs.History < 0 {
+		addError("server.jobs.history", "must not be negative")
+	}
+	validateProxy("emby.proxy", conf.Emby.Proxy, addError)
+	for i, h := range conf.Refresh.Schedule {
+		if h <= 0 {
+			addError(fmt.Sprintf("refresh.schedule[%d]", i), "must be positive")
+		}
+	}
+	if conf.Refresh.Interval < 0 {
+		addError("refresh.interval", "must not be negative")
+	}
+	if conf.Watch.Interval < 0 {
+		addError("watch.interval", "must not be negative")
+	}
+	if conf.SaveTemplate != "" {
+		if tpl, err := utils.ParsePathTemplate(conf.SaveTemplate, SaveTemplateFields); err != nil {
+			addError("save-template", "%s", err)
+		} else if !tpl.Uses("ep", "ep_id", "ep_title") {
+			addWarn("save-template", "no episode placeholder, episodes of the same media overwrite each other")
+		}
+	}
+	if conf.Watch.Enable && conf.SavePath == "" {
+		addWarn("save-path", "watch is enabled but save-path is empty, files are saved to the working directory")
+	}
+
+	for i, r := range conf.Tokenizer.Blacklist {
+		path := fmt.Sprintf("tokenizer.blacklist[%d]", i)
+		if r.Regex == "" {
+			addError(path+".regex", "regex is empty")
+		} else if _, err := regexp.Compile(r.Regex); err != nil {
+			addError(path+".regex", "invalid regex: %s", err)
+		}
+		if r.Mode != "" && len(schema.MatchModes) > 0 && !slices.Contains(schema.MatchModes, r.Mode) {
+			addError(path+".mode", "unknown mode %q, available: %s", r.Mode, strings.Join(schema.MatchModes, ", "))
+		}
+	}
+	for i, r := range conf.Filter.Blacklist {
+		path := fmt.Sprintf("filter.blacklist[%d]", i)
+		if r.Keyword == "" && r.Regex == "" {
+			addError(path, "keyword or regex is required")
+		}
+		if r.Regex != "" {
+			if _, err := regexp.Compile(r.Regex); err != nil {
+				addError(path+".regex", "invalid regex: %s", err)
+			}
+		}
+	}
+
+	var names []string
+	for i, p := range conf.Platforms {
+		path := fmt.Sprintf("platforms[%d]", i)
+		if p.Name != "" {
+			path = "platforms." + p.Name
+		}
+		switch {
+		case p.Name == "":
+			addError(path+".name", "name is required")
+		case slices.Contains(names, p.Name):
+			addError(path+".name", "duplicate platform")
+		case p.Plugin != nil:
+			if slices.Contains(schema.Platforms, p.Name) {
+				addError(path+".name", "plugin name conflicts with builtin platform")
+			}
+			if p.Plugin.Command == "" && p.Plugin.Url == "" {
+				addError(path+".plugin", "command or url is required")
+			}
+		case len(schema.Platforms) > 0 && !slices.Contains(schema.Platforms, p.Name):
+			addError(path+".name", "unknown platform %q, available: %s", p.Name, strings.Join(schema.Platforms, ", "))
+		}
+		names = append(names, p.Name)
+
+		if p.Timeout < 0 {
+			addError(path+".timeout", "must not be negative")
+		}
+		if p.MaxWorker < 0 {
+			addError(path+".max-worker", "must not be negative")
+		}
+		if p.MergeDanmakuInMills < 0 {
+			addError(path+".merge-danmaku-in-mills", "must not be negative")
+		}
+		if p.DensityWindowInMills < 0 {
+			addError(path+".density-window-in-mills", "must not be negative")
+		}
+		validateProxy(path+".proxy", p.Proxy, addError)
+		if p.Qps < 0 {
+			addError(path+".qps", "must not be negative")
+		}
+		if p.Burst < 0 {
+			addError(path+".burst", "must not be negative")
+		}
+		if p.RetryBudget < 0 {
+			addError(path+".retry-budget", "must not be negative")
+		}
+		for j, s := range p.Persists {
+			if len(schema.Persists) > 0 && !slices.Contains(schema.Persists, s) {
+				addError(fmt.Sprintf("%s.persists[%d]", path, j), "unknown type %q, available: %s", s, strings.Join(schema.Persists, ", "))
+			}
+		}
+		if p.Name == "dandan" && p.Url == "" {
+			addError(path+".url", "url is required")
+		}
+	}
+	return issues
+}
+
+func validateCache(cache CacheConfig, addError func(path, format string, args ...any)) {
+	if cache.Type != "" && !slices.Contains([]string{"memory", "disk", "tiered", "none"}, cache.Type) {
+		addError("server.cache.type", "unknown type %q, available: memory, disk, tiered, none", cache.Type)
+	}
+	if cache.MemorySize < 0 {
+		addError("server.cache.memory-size", "must not be negative")
+	}
+	if cache.DiskSize < 0 {
+		addError("server.cache.disk-size", "must not be negative")
+	}
+	if cache.EvictionInterval < 0 {
+		addError("server.cache.eviction-interval", "must not be negative")
+	}
+	for route := range cache.Routes {
+		if !slices.Contains([]string{"comment", "search", "bangumi"}, route) {
+			addError("server.cache.routes."+route, "unknown route, available: comment, search, bangumi")
+		}
+	}
+}
+
+func validateProxy(path string, proxy ProxyConfig, addError func(path, format string, args ...any)) {
+	if proxy.Url == "" {
+		return
+	}
+	u, err := url.Parse(proxy.Url)
+	if err != nil {
+		addError(path+".url", "invalid url: %s", err)
+		return
+	}
+	if !slices.Contains([]string{"http", "https", "socks5", "socks5h"}, u.Scheme) {
+		addError(path+".url", "unsupported scheme %q, available: http, https, socks5, socks5h", u.Scheme)
+	}
+	if u.Host == "" {
+		addError(path+".url", "host is empty")
+	}
+}
@@ -0,0 +1,126 @@
+package danmaku
+
+import (
+	"danmaku-tool/internal/utils"
+	"math"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const densityC = "density"
+
+const defaultDensityWindowInMills = 1000
+
+// scoreDanmaku 评估单条弹幕价值 点赞 > 独特性 > 长度 > 颜色/模式
+func scoreDanmaku(d *StandardDanmaku, freq int) float64 {
+	var score = math.Log1p(float64(max(d.Likes, 0)))
+	// 重复越多的内容价值越低 合并之后依旧可能存在跨窗口重复
+	score += 1 / float64(max(freq, 1))
+	// 过短的弹幕信息量少 过长的弹幕遮挡严重 20字以内线性加分
+	score += float64(min(utf8.RuneCountInString(strings.TrimSpace(d.Content)), 20)) / 20
+	if d.Color != WhiteColor {
+		score += 0.3
+	}
+	if d.Mode == TopMode || d.Mode == BottomMode {
+		score += 0.2
+	}
+	return score
+}
+
+// ThinDanmaku 弹幕密度控制 任意 windowInMills 时间窗口内最多保留 maxPerWindow 条弹幕
+// 按弹幕价值从高到低依次尝试放入，放入后会导致某个窗口超出上限则丢弃，返回结果保持原有顺序
+func ThinDanmaku(dms []*StandardDanmaku, windowInMills int64, maxPerWindow int) []*StandardDanmaku {
+	if maxPerWindow <= 0 || len(dms) <= maxPerWindow {
+		return dms
+	}
+	if windowInMills <= 0 {
+		windowInMills = defaultDensityWindowInMills
+	}
+	var start = time.Now()
+
+	var keys = make([]string, len(dms))
+	var freq = make(map[string]int, len(dms))
+	for i, d := range dms {
+		keys[i] = NormalizeContent(d.Content)
+		freq[keys[i]]++
+	}
+	var scores = make([]float64, len(dms))
+	var order = make([]int, len(dms))
+	for i, d := range dms {
+		scores[i] = scoreDanmaku(d, freq[keys[i]])
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return scores[order[i]] > scores[order[j]]
+	})
+
+	// 已接受的弹幕时间按窗口大小分桶 每个桶内有序
+	// 与 t 处于同一窗口的弹幕只可能在相邻的三个桶内
+	var buckets = make(map[int64][]int64)
+	var kept = make([]bool, len(dms))
+	var keptSize int
+	var neighbors = make([]int64, 0, 3*maxPerWindow+1)
+
+	for _, idx := range order {
+		t := dms[idx].OffsetMills
+		bid := floorDiv(t, windowInMills)
+
+		neighbors = neighbors[:0]
+		for b := bid - 1; b <= bid+1; b++ {
+			for _, v := range buckets[b] {
+				if v > t-windowInMills && v < t+windowInMills {
+					neighbors = append(neighbors, v)
+				}
+			}
+		}
+		if len(neighbors) >= maxPerWindow && overflow(neighbors, t, windowInMills, maxPerWindow) {
+			continue
+		}
+
+		bucket := buckets[bid]
+		pos := sort.Search(len(bucket), func(i int) bool { return bucket[i] > t })
+		bucket = append(bucket, 0)
+		copy(bucket[pos+1:], bucket[pos:])
+		bucket[pos] = t
+		buckets[bid] = bucket
+		kept[idx] = true
+		keptSize++
+	}
+
+	var result = make([]*StandardDanmaku, 0, keptSize)
+	for i, d := range dms {
+		if kept[i] {
+			result = append(result, d)
+		}
+	}
+
+	utils.DebugLog(densityC, "danmaku density thinned", "before", len(dms), "after", len(result),
+		"window_ms", windowInMills, "max", maxPerWindow, "cost_ms", time.Since(start).Milliseconds())
+	return result
+}
+
+// overflow 将 t 加入有序的 neighbors 后 是否存在包含 t 的 maxPerWindow+1 条弹幕处于同一时间窗口内
+func overflow(neighbors []int64, t int64, windowInMills int64, maxPerWindow int) bool {
+	pos := sort.Search(len(neighbors), func(i int) bool { return neighbors[i] > t })
+	points := make([]int64, 0, len(neighbors)+1)
+	points = append(points, neighbors[:pos]...)
+	points = append(points, t)
+	points = append(points, neighbors[pos:]...)
+
+	for i := max(0, pos-maxPerWindow); i <= pos && i+maxPerWindow < len(points); i++ {
+		if points[i+maxPerWindow]-points[i] < windowInMills {
+			return true
+		}
+	}
+	return false
+}
+
+func floorDiv(a, b int64) int64 {
+	q := a / b
+	if a%b != 0 && (a < 0) != (b < 0) {
+		q--
+	}
+	return q
+}
@@ -0,0 +1,90 @@
+package danmaku
+
+import (
+	"slices"
+	"strconv"
+	"testing"
+)
+
+// maxInWindow 任意 window 毫秒窗口内的最大弹幕数量
+func maxInWindow(dms []*StandardDanmaku, window int64) int {
+	var offsets = make([]int64, 0, len(dms))
+	for _, d := range dms {
+		offsets = append(offsets, d.OffsetMills)
+	}
+	slices.Sort(offsets)
+	var result, left int
+	for right := range offsets {
+		for offsets[right]-offsets[left] >= window {
+			left++
+		}
+		result = max(result, right-left+1)
+	}
+	return result
+}
+
+// burst 从 start 开始每隔 step 毫秒一条内容不同的弹幕
+func burst(size int, start, step int64) []*StandardDanmaku {
+	var dms = make([]*StandardDanmaku, 0, size)
+	for i := 0; i < size; i++ {
+		dms = append(dms, &StandardDanmaku{
+			OffsetMills: start + int64(i)*step,
+			Mode:        1,
+			Color:       WhiteColor,
+			Content:     "弹幕" + strconv.Itoa(i),
+		})
+	}
+	return dms
+}
+
+func TestThinDanmaku(t *testing.T) {
+	tests := []struct {
+		name         string
+		dms          []*StandardDanmaku
+		window       int64
+		maxPerWindow int
+		wantSize     int
+	}{
+		{name: "disabled", dms: burst(10, 0, 10), window: 1000, maxPerWindow: 0, wantSize: 10},
+		{name: "under cap", dms: burst(3, 0, 10), window: 1000, maxPerWindow: 3, wantSize: 3},
+		{name: "burst capped", dms: burst(10, 0, 10), window: 1000, maxPerWindow: 3, wantSize: 3},
+		{name: "sparse kept", dms: burst(10, 0, 1000), window: 1000, maxPerWindow: 1, wantSize: 10},
+		{name: "default window", dms: burst(10, 0, 100), window: 0, maxPerWindow: 2, wantSize: 2},
+		{name: "two bursts", dms: append(burst(5, 0, 10), burst(5, 5000, 10)...), window: 1000, maxPerWindow: 2, wantSize: 4},
+		{name: "sliding window", dms: burst(20, 0, 250), window: 1000, maxPerWindow: 2, wantSize: 10},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ThinDanmaku(tt.dms, tt.window, tt.maxPerWindow)
+			if len(got) != tt.wantSize {
+				t.Fatalf("size = %d, want %d", len(got), tt.wantSize)
+			}
+			window := tt.window
+			if window <= 0 {
+				window = defaultDensityWindowInMills
+			}
+			if tt.maxPerWindow > 0 {
+				if n := maxInWindow(got, window); n > tt.maxPerWindow {
+					t.Errorf("%d danmaku in one window, want at most %d", n, tt.maxPerWindow)
+				}
+			}
+			if !slices.IsSortedFunc(got, func(a, b *StandardDanmaku) int { return int(a.OffsetMills - b.OffsetMills) }) {
+				t.Errorf("result order changed")
+			}
+		})
+	}
+}
+
+func TestThinDanmakuPrefersValuable(t *testing.T) {
+	dms := burst(5, 0, 10)
+	dms[3].Likes = 100
+	dms[1].Color = 0xFF0000
+	got := ThinDanmaku(dms, 1000, 2)
+	if len(got) != 2 || got[0] != dms[1] || got[1] != dms[3] {
+		var contents []string
+		for _, d := range got {
+			contents = append(contents, d.Content)
+		}
+		t.Fatalf("kept %v, want [弹幕1 弹幕3]", contents)
+	}
+}
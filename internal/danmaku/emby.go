@@ -7,6 +7,7 @@ import (
 	"net/http"
 	"net/url"
 	"strconv"
+	"sync/atomic"
 	"time"
 )
 
@@ -31,8 +32,26 @@ type EmbyItem struct {
 	ParentIndexNumber int `json:"ParentIndexNumber"`
 }
 
-var embyClient = http.Client{
-	Timeout: time.Second * 10,
+// embyClient 与配置绑定 配置热加载后重新创建
+type embyClient struct {
+	conf   *config.DanmakuConfig
+	client *http.Client
+}
+
+var emby atomic.Pointer[embyClient]
+
+func getEmbyClient() (*http.Client, error) {
+	conf := config.GetConfig()
+	if c := emby.Load(); c != nil && c.conf == conf {
+		return c.client, nil
+	}
+	transport, err := NewProxyTransport(conf.Emby.Proxy)
+	if err != nil {
+		return nil, fmt.Errorf("emby %w", err)
+	}
+	client := &http.Client{Timeout: time.Second * 10, Transport: transport}
+	emby.Store(&embyClient{conf: conf, client: client})
+	return client, nil
 }
 
 const (
@@ -68,7 +87,11 @@ func doEmbyGet[T any](api string) (*T, error) {
 	req.Header.Set("X-Emby-Client", "danmaku-tool")
 	req.Header.Set("X-Emby-Device-Name", "danmaku-tool")
 
-	resp, err := embyClient.Do(req)
+	client, err := getEmbyClient()
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.Do(req)
 	if err != nil {
 		return nil, err
 	}
@@ -94,3 +117,13 @@ func GetSeasons(id string, recursive bool) (*EmbySearchResult, error) {
 
 	return doEmbyGet[EmbySearchResult](api)
 }
+
+type EmbySystemInfo struct {
+	ServerName string `json:"ServerName"`
+	Version    string `json:"Version"`
+}
+
+// PingEmby 检查emby服务是否可以访问 同时校验token
+func PingEmby() (*EmbySystemInfo, error) {
+	return doEmbyGet[EmbySystemInfo](config.GetConfig().Emby.Url + "/emby/System/Info")
+}
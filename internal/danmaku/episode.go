@@ -0,0 +1,56 @@
+package danmaku
+
+import (
+	"context"
+	"fmt"
+)
+
+/*
+	单集抓取 订阅以及后台任务按照 Media 返回的单集逐个抓取
+	平台实现 EpisodeScraper 时与 Scrape 保存的文件一致 包括文件名、视频时长等信息
+	未实现的平台使用 GetDanmaku 获取弹幕 保存在 save-path/{platform}/{剧集id}/{单集id}
+	ctx 取消后平台不再请求剩余的分片 已经获取的部分弹幕不会保存
+*/
+
+// ScrapeEpisode 抓取并保存单集弹幕 返回写入的文件以及弹幕数量 没有写入任何文件时返回错误
+func ScrapeEpisode(ctx context.Context, scraper Scraper, media *Media, ep *MediaEpisode) ([]string, int, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, 0, err
+	}
+	var (
+		files []string
+		count int
+		err   error
+	)
+	if s, ok := scraper.(EpisodeScraper); ok {
+		files, count, err = s.ScrapeEpisode(ctx, ep.Id)
+	} else {
+		files, count, err = writeEpisode(ctx, scraper, media, ep)
+	}
+	if err == nil && len(files) == 0 {
+		err = fmt.Errorf("no file written")
+	}
+	return files, count, err
+}
+
+// writeEpisode 通用的单集保存 视频时长未知 获取弹幕期间取消则不保存
+func writeEpisode(ctx context.Context, scraper Scraper, media *Media, ep *MediaEpisode) ([]string, int, error) {
+	savePath, err := MediaSavePath(scraper.Platform(), media.Id)
+	if err != nil {
+		return nil, 0, err
+	}
+	data, err := scraper.GetDanmaku(ep.Id)
+	if err != nil {
+		return nil, 0, err
+	}
+	if err = ctx.Err(); err != nil {
+		return nil, 0, err
+	}
+	serializer := &SerializerData{
+		SeasonId:  media.Id,
+		EpisodeId: ep.Id,
+		Data:      data,
+	}
+	serializer.SetMedia(media, ep)
+	return WriteFile(scraper.Platform(), serializer, savePath, EpisodeFilename(ep.Id)), len(data), nil
+}
@@ -0,0 +1,186 @@
+package danmaku
+
+import (
+	"danmaku-tool/internal/config"
+	"danmaku-tool/internal/utils"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unicode/utf8"
+)
+
+const filterC = "filter"
+
+var (
+	punctuationRegex = regexp.MustCompile(`^[\p{P}\p{S}\s]+$`)
+	urlRegex         = regexp.MustCompile(`(?i)(https?://|www\.)\S+|[a-z0-9-]+\.(com|cn|net|org|top|xyz|cc|io|me|tv)\b`)
+	contactRegex     = regexp.MustCompile(`(?i)((qq|扣扣|企鹅|vx|wx|微信|威信)\s*(号|群)?\s*[:：]?\s*[a-z0-9_-]{5,})|(^|\D)1[3-9]\d{9}($|\D)`)
+)
+
+// danmakuFilter 单条过滤规则 drop返回true则过滤掉该弹幕
+type danmakuFilter struct {
+	name string
+	drop func(d *StandardDanmaku) bool
+}
+
+// compiledFilters 与配置绑定 配置热加载替换后首次使用时重新生成 按照平台缓存
+type compiledFilters struct {
+	conf      *config.DanmakuConfig
+	platforms sync.Map // Platform -> []danmakuFilter
+}
+
+var filterRules atomic.Pointer[compiledFilters]
+
+func getFilters(platform Platform) []danmakuFilter {
+	conf := config.GetConfig()
+	c := filterRules.Load()
+	if c == nil || c.conf != conf {
+		c = &compiledFilters{conf: conf}
+		filterRules.Store(c)
+	}
+	if v, ok := c.platforms.Load(platform); ok {
+		return v.([]danmakuFilter)
+	}
+	filters := buildFilters(conf.Filter, platform)
+	c.platforms.Store(platform, filters)
+	return filters
+}
+
+// buildFilters 根据配置生成平台对应的过滤规则
+func buildFilters(conf config.FilterConfig, platform Platform) []danmakuFilter {
+	if !conf.Enable {
+		return nil
+	}
+	var filters []danmakuFilter
+
+	for _, m := range conf.DropModes {
+		mode := m
+		filters = append(filters, danmakuFilter{
+			name: "mode:" + strconv.FormatInt(int64(mode), 10),
+			drop: func(d *StandardDanmaku) bool { return d.Mode == mode },
+		})
+	}
+	if conf.MinLength > 0 {
+		filters = append(filters, danmakuFilter{
+			name: "min_length",
+			drop: func(d *StandardDanmaku) bool {
+				return utf8.RuneCountInString(strings.TrimSpace(d.Content)) < conf.MinLength
+			},
+		})
+	}
+	if conf.MaxLength > 0 {
+		filters = append(filters, danmakuFilter{
+			name: "max_length",
+			drop: func(d *StandardDanmaku) bool {
+				return utf8.RuneCountInString(strings.TrimSpace(d.Content)) > conf.MaxLength
+			},
+		})
+	}
+
+	for _, r := range conf.Blacklist {
+		// 全平台 或者 特定平台
+		if r.Platform != "" && r.Platform != string(platform) {
+			continue
+		}
+		if r.Keyword != "" {
+			keyword := r.Keyword
+			filters = append(filters, danmakuFilter{
+				name: "keyword:" + keyword,
+				drop: func(d *StandardDanmaku) bool { return strings.Contains(d.Content, keyword) },
+			})
+		}
+		if r.Regex != "" {
+			re, err := regexp.Compile(r.Regex)
+			if err != nil {
+				utils.ErrorLog(filterC, "invalid filter regex", "regex", r.Regex, "error", err)
+				continue
+			}
+			filters = append(filters, danmakuFilter{
+				name: "regex:" + r.Regex,
+				drop: func(d *StandardDanmaku) bool { return re.MatchString(d.Content) },
+			})
+		}
+	}
+
+	spam := conf.Spam
+	if spam.Punctuation {
+		filters = append(filters, danmakuFilter{
+			name: "spam_punctuation",
+			drop: func(d *StandardDanmaku) bool { return punctuationRegex.MatchString(d.Content) },
+		})
+	}
+	if spam.Url {
+		filters = append(filters, danmakuFilter{
+			name: "spam_url",
+			drop: func(d *StandardDanmaku) bool { return urlRegex.MatchString(d.Content) },
+		})
+	}
+	if spam.Contact {
+		filters = append(filters, danmakuFilter{
+			name: "spam_contact",
+			drop: func(d *StandardDanmaku) bool { return contactRegex.MatchString(d.Content) },
+		})
+	}
+	if spam.RepeatChars > 0 {
+		filters = append(filters, danmakuFilter{
+			name: "spam_repeat_chars",
+			drop: func(d *StandardDanmaku) bool { return maxRepeatRunes(d.Content) >= spam.RepeatChars },
+		})
+	}
+
+	return filters
+}
+
+// maxRepeatRunes 同一字符最长连续重复次数
+func maxRepeatRunes(s string) int {
+	var maxCount, count int
+	var last rune = -1
+	for _, r := range s {
+		if r == last {
+			count++
+		} else {
+			last = r
+			count = 1
+		}
+		if count > maxCount {
+			maxCount = count
+		}
+	}
+	return maxCount
+}
+
+// FilterDanmaku 按照配置规则过滤弹幕 返回过滤后的弹幕以及每条规则过滤的数量
+func FilterDanmaku(platform Platform, dms []*StandardDanmaku) ([]*StandardDanmaku, map[string]int) {
+	filters := getFilters(platform)
+	if len(filters) == 0 {
+		return dms, nil
+	}
+	var start = time.Now()
+	var stats = make(map[string]int, len(filters))
+	var result = make([]*StandardDanmaku, 0, len(dms))
+
+	for _, d := range dms {
+		dropped := false
+		for _, f := range filters {
+			if f.drop(d) {
+				stats[f.name]++
+				dropped = true
+				break
+			}
+		}
+		if !dropped {
+			result = append(result, d)
+		}
+	}
+
+	var args = []any{"platform", platform, "before", len(dms), "after", len(result), "cost_ms", time.Since(start).Milliseconds()}
+	for name, count := range stats {
+		args = append(args, name, count)
+	}
+	utils.InfoLog(filterC, "danmaku filtered", args...)
+
+	return result, stats
+}
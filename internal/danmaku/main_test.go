@@ -0,0 +1,12 @@
+package danmaku
+
+import (
+	"danmaku-tool/internal/utils"
+	"os"
+	"testing"
+)
+
+func TestMain(m *testing.M) {
+	utils.InitLogger(false, false)
+	os.Exit(m.Run())
+}
@@ -1,7 +1,13 @@
 package danmaku
 
 import (
+	"context"
+	"danmaku-tool/internal/utils"
 	"net/http"
+	"net/url"
+	"slices"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -52,9 +58,69 @@ type MediaEpisode struct {
 }
 
 type PlatformClient struct {
-	MaxWorker  int
-	Cookie     string
-	HttpClient *http.Client
+	// 平台配置 配置热加载时整体替换 进行中的请求不受影响
+	settings atomic.Pointer[clientSettings]
+
+	lastSuccess atomic.Int64 // 最近一次请求成功的时间 unix ms
+	lastFailure atomic.Int64 // 最近一次请求失败的时间 unix ms
+}
+
+type clientSettings struct {
+	platform   Platform
+	maxWorker  int
+	cookie     string
+	httpClient *http.Client
+
+	limiter     *tokenBucket // 请求限流 为空则不限流
+	maxRetries  int
+	retryBudget *tokenBucket // 重试预算
+}
+
+func (p *PlatformClient) MaxWorker() int {
+	return p.settings.Load().maxWorker
+}
+
+func (p *PlatformClient) Cookie() string {
+	return p.settings.Load().cookie
+}
+
+func (p *PlatformClient) HttpClient() *http.Client {
+	return p.settings.Load().httpClient
+}
+
+// platformClient 用于配置热加载时获取scraper内嵌的 PlatformClient
+func (p *PlatformClient) platformClient() *PlatformClient {
+	return p
+}
+
+// Reloader 配置热加载后执行 可选实现
+type Reloader interface {
+	Reload() error
+}
+
+// Prober 平台可用性探测 比如cookie是否有效、接口签名token能否获取 可选实现
+type Prober interface {
+	Probe() error
+}
+
+// RelatedScraper 可以选择是否附带第三方关联弹幕的平台 比如上游dandan api 可选实现
+type RelatedScraper interface {
+	GetDanmakuWithRelated(id string, withRelated bool) ([]*StandardDanmaku, error)
+}
+
+// AirTimer 播出时间 用于定时刷新 id同 Scrape 以及 GetDanmaku 剧集id返回最新一集的播出时间 可选实现
+type AirTimer interface {
+	AirTime(id string) (time.Time, error)
+}
+
+// URLResolver 从平台网页链接解析 Scrape 使用的id 可选实现 不是该平台的链接返回空id
+type URLResolver interface {
+	ResolveURL(u *url.URL) (string, error)
+}
+
+// EpisodeScraper 抓取并保存剧集中的单集 id同 MediaEpisode.Id 保存路径以及文件名与 Scrape 一致 返回写入的文件以及弹幕数量 可选实现
+type EpisodeScraper interface {
+	ScrapeEpisode(ctx context.Context, id string) ([]string, int, error)
 }
 
 type Scraper interface {
@@ -81,9 +147,17 @@ type SerializerData struct {
 	SeasonId, EpisodeId string
 	// ass 文件用
 	ResX, ResY int // 视频分辨率
+	// 保存路径模板用 可选
+	Title                       string // 剧集标题
+	Year                        int
+	SeasonNumber, EpisodeNumber int // 季数未设置则从标题解析
+	EpisodeTitle                string
 }
 type DataSerializer interface {
-	Serialize(data *SerializerData) error
+	// Serialize 序列化并写入文件 返回写入的文件
+	Serialize(data *SerializerData) (string, error)
+	// Marshal 只序列化 用于接口下载
+	Marshal(data *SerializerData) ([]byte, error)
 	Type() string
 }
 
@@ -119,6 +193,7 @@ type StandardDanmaku struct {
 	// 以下字段用于其他记录
 	FontSize int32 // 字体大小
 	Platform Platform
+	Likes    int64 // 点赞数 用于弹幕密度控制时评估弹幕价值
 }
 
 type MatchParam struct {
@@ -146,18 +221,27 @@ const TopMode = 5
 
 type manager struct {
 	scrapers     []Scraper
+	scrapersLock sync.RWMutex // 配置热加载时会重新注册平台
 	initializers []interface{}
 	serializers  map[string]DataSerializer
+	// 运行时禁用的平台
+	disabled     map[Platform]bool
+	disabledLock sync.RWMutex
 }
 
 var adapter = &manager{
 	scrapers:     []Scraper{},
 	initializers: []interface{}{},
 	serializers:  map[string]DataSerializer{},
+	disabled:     map[Platform]bool{},
 }
 
+// GetScraper 获取已启用的平台
 func GetScraper(platform string) Scraper {
-	for _, v := range adapter.scrapers {
+	if !PlatformEnabled(Platform(platform)) {
+		return nil
+	}
+	for _, v := range GetScrapers() {
 		if string(v.Platform()) == platform {
 			return v
 		}
@@ -166,7 +250,10 @@ func GetScraper(platform string) Scraper {
 }
 
 func GetMediaService(platform string) MediaService {
-	for _, v := range adapter.scrapers {
+	if !PlatformEnabled(Platform(platform)) {
+		return nil
+	}
+	for _, v := range GetScrapers() {
 		if platform == string(v.Platform()) {
 			if s, ok := v.(MediaService); ok {
 				return s
@@ -176,17 +263,90 @@ func GetMediaService(platform string) MediaService {
 	return nil
 }
 
+// AirTime 平台提供的播出时间 不支持或者获取失败返回零值
+func AirTime(platform, id string) time.Time {
+	a, ok := GetScraper(platform).(AirTimer)
+	if !ok {
+		return time.Time{}
+	}
+	aired, err := a.AirTime(id)
+	if err != nil {
+		utils.DebugLog(managerUtilC, "get air time failed", "platform", platform, "id", id, "error", err)
+		return time.Time{}
+	}
+	return aired
+}
+
+func GetSerializer(t string) DataSerializer {
+	return adapter.serializers[t]
+}
+
+// GetSerializerTypes 所有支持的弹幕文件类型
+func GetSerializerTypes() []string {
+	var types = make([]string, 0, len(adapter.serializers))
+	for t := range adapter.serializers {
+		types = append(types, t)
+	}
+	slices.Sort(types)
+	return types
+}
+
 func GetInitializers() []interface{} {
 	return adapter.initializers
 }
 
 func GetPlatforms() []string {
 	return []string{
-		Bilibili, Tencent, Youku, Iqiyi,
+		Bilibili, Tencent, Youku, Iqiyi, Mgtv, Acfun, Dandan,
+	}
+}
+
+// GetScrapers 获取所有已注册的平台 包含禁用的平台
+func GetScrapers() []Scraper {
+	adapter.scrapersLock.RLock()
+	defer adapter.scrapersLock.RUnlock()
+	return slices.Clone(adapter.scrapers)
+}
+
+// GetEnabledScrapers 获取所有已启用的平台
+func GetEnabledScrapers() []Scraper {
+	scrapers := GetScrapers()
+	var result = make([]Scraper, 0, len(scrapers))
+	for _, s := range scrapers {
+		if PlatformEnabled(s.Platform()) {
+			result = append(result, s)
+		}
+	}
+	return result
+}
+
+func PlatformEnabled(platform Platform) bool {
+	adapter.disabledLock.RLock()
+	defer adapter.disabledLock.RUnlock()
+	return !adapter.disabled[platform]
+}
+
+// SetPlatformEnabled 运行时启用或者禁用平台 禁用后不再参与匹配以及弹幕获取
+func SetPlatformEnabled(platform Platform, enabled bool) {
+	adapter.disabledLock.Lock()
+	defer adapter.disabledLock.Unlock()
+	if enabled {
+		delete(adapter.disabled, platform)
+	} else {
+		adapter.disabled[platform] = true
 	}
 }
 
+// RegisterScraper 注册平台 同一平台重复注册则替换
 func RegisterScraper(s Scraper) {
+	adapter.scrapersLock.Lock()
+	defer adapter.scrapersLock.Unlock()
+	for i, v := range adapter.scrapers {
+		if v.Platform() == s.Platform() {
+			adapter.scrapers[i] = s
+			return
+		}
+	}
 	adapter.scrapers = append(adapter.scrapers, s)
 }
 
@@ -201,4 +361,7 @@ const (
 	Tencent  = "tencent"
 	Youku    = "youku"
 	Iqiyi    = "iqiyi"
+	Mgtv     = "mgtv"
+	Acfun    = "acfun"
+	Dandan   = "dandan"
 )
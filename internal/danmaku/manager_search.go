@@ -2,15 +2,21 @@ package danmaku
 
 import (
 	"danmaku-tool/internal/config"
+	"danmaku-tool/internal/metrics"
 	"danmaku-tool/internal/utils"
 	"fmt"
 	"sort"
+	"strings"
 	"sync"
 	"time"
 )
 
 const searchMediaC = "search_media"
 
+// matchGroup 合并相同参数的并发匹配请求
+var matchGroup utils.Group[[]*Media]
+
+// MatchMedia 并发匹配所有平台 相同参数的并发请求共享同一次匹配结果 返回的结果不应该被修改
 func MatchMedia(param MatchParam) []*Media {
 	// 如果未设置季信息，则从标题中解析
 	if param.SeasonId < 0 {
@@ -18,6 +24,20 @@ func MatchMedia(param MatchParam) []*Media {
 	}
 	// 预处理标题
 	param.Title = ClearTitleAndSeason(param.Title)
+
+	key := fmt.Sprintf("%s\x00%d\x00%d\x00%d\x00%d\x00%v", strings.ToLower(param.Title), param.SeasonId, param.EpisodeId,
+		param.ProductionYear, param.DurationSeconds, param.Mode)
+	media, _, shared := matchGroup.Do(key, func() ([]*Media, error) {
+		return matchMedia(param), nil
+	})
+	if shared {
+		metrics.CoalescedRequests.Inc(metrics.OpMatch)
+		utils.DebugLog(searchMediaC, "match coalesced", "title", param.Title)
+	}
+	return media
+}
+
+func matchMedia(param MatchParam) []*Media {
 	// 从emby获取年份等信息
 	if config.EmbyEnabled() {
 		search, err := SearchEmby(param.Title, param.SeasonId)
@@ -44,10 +64,11 @@ func MatchMedia(param MatchParam) []*Media {
 		}
 	}
 
-	ch := make(chan []*Media, len(adapter.scrapers))
+	scrapers := GetEnabledScrapers()
+	ch := make(chan []*Media, len(scrapers))
 	wg := sync.WaitGroup{}
-	wg.Add(len(adapter.scrapers))
-	for _, s := range adapter.scrapers {
+	wg.Add(len(scrapers))
+	for _, s := range scrapers {
 		go func(scraper Scraper) {
 			defer wg.Done()
 			// 并发 复制参数进行处理
@@ -59,6 +80,7 @@ func MatchMedia(param MatchParam) []*Media {
 
 			start := time.Now()
 			media, err := scraper.Match(searchParam)
+			metrics.ObservePlatformCall(string(scraper.Platform()), metrics.OpMatch, start, err)
 			if err != nil {
 				utils.ErrorLog(searchMediaC, err.Error(), "platform", scraper.Platform(), "title", param.Title)
 				return
@@ -2,6 +2,7 @@ package danmaku
 
 import (
 	"danmaku-tool/internal/config"
+	"danmaku-tool/internal/metrics"
 	"danmaku-tool/internal/utils"
 	"fmt"
 	"net/http"
@@ -9,11 +10,30 @@ import (
 	"strconv"
 	"strings"
 	"time"
+	"unicode"
 )
 
 const managerUtilC = "manager_util"
 
-func MergeDanmaku(dms []*StandardDanmaku, mergedInMills int64, durationInMills int64) []*StandardDanmaku {
+// ProcessDanmaku 弹幕后处理 过滤 -> 合并 -> 密度控制 同时作用于弹幕文件保存和dandan api
+func ProcessDanmaku(platform Platform, dms []*StandardDanmaku, durationInMills int64) []*StandardDanmaku {
+	dms, _ = FilterDanmaku(platform, dms)
+	conf := config.GetPlatformConfig(string(platform))
+	if conf == nil {
+		return dms
+	}
+	if conf.MergeDanmakuInMills > 0 {
+		dms = MergeDanmaku(dms, conf.MergeDanmakuInMills, durationInMills, conf.MergeAnnotation, conf.MergeMinCount)
+	}
+	if conf.DensityMaxPerWindow > 0 {
+		dms = ThinDanmaku(dms, conf.DensityWindowInMills, conf.DensityMaxPerWindow)
+	}
+	return dms
+}
+
+// MergeDanmaku 合并 mergedInMills 毫秒内的相似弹幕 弹幕内容归一化后相同视为重复
+// 保留最早出现的一条，重复次数达到 minCount 时按照 annotation 模板标注次数
+func MergeDanmaku(dms []*StandardDanmaku, mergedInMills int64, durationInMills int64, annotation string, minCount int) []*StandardDanmaku {
 	var start = time.Now()
 	utils.DebugLog(managerUtilC, "danmaku size merge start", "size", len(dms))
 	if mergedInMills <= 0 {
@@ -27,24 +47,58 @@ func MergeDanmaku(dms []*StandardDanmaku, mergedInMills int64, durationInMills i
 		utils.DebugLog(managerUtilC, "danmaku size merge no duration mills set")
 		initBuckets = 7200 // 2h
 	}
-	buckets := make(map[int64]map[string]bool, initBuckets)
+	// 桶内记录归一化内容对应的保留弹幕在结果中的下标
+	buckets := make(map[int64]map[string]int, initBuckets)
 	var result = make([]*StandardDanmaku, 0, len(dms))
+	var counts = make([]int, 0, len(dms))
+	var likes = make([]int64, 0, len(dms))
 
 	for _, d := range dms {
 		bid := d.OffsetMills / mergedInMills // 所属时间桶
+		key := NormalizeContent(d.Content)
 
 		if _, ok := buckets[bid]; !ok {
 			// 预估长度
-			buckets[bid] = make(map[string]bool, int64(len(dms))/initBuckets+1)
+			buckets[bid] = make(map[string]int, int64(len(dms))/initBuckets+1)
 		}
 
 		// 检查当前桶和前一个桶是否出现过（跨桶重复处理）
-		if buckets[bid][d.Content] || buckets[bid-1][d.Content] {
+		idx, ok := buckets[bid][key]
+		if !ok {
+			idx, ok = buckets[bid-1][key]
+		}
+		if ok {
+			counts[idx]++
+			likes[idx] += d.Likes
 			continue
 		}
 
+		buckets[bid][key] = len(result)
 		result = append(result, d)
-		buckets[bid][d.Content] = true
+		counts = append(counts, 1)
+		likes = append(likes, d.Likes)
+	}
+
+	if annotation != MergeAnnotationNone {
+		if annotation == "" {
+			annotation = defaultMergeAnnotation
+		}
+		if minCount < 2 {
+			minCount = 2
+		}
+		for i, d := range result {
+			if counts[i] < minCount {
+				continue
+			}
+			// 原弹幕可能被缓存共享 不能直接修改
+			merged := *d
+			merged.Content = strings.NewReplacer(
+				"{content}", d.Content,
+				"{count}", strconv.Itoa(counts[i]),
+			).Replace(annotation)
+			merged.Likes = likes[i]
+			result[i] = &merged
+		}
 	}
 
 	utils.DebugLog(managerUtilC, "danmaku size merge end", "size", len(result), "cost_ms", time.Since(start).Milliseconds())
@@ -52,6 +106,56 @@ func MergeDanmaku(dms []*StandardDanmaku, mergedInMills int64, durationInMills i
 	return result
 }
 
+const (
+	// MergeAnnotationNone 合并后不标注重复次数
+	MergeAnnotationNone    = "none"
+	defaultMergeAnnotation = "{content} ×{count}"
+)
+
+// 连续重复字符最多保留的个数
+const maxRepeatRun = 2
+
+// NormalizeContent 弹幕内容归一化 用于判断相似弹幕
+// 全角转半角、忽略大小写和空白、去除emoji变体和肤色修饰、连续重复字符最多保留2个 比如 哈哈哈 哈哈哈哈 均为 哈哈 而 好好学习 与 好学习、666 与 6 不同
+func NormalizeContent(content string) string {
+	var sb strings.Builder
+	sb.Grow(len(content))
+	var last rune = -1
+	var run int
+	for _, r := range content {
+		switch {
+		case r >= 0xFF01 && r <= 0xFF5E: // 全角ASCII
+			r -= 0xFEE0
+		case r == 0x3000: // 全角空格
+			r = ' '
+		}
+		if unicode.IsSpace(r) || isEmojiModifier(r) {
+			continue
+		}
+		r = unicode.ToLower(r)
+		if r == last {
+			run++
+		} else {
+			last = r
+			run = 1
+		}
+		if run > maxRepeatRun {
+			continue
+		}
+		sb.WriteRune(r)
+	}
+	if sb.Len() == 0 {
+		return content
+	}
+	return sb.String()
+}
+
+func isEmojiModifier(r rune) bool {
+	return (r >= 0xFE00 && r <= 0xFE0F) || // 变体选择符
+		(r >= 0x1F3FB && r <= 0x1F3FF) || // 肤色
+		r == 0x200D || r == 0x20E3 // ZWJ 以及键帽组合符
+}
+
 func (d *StandardDanmaku) GenDandanAttribute(text ...string) string {
 	var attr = []string{
 		strconv.FormatFloat(float64(d.OffsetMills)/1000, 'f', 2, 64),
@@ -68,12 +172,77 @@ func (d *StandardDanmaku) GenDandanAttribute(text ...string) string {
 const defaultUA = "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/141.0.0.0 Safari/537.36"
 
 func (p *PlatformClient) DoReq(req *http.Request) (*http.Response, error) {
+	return p.do(p.HttpClient(), req)
+}
+
+// DoReqWithoutRedirect 不跟随重定向 用于获取302跳转地址
+func (p *PlatformClient) DoReqWithoutRedirect(req *http.Request) (*http.Response, error) {
+	client := *p.HttpClient()
+	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+	return p.do(&client, req)
+}
+
+// do 限流后发起请求 412/429/5xx 以及超时按照退避策略重试
+func (p *PlatformClient) do(client *http.Client, req *http.Request) (*http.Response, error) {
 	ua := config.GetConfig().UA
 	if ua == "" {
 		ua = defaultUA
 	}
 	req.Header.Set("User-Agent", ua)
-	return p.HttpClient.Do(req)
+	settings := p.settings.Load()
+	for attempt := 0; ; attempt++ {
+		if err := settings.limiter.wait(req.Context()); err != nil {
+			return nil, err
+		}
+		resp, err := client.Do(req)
+		if err == nil && resp.StatusCode < http.StatusBadRequest {
+			p.lastSuccess.Store(time.Now().UnixMilli())
+		} else {
+			p.lastFailure.Store(time.Now().UnixMilli())
+		}
+
+		delay, retry := retryDelay(resp, err, attempt)
+		// 请求体无法重放的不重试
+		replayable := req.Body == nil || req.GetBody != nil
+		if !retry || !replayable || attempt >= settings.maxRetries || !settings.retryBudget.take() {
+			return resp, err
+		}
+		reason := "timeout"
+		if err == nil {
+			reason = strconv.Itoa(resp.StatusCode)
+		}
+		utils.WarnLog(managerUtilC, "request retry", "platform", settings.platform, "url", req.URL.Host+req.URL.Path,
+			"attempt", attempt+1, "reason", reason, "delay_ms", delay.Milliseconds())
+		metrics.PlatformRetries.Inc(string(settings.platform), reason)
+		drain(resp)
+		if err = sleep(req.Context(), delay); err != nil {
+			return nil, err
+		}
+		if req.GetBody != nil {
+			if req.Body, err = req.GetBody(); err != nil {
+				return nil, err
+			}
+		}
+	}
+}
+
+// LastSuccess 最近一次请求成功的时间 从未成功则返回零值
+func (p *PlatformClient) LastSuccess() time.Time {
+	return unixMilli(p.lastSuccess.Load())
+}
+
+// LastFailure 最近一次请求失败的时间 从未失败则返回零值
+func (p *PlatformClient) LastFailure() time.Time {
+	return unixMilli(p.lastFailure.Load())
+}
+
+func unixMilli(v int64) time.Time {
+	if v > 0 {
+		return time.UnixMilli(v)
+	}
+	return time.Time{}
 }
 
 var SeriesRegex = regexp.MustCompile(`(.*)\sS(\d{1,3})E(\d{1,3})$`)
@@ -144,25 +313,19 @@ func (p MatchParam) MatchTitle(title string) bool {
 	}
 	matchMode := string(p.Mode)
 	// 黑名单 正则匹配替换
-	if config.GetConfig().Tokenizer.Enable && config.GetConfig().Tokenizer.Blacklist != nil {
-		for _, r := range config.GetConfig().Tokenizer.Blacklist {
-			re, err := regexp.Compile(r.Regex)
-			if err != nil {
-				continue
-			}
-			// 全平台
-			noneMatchPlatform := r.Platform == ""
-			// 特定平台
-			matchPlatform := r.Platform != "" && r.Platform == string(p.Platform)
-			if (noneMatchPlatform || matchPlatform) && re.MatchString(title) {
-				// 更改后续匹配模式
-				if r.Mode != "" {
-					matchMode = r.Mode
-				}
-				title = re.ReplaceAllLiteralString(title, r.Replacement)
-				// 只匹配一次
-				break
+	for _, r := range getTokenizerRules() {
+		// 全平台
+		noneMatchPlatform := r.platform == ""
+		// 特定平台
+		matchPlatform := r.platform != "" && r.platform == string(p.Platform)
+		if (noneMatchPlatform || matchPlatform) && r.re.MatchString(title) {
+			// 更改后续匹配模式
+			if r.mode != "" {
+				matchMode = r.mode
 			}
+			title = r.re.ReplaceAllLiteralString(title, r.replacement)
+			// 只匹配一次
+			break
 		}
 	}
 	// 如果是搜索模式，则匹配到命中搜索词结束
@@ -245,27 +408,76 @@ func (p MatchParam) MatchYearString(year string) (int, bool) {
 func InitPlatformClient(c *PlatformClient, platform Platform) error {
 	conf := config.GetPlatformConfig(string(platform))
 	if conf == nil || conf.Name == "" {
+		// 热加载时平台配置被删除 直接禁用
+		SetPlatformEnabled(platform, false)
 		return fmt.Errorf("[%s] is not configured", platform)
 	}
+	// 禁用的平台依旧初始化 可以在运行时通过管理接口启用
+	SetPlatformEnabled(platform, conf.Priority >= 0)
 	if conf.Priority < 0 {
 		utils.InfoLog(managerUtilC, fmt.Sprintf("[%s] is disabled", platform))
-		return nil
 	}
 
-	c.Cookie = conf.Cookie
-	c.MaxWorker = conf.MaxWorker
-	if c.MaxWorker <= 0 {
-		c.MaxWorker = defaultMaxWorker
+	settings := &clientSettings{
+		platform:   platform,
+		cookie:     conf.Cookie,
+		maxWorker:  conf.MaxWorker,
+		maxRetries: conf.MaxRetries,
+	}
+	if settings.maxWorker <= 0 {
+		settings.maxWorker = defaultMaxWorker
+	}
+	if conf.Qps > 0 {
+		settings.limiter = newTokenBucket(conf.Qps, conf.Burst)
 	}
+	if settings.maxRetries == 0 {
+		settings.maxRetries = defaultMaxRetries
+	}
+	retryBudget := conf.RetryBudget
+	if retryBudget <= 0 {
+		retryBudget = defaultRetryBudget
+	}
+	settings.retryBudget = newTokenBucket(float64(retryBudget)/60, retryBudget)
 	var timeout = conf.Timeout
 	if timeout <= 0 {
 		timeout = defaultTimeoutInSeconds
 	}
-	c.HttpClient = &http.Client{Timeout: time.Duration(timeout * 1e9)}
+	transport, err := NewProxyTransport(conf.Proxy)
+	if err != nil {
+		return fmt.Errorf("[%s] %w", platform, err)
+	}
+	settings.httpClient = &http.Client{Timeout: time.Duration(timeout * 1e9), Transport: transport}
+	if old := c.settings.Swap(settings); old != nil {
+		// 进行中的请求不受影响
+		old.httpClient.CloseIdleConnections()
+	}
 
 	return nil
 }
 
+// ReloadPlatforms 配置热加载后重新初始化平台 平台配置整体替换 新增的平台会被注册 删除的平台会被禁用
+func ReloadPlatforms() {
+	for _, i := range adapter.initializers {
+		var err error
+		switch v := i.(type) {
+		case Reloader:
+			err = v.Reload()
+		case Scraper:
+			err = v.Init()
+		default:
+			continue
+		}
+		if err != nil {
+			utils.InfoLog(managerUtilC, err.Error())
+		}
+	}
+	for _, s := range GetScrapers() {
+		if config.GetPlatformConfig(string(s.Platform())) == nil {
+			SetPlatformEnabled(s.Platform(), false)
+		}
+	}
+}
+
 const (
 	defaultMaxWorker        = 4
 	defaultTimeoutInSeconds = 30
@@ -0,0 +1,136 @@
+package danmaku
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestNormalizeContent(t *testing.T) {
+	tests := []struct {
+		content string
+		want    string
+	}{
+		{content: "哈哈哈", want: "哈哈"},
+		{content: "哈哈哈哈哈哈", want: "哈哈"},
+		{content: "哈哈", want: "哈哈"},
+		{content: "好好学习", want: "好好学习"},
+		{content: "好学习", want: "好学习"},
+		{content: "666", want: "66"},
+		{content: "6", want: "6"},
+		{content: "ＡＢＣ！", want: "abc!"},
+		{content: "Hello  World", want: "helloworld"},
+		{content: "前方　高能", want: "前方高能"},
+		{content: "👍🏻👍", want: "👍👍"},
+		{content: "❤️", want: "❤"},
+		{content: "   ", want: "   "},
+		{content: "", want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.content, func(t *testing.T) {
+			if got := NormalizeContent(tt.content); got != tt.want {
+				t.Errorf("NormalizeContent(%q) = %q, want %q", tt.content, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalizeContentDistinct(t *testing.T) {
+	pairs := [][2]string{
+		{"好好学习", "好学习"},
+		{"666", "6"},
+		{"哈哈", "哈"},
+	}
+	for _, p := range pairs {
+		if NormalizeContent(p[0]) == NormalizeContent(p[1]) {
+			t.Errorf("%q and %q should not be similar", p[0], p[1])
+		}
+	}
+}
+
+func dm(offset int64, content string, likes int64) *StandardDanmaku {
+	return &StandardDanmaku{OffsetMills: offset, Content: content, Likes: likes}
+}
+
+func contents(dms []*StandardDanmaku) []string {
+	var result = make([]string, 0, len(dms))
+	for _, d := range dms {
+		result = append(result, d.Content)
+	}
+	return result
+}
+
+func TestMergeDanmaku(t *testing.T) {
+	tests := []struct {
+		name       string
+		dms        []*StandardDanmaku
+		mergedIn   int64
+		annotation string
+		minCount   int
+		want       []string
+	}{
+		{
+			name:     "no merge",
+			dms:      []*StandardDanmaku{dm(0, "a", 0), dm(10, "a", 0)},
+			mergedIn: 0,
+			want:     []string{"a", "a"},
+		},
+		{
+			name:     "same bucket",
+			dms:      []*StandardDanmaku{dm(0, "哈哈哈", 0), dm(100, "哈哈哈哈", 0), dm(200, "ｈａｈａ", 0)},
+			mergedIn: 1000,
+			want:     []string{"哈哈哈 ×2", "ｈａｈａ"},
+		},
+		{
+			name:     "adjacent bucket",
+			dms:      []*StandardDanmaku{dm(900, "awsl", 0), dm(1100, "AWSL", 0)},
+			mergedIn: 1000,
+			want:     []string{"awsl ×2"},
+		},
+		{
+			name:     "far apart",
+			dms:      []*StandardDanmaku{dm(0, "awsl", 0), dm(5000, "awsl", 0)},
+			mergedIn: 1000,
+			want:     []string{"awsl", "awsl"},
+		},
+		{
+			name:       "no annotation",
+			dms:        []*StandardDanmaku{dm(0, "a", 0), dm(10, "a", 0)},
+			mergedIn:   1000,
+			annotation: MergeAnnotationNone,
+			want:       []string{"a"},
+		},
+		{
+			name:       "custom annotation",
+			dms:        []*StandardDanmaku{dm(0, "a", 0), dm(10, "a", 0), dm(20, "a", 0)},
+			mergedIn:   1000,
+			annotation: "{content}({count})",
+			want:       []string{"a(3)"},
+		},
+		{
+			name:     "under min count",
+			dms:      []*StandardDanmaku{dm(0, "a", 0), dm(10, "a", 0), dm(20, "b", 0), dm(30, "b", 0), dm(40, "b", 0)},
+			mergedIn: 1000,
+			minCount: 3,
+			want:     []string{"a", "b ×3"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := MergeDanmaku(tt.dms, tt.mergedIn, 0, tt.annotation, tt.minCount)
+			if !slices.Equal(contents(got), tt.want) {
+				t.Errorf("got %q, want %q", contents(got), tt.want)
+			}
+		})
+	}
+}
+
+func TestMergeDanmakuKeepsOriginal(t *testing.T) {
+	dms := []*StandardDanmaku{dm(0, "a", 1), dm(10, "a", 2)}
+	got := MergeDanmaku(dms, 1000, 0, "", 0)
+	if len(got) != 1 || got[0].Likes != 3 {
+		t.Fatalf("got %+v, want one danmaku with 3 likes", got)
+	}
+	if dms[0].Content != "a" || dms[0].Likes != 1 {
+		t.Errorf("original danmaku modified: %+v", dms[0])
+	}
+}
@@ -0,0 +1,214 @@
+package danmaku
+
+import (
+	"crypto/sha256"
+	"danmaku-tool/internal/utils"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+/*
+	保存目录下的 manifest.json 记录每一集的抓取结果 key为弹幕文件名（不含后缀）
+	写入文件时记录弹幕数量以及内容hash 内容未变化时不重写文件
+	scrape命令跳过最近抓取过的单集 中断后重新执行会从未完成的单集继续
+*/
+
+const manifestFile = "manifest.json"
+
+// Manifest 单个保存目录的抓取记录
+type Manifest struct {
+	Platform Platform                    `json:"platform"`
+	SeasonId string                      `json:"seasonId"`
+	Episodes map[string]*ManifestEpisode `json:"episodes"`
+}
+
+// ManifestEpisode 单集抓取记录
+type ManifestEpisode struct {
+	EpisodeId string    `json:"episodeId"`
+	Count     int       `json:"count"` // 最近一次抓取的原始弹幕数量
+	Hash      string    `json:"hash"`  // 原始弹幕内容hash 与抓取顺序无关
+	FetchedAt time.Time `json:"fetchedAt"`
+	UpdatedAt time.Time `json:"updatedAt"` // 最近一次内容变化写入文件的时间
+	Files     []string  `json:"files"`     // 弹幕文件 相对 manifest 所在目录
+}
+
+var (
+	manifestLock sync.Mutex
+	skipFresh    atomic.Int64
+	rewrite      atomic.Bool
+)
+
+// SetSkipFresh 抓取时跳过指定时间内抓取过的单集 <=0 不跳过
+func SetSkipFresh(d time.Duration) {
+	skipFresh.Store(int64(d))
+}
+
+// SetRewrite 内容未变化时也重写文件
+func SetRewrite(b bool) {
+	rewrite.Store(b)
+}
+
+// ReadManifest 读取保存目录的抓取记录 文件不存在则为空
+func ReadManifest(savePath string) (*Manifest, error) {
+	m := &Manifest{Episodes: map[string]*ManifestEpisode{}}
+	content, err := os.ReadFile(filepath.Join(savePath, manifestFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return m, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err = json.Unmarshal(content, m); err != nil {
+		return nil, fmt.Errorf("invalid manifest in %s: %w", savePath, err)
+	}
+	if m.Episodes == nil {
+		m.Episodes = map[string]*ManifestEpisode{}
+	}
+	return m, nil
+}
+
+func (m *Manifest) write(savePath string) error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	// 使用 save-template 时记录所在的目录可能不存在
+	if err = os.MkdirAll(savePath, os.ModePerm); err != nil {
+		return err
+	}
+	file := filepath.Join(savePath, manifestFile)
+	tmp := file + ".tmp"
+	if err = os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, file)
+}
+
+// filesExist 记录的弹幕文件都还存在
+func (e *ManifestEpisode) filesExist(savePath string) bool {
+	if len(e.Files) == 0 {
+		return false
+	}
+	for _, f := range e.Files {
+		if _, err := os.Stat(manifestPath(savePath, f)); err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// manifestPath 记录的文件完整路径
+func manifestPath(savePath, file string) string {
+	if filepath.IsAbs(file) {
+		return file
+	}
+	return filepath.Join(savePath, file)
+}
+
+// UpToDate 单集在 SetSkipFresh 时间内抓取过并且文件存在 抓取时跳过
+func UpToDate(savePath, filename string) bool {
+	fresh := time.Duration(skipFresh.Load())
+	if fresh <= 0 {
+		return false
+	}
+	manifestLock.Lock()
+	defer manifestLock.Unlock()
+	m, err := ReadManifest(savePath)
+	if err != nil {
+		utils.WarnLog(serializerC, err.Error())
+		return false
+	}
+	e := m.Episodes[filename]
+	if e == nil || time.Since(e.FetchedAt) > fresh || !e.filesExist(savePath) {
+		return false
+	}
+	utils.InfoLog(serializerC, "episode up to date, skipped", "file", filename, "fetched_at", e.FetchedAt.Format(time.DateTime))
+	return true
+}
+
+// unchanged 内容与上次抓取一致并且文件存在 只更新抓取时间 返回已有文件
+func unchanged(savePath, filename, hash string) ([]string, bool) {
+	if rewrite.Load() {
+		return nil, false
+	}
+	manifestLock.Lock()
+	defer manifestLock.Unlock()
+	m, err := ReadManifest(savePath)
+	if err != nil {
+		utils.WarnLog(serializerC, err.Error())
+		return nil, false
+	}
+	e := m.Episodes[filename]
+	if e == nil || e.Hash != hash || !e.filesExist(savePath) {
+		return nil, false
+	}
+	e.FetchedAt = time.Now()
+	if err = m.write(savePath); err != nil {
+		utils.WarnLog(serializerC, "write manifest failed", "path", savePath, "error", err)
+	}
+	var files = make([]string, 0, len(e.Files))
+	for _, f := range e.Files {
+		files = append(files, manifestPath(savePath, f))
+	}
+	return files, true
+}
+
+// recordManifest 记录写入结果
+func recordManifest(platform Platform, data *SerializerData, savePath, filename, hash string, count int, files []string) {
+	manifestLock.Lock()
+	defer manifestLock.Unlock()
+	m, err := ReadManifest(savePath)
+	if err != nil {
+		// 无法解析的记录直接覆盖
+		utils.WarnLog(serializerC, err.Error())
+		m = &Manifest{Episodes: map[string]*ManifestEpisode{}}
+	}
+	m.Platform = platform
+	m.SeasonId = data.SeasonId
+	now := time.Now()
+	var names = make([]string, 0, len(files))
+	for _, f := range files {
+		// 使用 save-template 时文件不在记录所在的目录
+		name, e := filepath.Rel(savePath, f)
+		if e != nil {
+			name = f
+		}
+		names = append(names, name)
+	}
+	m.Episodes[filename] = &ManifestEpisode{
+		EpisodeId: data.EpisodeId,
+		Count:     count,
+		Hash:      hash,
+		FetchedAt: now,
+		UpdatedAt: now,
+		Files:     names,
+	}
+	if err = m.write(savePath); err != nil {
+		utils.WarnLog(serializerC, "write manifest failed", "path", savePath, "error", err)
+	}
+}
+
+// hashDanmaku 原始弹幕的内容hash 分片并发抓取的顺序不影响结果
+func hashDanmaku(dms []*StandardDanmaku) string {
+	var keys = make([]string, 0, len(dms))
+	for _, d := range dms {
+		keys = append(keys, strconv.FormatInt(d.OffsetMills, 10)+"\x00"+strconv.Itoa(d.Mode)+"\x00"+
+			strconv.Itoa(d.Color)+"\x00"+d.Content)
+	}
+	slices.Sort(keys)
+	h := sha256.New()
+	for _, k := range keys {
+		h.Write([]byte(k))
+		h.Write([]byte{'\n'})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,84 @@
+package danmaku
+
+import (
+	"danmaku-tool/internal/config"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+/*
+	平台以及emby请求代理 支持 http https socks5 socks5h
+	认证信息可以写在url中 也可以单独配置 username password
+	no-proxy 支持域名（包含子域名）、IP、CIDR 以及 * 全部不走代理
+*/
+
+// NewProxyTransport 根据代理配置创建 transport 未配置代理则返回nil 使用默认transport（同样会读取 HTTP_PROXY 等环境变量）
+func NewProxyTransport(conf config.ProxyConfig) (http.RoundTripper, error) {
+	if conf.Url == "" {
+		return nil, nil
+	}
+	proxyURL, err := ParseProxyUrl(conf)
+	if err != nil {
+		return nil, err
+	}
+	noProxy := conf.NoProxy
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = func(req *http.Request) (*url.URL, error) {
+		if matchNoProxy(req.URL.Hostname(), noProxy) {
+			return nil, nil
+		}
+		return proxyURL, nil
+	}
+	return transport, nil
+}
+
+// ParseProxyUrl 解析代理地址 username password 优先于url中的认证信息
+func ParseProxyUrl(conf config.ProxyConfig) (*url.URL, error) {
+	proxyURL, err := url.Parse(conf.Url)
+	if err != nil {
+		return nil, fmt.Errorf("invalid proxy url: %w", err)
+	}
+	switch proxyURL.Scheme {
+	case "http", "https", "socks5", "socks5h":
+	default:
+		return nil, fmt.Errorf("unsupported proxy scheme: %s", proxyURL.Scheme)
+	}
+	if proxyURL.Host == "" {
+		return nil, fmt.Errorf("proxy host is empty")
+	}
+	if conf.Username != "" {
+		proxyURL.User = url.UserPassword(conf.Username, conf.Password)
+	}
+	return proxyURL, nil
+}
+
+func matchNoProxy(host string, noProxy []string) bool {
+	host = strings.ToLower(host)
+	ip := net.ParseIP(host)
+	for _, rule := range noProxy {
+		rule = strings.ToLower(strings.TrimSpace(rule))
+		switch {
+		case rule == "":
+			continue
+		case rule == "*":
+			return true
+		case ip != nil:
+			if _, cidr, err := net.ParseCIDR(rule); err == nil && cidr.Contains(ip) {
+				return true
+			}
+			if ruleIP := net.ParseIP(rule); ruleIP != nil && ruleIP.Equal(ip) {
+				return true
+			}
+		default:
+			// .example.com 和 example.com 均匹配自身以及子域名
+			domain := strings.TrimPrefix(rule, ".")
+			if host == domain || strings.HasSuffix(host, "."+domain) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,94 @@
+package danmaku
+
+import (
+	"danmaku-tool/internal/config"
+	"net/http"
+	"testing"
+)
+
+func TestMatchNoProxy(t *testing.T) {
+	tests := []struct {
+		name    string
+		host    string
+		noProxy []string
+		want    bool
+	}{
+		{name: "empty rules", host: "api.bilibili.com", noProxy: nil, want: false},
+		{name: "blank rule", host: "api.bilibili.com", noProxy: []string{" "}, want: false},
+		{name: "wildcard", host: "api.bilibili.com", noProxy: []string{"*"}, want: true},
+		{name: "exact domain", host: "bilibili.com", noProxy: []string{"bilibili.com"}, want: true},
+		{name: "subdomain", host: "api.bilibili.com", noProxy: []string{"bilibili.com"}, want: true},
+		{name: "leading dot", host: "api.bilibili.com", noProxy: []string{".bilibili.com"}, want: true},
+		{name: "leading dot matches self", host: "bilibili.com", noProxy: []string{".bilibili.com"}, want: true},
+		{name: "suffix is not subdomain", host: "notbilibili.com", noProxy: []string{"bilibili.com"}, want: false},
+		{name: "case insensitive", host: "API.Bilibili.COM", noProxy: []string{" BILIBILI.com "}, want: true},
+		{name: "other domain", host: "mgtv.com", noProxy: []string{"bilibili.com", "iqiyi.com"}, want: false},
+		{name: "ip", host: "192.168.1.10", noProxy: []string{"192.168.1.10"}, want: true},
+		{name: "ip mismatch", host: "192.168.1.11", noProxy: []string{"192.168.1.10"}, want: false},
+		{name: "cidr", host: "10.0.3.4", noProxy: []string{"10.0.0.0/8"}, want: true},
+		{name: "cidr mismatch", host: "172.16.0.1", noProxy: []string{"10.0.0.0/8"}, want: false},
+		{name: "ipv6", host: "::1", noProxy: []string{"::1"}, want: true},
+		{name: "ipv6 cidr", host: "fd00::1", noProxy: []string{"fd00::/8"}, want: true},
+		{name: "domain rule ignores ip", host: "127.0.0.1", noProxy: []string{"localhost"}, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := matchNoProxy(tt.host, tt.noProxy); got != tt.want {
+				t.Errorf("matchNoProxy(%q, %q) = %v, want %v", tt.host, tt.noProxy, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseProxyUrl(t *testing.T) {
+	tests := []struct {
+		name    string
+		conf    config.ProxyConfig
+		want    string
+		wantErr bool
+	}{
+		{name: "http", conf: config.ProxyConfig{Url: "http://127.0.0.1:7890"}, want: "http://127.0.0.1:7890"},
+		{name: "socks5h", conf: config.ProxyConfig{Url: "socks5h://proxy:1080"}, want: "socks5h://proxy:1080"},
+		{name: "url auth", conf: config.ProxyConfig{Url: "http://u:p@proxy:8080"}, want: "http://u:p@proxy:8080"},
+		{name: "config auth overrides", conf: config.ProxyConfig{Url: "http://u:p@proxy:8080", Username: "a", Password: "b"}, want: "http://a:b@proxy:8080"},
+		{name: "unsupported scheme", conf: config.ProxyConfig{Url: "ftp://proxy:21"}, wantErr: true},
+		{name: "missing host", conf: config.ProxyConfig{Url: "http://"}, wantErr: true},
+		{name: "invalid", conf: config.ProxyConfig{Url: "http://[::1"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseProxyUrl(tt.conf)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && got.String() != tt.want {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewProxyTransportNoProxy(t *testing.T) {
+	transport, err := NewProxyTransport(config.ProxyConfig{Url: "http://127.0.0.1:7890", NoProxy: []string{"local.lan"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	proxy := transport.(*http.Transport).Proxy
+	for host, want := range map[string]string{
+		"http://nas.local.lan/api":   "",
+		"https://api.bilibili.com/x": "http://127.0.0.1:7890",
+	} {
+		req, _ := http.NewRequest(http.MethodGet, host, nil)
+		u, _ := proxy(req)
+		var got string
+		if u != nil {
+			got = u.String()
+		}
+		if got != want {
+			t.Errorf("%s proxy = %q, want %q", host, got, want)
+		}
+	}
+	if transport, _ = NewProxyTransport(config.ProxyConfig{}); transport != nil {
+		t.Errorf("transport without proxy url should be nil")
+	}
+}
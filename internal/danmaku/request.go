@@ -0,0 +1,179 @@
+package danmaku
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+/*
+	平台请求限流以及重试
+
+	限流：每个平台一个令牌桶 qps <=0 不限流
+	重试：412/429/5xx 以及超时 指数退避加随机抖动 优先使用 Retry-After
+	重试预算：每个平台每分钟最多重试次数 避免平台异常时重试放大请求量
+*/
+
+const (
+	defaultMaxRetries  = 2
+	defaultRetryBudget = 30 // 每分钟
+	retryBaseDelay     = 500 * time.Millisecond
+	retryMaxDelay      = 10 * time.Second
+	// Retry-After 超过该时间则不再重试
+	retryAfterLimit = time.Minute
+)
+
+// nearRateLimitRatio 可用令牌低于该比例视为接近限流
+const nearRateLimitRatio = 0.5
+
+// NearRateLimit 平台是否接近限流或者重试预算即将耗尽 用于跳过预取等非必要请求 平台不可用同样返回true
+func NearRateLimit(platform string) bool {
+	s := GetScraper(platform)
+	if s == nil {
+		return true
+	}
+	c, ok := s.(interface{ platformClient() *PlatformClient })
+	if !ok {
+		return false
+	}
+	settings := c.platformClient().settings.Load()
+	if settings == nil {
+		return false
+	}
+	if settings.limiter != nil && settings.limiter.available() < nearRateLimitRatio {
+		return true
+	}
+	return settings.retryBudget != nil && settings.retryBudget.available() < nearRateLimitRatio
+}
+
+// tokenBucket 令牌桶 rate 每秒生成的令牌数 capacity 桶容量
+type tokenBucket struct {
+	lock     sync.Mutex
+	rate     float64
+	capacity float64
+	tokens   float64
+	last     time.Time
+}
+
+func newTokenBucket(rate float64, capacity int) *tokenBucket {
+	if capacity <= 0 {
+		capacity = max(1, int(math.Ceil(rate)))
+	}
+	return &tokenBucket{
+		rate:     rate,
+		capacity: float64(capacity),
+		tokens:   float64(capacity),
+		last:     time.Now(),
+	}
+}
+
+func (b *tokenBucket) refill(now time.Time) {
+	b.tokens = min(b.capacity, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	b.last = now
+}
+
+// reserve 获取一个令牌 返回需要等待的时间
+func (b *tokenBucket) reserve() time.Duration {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.refill(time.Now())
+	b.tokens--
+	if b.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-b.tokens / b.rate * float64(time.Second))
+}
+
+// take 有令牌则获取 不等待
+func (b *tokenBucket) take() bool {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.refill(time.Now())
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// available 当前可用令牌比例
+func (b *tokenBucket) available() float64 {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.refill(time.Now())
+	return max(b.tokens, 0) / b.capacity
+}
+
+// wait 等待令牌 请求取消则返回错误
+func (b *tokenBucket) wait(ctx context.Context) error {
+	if b == nil {
+		return nil
+	}
+	return sleep(ctx, b.reserve())
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// retryDelay 判断是否需要重试 返回等待时间
+func retryDelay(resp *http.Response, err error, attempt int) (time.Duration, bool) {
+	if err != nil {
+		var netErr net.Error
+		if !errors.As(err, &netErr) || !netErr.Timeout() {
+			return 0, false
+		}
+	} else if resp.StatusCode != http.StatusPreconditionFailed &&
+		resp.StatusCode != http.StatusTooManyRequests &&
+		resp.StatusCode < http.StatusInternalServerError {
+		return 0, false
+	}
+	if resp != nil {
+		if d, ok := parseRetryAfter(resp.Header.Get("Retry-After")); ok {
+			return d, d <= retryAfterLimit
+		}
+	}
+	// full jitter
+	backoff := min(retryMaxDelay, retryBaseDelay<<attempt)
+	return time.Duration(rand.Int64N(int64(backoff))) + retryBaseDelay/2, true
+}
+
+// parseRetryAfter 支持秒数以及http时间两种格式
+func parseRetryAfter(value string) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.Duration(max(seconds, 0)) * time.Second, true
+	}
+	if t, err := http.ParseTime(value); err == nil {
+		return max(time.Until(t), 0), true
+	}
+	return 0, false
+}
+
+// drain 重试前读取并关闭响应 复用连接
+func drain(resp *http.Response) {
+	if resp == nil || resp.Body == nil {
+		return
+	}
+	_, _ = io.CopyN(io.Discard, resp.Body, 64<<10)
+	_ = resp.Body.Close()
+}
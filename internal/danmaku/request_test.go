@@ -0,0 +1,161 @@
+package danmaku
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestTokenBucket(t *testing.T) {
+	tests := []struct {
+		name         string
+		rate         float64
+		capacity     int
+		wantCapacity float64
+	}{
+		{name: "explicit capacity", rate: 2, capacity: 5, wantCapacity: 5},
+		{name: "default capacity", rate: 2.5, capacity: 0, wantCapacity: 3},
+		{name: "slow rate", rate: 0.2, capacity: 0, wantCapacity: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := newTokenBucket(tt.rate, tt.capacity)
+			if b.capacity != tt.wantCapacity {
+				t.Fatalf("capacity = %v, want %v", b.capacity, tt.wantCapacity)
+			}
+			for i := 0; i < int(tt.wantCapacity); i++ {
+				if !b.take() {
+					t.Fatalf("take %d failed with full bucket", i)
+				}
+			}
+			if b.take() {
+				t.Fatalf("take succeeded with empty bucket")
+			}
+			if a := b.available(); a > 0.1 {
+				t.Errorf("available = %v after draining", a)
+			}
+
+			// 模拟经过一个令牌的生成时间
+			b.last = b.last.Add(-time.Duration(float64(time.Second) / tt.rate))
+			if !b.take() {
+				t.Errorf("take failed after refill")
+			}
+
+			// 桶内令牌不超过容量
+			b.last = b.last.Add(-time.Hour)
+			if a := b.available(); a != 1 {
+				t.Errorf("available = %v after long idle, want 1", a)
+			}
+		})
+	}
+}
+
+func TestTokenBucketReserve(t *testing.T) {
+	b := newTokenBucket(10, 1)
+	if d := b.reserve(); d != 0 {
+		t.Fatalf("first reserve wait %v, want 0", d)
+	}
+	// 之后每个令牌等待 100ms 累加
+	for i := 1; i <= 3; i++ {
+		d := b.reserve()
+		want := time.Duration(i) * 100 * time.Millisecond
+		if d < want-5*time.Millisecond || d > want {
+			t.Errorf("reserve %d wait %v, want about %v", i, d, want)
+		}
+	}
+}
+
+func TestTokenBucketNilWait(t *testing.T) {
+	var b *tokenBucket
+	if err := b.wait(t.Context()); err != nil {
+		t.Fatalf("nil bucket wait: %v", err)
+	}
+}
+
+func response(status int, retryAfter string) *http.Response {
+	resp := &http.Response{StatusCode: status, Header: http.Header{}}
+	if retryAfter != "" {
+		resp.Header.Set("Retry-After", retryAfter)
+	}
+	return resp
+}
+
+func TestRetryDelay(t *testing.T) {
+	timeout := &net.DNSError{Err: "timeout", IsTimeout: true}
+	tests := []struct {
+		name      string
+		resp      *http.Response
+		err       error
+		wantRetry bool
+		wantDelay time.Duration // 0 表示随机退避
+	}{
+		{name: "ok", resp: response(http.StatusOK, ""), wantRetry: false},
+		{name: "not found", resp: response(http.StatusNotFound, ""), wantRetry: false},
+		{name: "forbidden", resp: response(http.StatusForbidden, ""), wantRetry: false},
+		{name: "precondition failed", resp: response(http.StatusPreconditionFailed, ""), wantRetry: true},
+		{name: "too many requests", resp: response(http.StatusTooManyRequests, ""), wantRetry: true},
+		{name: "server error", resp: response(http.StatusBadGateway, ""), wantRetry: true},
+		{name: "retry after seconds", resp: response(http.StatusTooManyRequests, "3"), wantRetry: true, wantDelay: 3 * time.Second},
+		{name: "retry after too long", resp: response(http.StatusServiceUnavailable, "3600"), wantRetry: false},
+		{name: "retry after invalid", resp: response(http.StatusServiceUnavailable, "soon"), wantRetry: true},
+		{name: "timeout", err: timeout, wantRetry: true},
+		{name: "wrapped timeout", err: errors.Join(errors.New("request"), timeout), wantRetry: true},
+		{name: "other error", err: errors.New("connection refused"), wantRetry: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for attempt := 0; attempt < 8; attempt++ {
+				d, retry := retryDelay(tt.resp, tt.err, attempt)
+				if retry != tt.wantRetry {
+					t.Fatalf("attempt %d retry = %v, want %v", attempt, retry, tt.wantRetry)
+				}
+				if !retry {
+					return
+				}
+				if tt.wantDelay > 0 {
+					if d != tt.wantDelay {
+						t.Errorf("attempt %d delay = %v, want %v", attempt, d, tt.wantDelay)
+					}
+					continue
+				}
+				backoff := min(retryMaxDelay, retryBaseDelay<<attempt)
+				if d < retryBaseDelay/2 || d >= backoff+retryBaseDelay/2 {
+					t.Errorf("attempt %d delay = %v, out of [%v, %v)", attempt, d, retryBaseDelay/2, backoff+retryBaseDelay/2)
+				}
+			}
+		})
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	future := time.Now().Add(30 * time.Second).UTC().Format(http.TimeFormat)
+	past := time.Now().Add(-time.Minute).UTC().Format(http.TimeFormat)
+	tests := []struct {
+		value  string
+		wantOk bool
+		min    time.Duration
+		max    time.Duration
+	}{
+		{value: "", wantOk: false},
+		{value: "abc", wantOk: false},
+		{value: "0", wantOk: true},
+		{value: "-5", wantOk: true},
+		{value: strconv.Itoa(10), wantOk: true, min: 10 * time.Second, max: 10 * time.Second},
+		{value: future, wantOk: true, min: 28 * time.Second, max: 30 * time.Second},
+		{value: past, wantOk: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			d, ok := parseRetryAfter(tt.value)
+			if ok != tt.wantOk {
+				t.Fatalf("ok = %v, want %v", ok, tt.wantOk)
+			}
+			if d < tt.min || d > tt.max {
+				t.Errorf("delay = %v, want in [%v, %v]", d, tt.min, tt.max)
+			}
+		})
+	}
+}
@@ -0,0 +1,58 @@
+package danmaku
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+/*
+	从粘贴的平台网页链接解析平台以及 Scrape 使用的id 各平台实现 URLResolver
+	链接可以省略协议 比如 b23.tv/xxxx
+*/
+
+// IsURL 参数是否为链接 平台id不包含域名
+func IsURL(s string) bool {
+	if strings.Contains(s, "://") {
+		return true
+	}
+	host, _, _ := strings.Cut(s, "/")
+	return strings.Contains(host, ".") && !strings.ContainsAny(host, " =")
+}
+
+// ResolveURL 解析链接对应的平台以及id
+func ResolveURL(raw string) (Platform, string, error) {
+	raw = strings.TrimSpace(raw)
+	if !strings.Contains(raw, "://") {
+		raw = "https://" + raw
+	}
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		return "", "", fmt.Errorf("invalid url: %s", raw)
+	}
+	for _, s := range GetScrapers() {
+		r, ok := s.(URLResolver)
+		if !ok {
+			continue
+		}
+		id, e := r.ResolveURL(u)
+		if e != nil {
+			return "", "", fmt.Errorf("%s: %w", s.Platform(), e)
+		}
+		if id != "" {
+			return s.Platform(), id, nil
+		}
+	}
+	return "", "", fmt.Errorf("unsupported url or platform not configured: %s", raw)
+}
+
+// MatchHost 链接域名是否为指定域名或者其子域名
+func MatchHost(u *url.URL, domains ...string) bool {
+	host := strings.ToLower(u.Hostname())
+	for _, d := range domains {
+		if host == d || strings.HasSuffix(host, "."+d) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,34 @@
+package danmaku
+
+import (
+	"context"
+	"danmaku-tool/internal/metrics"
+	"sync/atomic"
+	"time"
+)
+
+/*
+	单集弹幕分片请求统计 分片失败时平台只记录日志并继续 抓取结果不包含失败信息
+	后台任务通过 WithSegmentCounter 统计单次抓取的分片失败次数 只统计使用对应ctx的请求
+*/
+
+type segmentCounterKey struct{}
+
+// WithSegmentCounter 返回统计分片失败次数的ctx 返回的函数获取当前失败次数
+func WithSegmentCounter(ctx context.Context) (context.Context, func() int) {
+	var failures = &atomic.Int64{}
+	return context.WithValue(ctx, segmentCounterKey{}, failures), func() int {
+		return int(failures.Load())
+	}
+}
+
+// ObserveSegment 记录分片请求结果
+func ObserveSegment(ctx context.Context, platform string, start time.Time, err error) {
+	metrics.ObservePlatformCall(platform, metrics.OpSegment, start, err)
+	if err == nil {
+		return
+	}
+	if failures, ok := ctx.Value(segmentCounterKey{}).(*atomic.Int64); ok {
+		failures.Add(1)
+	}
+}
@@ -48,13 +48,28 @@ func (x *DataXMLPersist) Type() string {
 	return XMLSerializer
 }
 
-func (x *DataXMLPersist) Serialize(s *SerializerData) error {
+func (x *DataXMLPersist) Serialize(s *SerializerData) (string, error) {
 	fullPath := s.fullPath
 	filename := s.filename
 	if e := checkPersistPath(fullPath, filename); e != nil {
-		return e
+		return "", e
 	}
 
+	finalXml, err := x.Marshal(s)
+	if err != nil {
+		return "", err
+	}
+	writeFile := filepath.Join(fullPath, filename+".xml")
+	err = os.WriteFile(writeFile, finalXml, 0644)
+	if err != nil {
+		return "", err
+	}
+
+	utils.InfoLog(XMLSerializer, "file save success", "file", writeFile)
+	return writeFile, nil
+}
+
+func (x *DataXMLPersist) Marshal(s *SerializerData) ([]byte, error) {
 	data := NormalConvert(s)
 
 	var xmlData []byte
@@ -65,20 +80,13 @@ func (x *DataXMLPersist) Serialize(s *SerializerData) error {
 		xmlData, err = xml.Marshal(data)
 	}
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	// 注意：xml.Marshal 不会自动添加声明头，需要手动添加。
 	finalXml := []byte(xml.Header)
 	finalXml = append(finalXml, xmlData...)
-	writeFile := filepath.Join(fullPath, filename+".xml")
-	err = os.WriteFile(writeFile, finalXml, 0644)
-	if err != nil {
-		return err
-	}
-
-	utils.InfoLog(XMLSerializer, "file save success", "file", writeFile)
-	return nil
+	return finalXml, nil
 }
 
 func NormalConvert(s *SerializerData) *DataXML {
@@ -121,17 +129,56 @@ func NormalConvert(s *SerializerData) *DataXML {
 
 const serializerC = "serializer"
 
-func WriteFile(platform Platform, data *SerializerData, savePath, filename string) {
+// MediaSavePath 剧集弹幕保存目录 save-path/{platform}/{剧集id} 插件等外部返回的id可能包含路径 清理后不允许超出 save-path
+func MediaSavePath(platform Platform, mediaId string) (string, error) {
+	root := config.GetConfig().SavePath
+	name := utils.SanitizeFilename(strings.TrimSpace(mediaId))
+	if strings.Trim(name, ".") == "" {
+		return "", fmt.Errorf("invalid media id: %q", mediaId)
+	}
+	savePath := filepath.Join(root, string(platform), name)
+	if rel, err := filepath.Rel(root, savePath); err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("media id %q resolves outside save path", mediaId)
+	}
+	return savePath, nil
+}
+
+// EpisodeFilename 单集id作为文件名 替换路径分隔符以及文件系统不允许的字符
+func EpisodeFilename(episodeId string) string {
+	name := utils.SanitizeFilename(strings.TrimSpace(episodeId))
+	if strings.Trim(name, ".") == "" {
+		name = strings.ReplaceAll(name, ".", "_") + "_"
+	}
+	return name
+}
+
+// WriteFile 过滤合并弹幕后按照平台配置的格式写入文件并记录到 manifest 返回写入成功的文件 内容未变化时返回已有文件
+func WriteFile(platform Platform, data *SerializerData, savePath, filename string) []string {
 	conf := config.GetPlatformConfig(string(platform))
 	if conf == nil {
 		utils.ErrorLog(serializerC, "config not exists", "platform", platform)
-		return
+		return nil
+	}
+	// 内容与上次抓取一致则不重写文件
+	count, hash := len(data.Data), hashDanmaku(data.Data)
+	if files, ok := unchanged(savePath, filename, hash); ok {
+		utils.InfoLog(serializerC, "danmaku unchanged, skip writing", "platform", platform, "file", filename, "size", count)
+		return files
 	}
-	// 合并弹幕
-	mergedMills := conf.MergeDanmakuInMills
-	if mergedMills > 0 {
-		data.Data = MergeDanmaku(data.Data, mergedMills, data.DurationInMills)
+	// manifest 记录在调用方的默认目录 文件按照模板保存
+	dir, name := outputPath(platform, data, savePath, filename)
+	// 过滤 合并弹幕
+	data.Data = ProcessDanmaku(platform, data.Data, data.DurationInMills)
+	if mergeExisting.Load() {
+		merged, added, err := mergeExistingFile(data.Data, dir, name, conf, data.DurationInMills)
+		if err != nil {
+			utils.WarnLog(serializerC, "merge existing file failed, overwrite", "platform", platform, "file", filename, "error", err)
+		} else {
+			utils.InfoLog(serializerC, "merge existing file", "platform", platform, "file", filename, "added", added)
+		}
+		data.Data = merged
 	}
+	var files []string
 	for _, s := range conf.Persists {
 		serializer := adapter.serializers[s]
 		if serializer == nil {
@@ -140,13 +187,19 @@ func WriteFile(platform Platform, data *SerializerData, savePath, filename strin
 		}
 
 		data.Platform = platform
-		data.fullPath = savePath
-		data.filename = filename
-		err := serializer.Serialize(data)
+		data.fullPath = dir
+		data.filename = name
+		file, err := serializer.Serialize(data)
 		if err != nil {
 			utils.ErrorLog(serializerC, err.Error(), "platform", platform, "serializer", serializer.Type())
+			continue
 		}
+		files = append(files, file)
+	}
+	if len(files) > 0 {
+		recordManifest(platform, data, savePath, filename, hash, count, files)
 	}
+	return files
 }
 
 func checkPersistPath(fullPath, filename string) error {
@@ -175,12 +228,26 @@ func (a *DataAssPersist) Type() string {
 	return ASSSerializer
 }
 
-func (a *DataAssPersist) Serialize(data *SerializerData) error {
+func (a *DataAssPersist) Serialize(data *SerializerData) (string, error) {
 	savePath := data.fullPath
 	filename := data.filename
 	if e := checkPersistPath(savePath, filename); e != nil {
-		return e
+		return "", e
+	}
+
+	assData, err := a.Marshal(data)
+	if err != nil {
+		return "", err
 	}
+	writeFile := filepath.Join(savePath, filename+".ass")
+	if err = os.WriteFile(writeFile, assData, 0644); err != nil {
+		return "", err
+	}
+	utils.InfoLog(ASSSerializer, "file save success", "file", writeFile)
+	return writeFile, nil
+}
+
+func (a *DataAssPersist) Marshal(data *SerializerData) ([]byte, error) {
 	if data.ResX == 0 || data.ResY == 0 {
 		data.ResX = 1920
 		data.ResY = 1080
@@ -225,18 +292,8 @@ func (a *DataAssPersist) Serialize(data *SerializerData) error {
 	}
 	events := strings.Join(eventsLines, "\n")
 
-	writeFile := filepath.Join(savePath, filename+".ass")
-	file, e := os.Create(writeFile)
-	if e != nil {
-		return e
-	}
-	defer utils.SafeClose(file)
 	assStr := strings.Join([]string{scriptInfo, styles, events}, "\n\n")
-	if _, err := file.WriteString(assStr); err != nil {
-		return err
-	}
-	utils.InfoLog(ASSSerializer, "file save success", "file", writeFile)
-	return nil
+	return []byte(assStr), nil
 }
 
 func (a *DataAssPersist) buildDialogue(data *StandardDanmaku, x, y int, index int) string {
@@ -0,0 +1,169 @@
+package danmaku
+
+import (
+	"danmaku-tool/internal/config"
+	"encoding/xml"
+	"errors"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+)
+
+/*
+	与已经保存的弹幕文件合并 用于定时刷新
+	以xml文件为准读取已有弹幕 只追加新出现的弹幕 ass等其他格式使用合并后的弹幕重新生成
+	弹幕按照出现时间（精确到10ms）以及归一化后的内容判断是否重复 合并标注的重复次数不参与判断
+	有新增弹幕时重新执行合并以及密度控制 已经标注次数的弹幕按照次数展开后再合并 避免多次刷新后超出密度限制
+*/
+
+var mergeExisting atomic.Bool
+
+// SetMergeExisting 写入弹幕文件时是否与已有文件合并
+func SetMergeExisting(merge bool) {
+	mergeExisting.Store(merge)
+}
+
+// mergeExistingFile 合并已有的xml弹幕文件 返回合并后的弹幕以及新增数量
+func mergeExistingFile(dms []*StandardDanmaku, savePath, filename string, conf *config.PlatformConfig, durationInMills int64) ([]*StandardDanmaku, int, error) {
+	existing, err := ReadXMLFile(filepath.Join(savePath, filename+".xml"))
+	if errors.Is(err, os.ErrNotExist) {
+		return dms, len(dms), nil
+	}
+	if err != nil {
+		return dms, 0, err
+	}
+	merged, added := MergeNew(existing, dms, conf.MergeAnnotation)
+	if added > 0 {
+		merged = reprocessMerged(merged, conf, durationInMills)
+	}
+	return merged, added, nil
+}
+
+// reprocessMerged 合并后的弹幕重新执行合并以及密度控制 过滤在合并之前已经执行
+func reprocessMerged(dms []*StandardDanmaku, conf *config.PlatformConfig, durationInMills int64) []*StandardDanmaku {
+	if conf.MergeDanmakuInMills > 0 {
+		dms = MergeDanmaku(ExpandAnnotated(dms, conf.MergeAnnotation), conf.MergeDanmakuInMills, durationInMills, conf.MergeAnnotation, conf.MergeMinCount)
+	}
+	if conf.DensityMaxPerWindow > 0 {
+		dms = ThinDanmaku(dms, conf.DensityWindowInMills, conf.DensityMaxPerWindow)
+	}
+	return dms
+}
+
+// ExpandAnnotated 已经标注重复次数的弹幕还原为多条原始弹幕 再次合并时次数可以累加 点赞数保留在第一条
+func ExpandAnnotated(dms []*StandardDanmaku, annotation string) []*StandardDanmaku {
+	parse := annotationParser(annotation)
+	var result = make([]*StandardDanmaku, 0, len(dms))
+	for _, d := range dms {
+		content, count := parse(d.Content)
+		if count <= 1 {
+			result = append(result, d)
+			continue
+		}
+		for i := 0; i < count; i++ {
+			c := *d
+			c.Content = content
+			if i > 0 {
+				c.Likes = 0
+			}
+			result = append(result, &c)
+		}
+	}
+	return result
+}
+
+// ReadXMLFile 读取xml弹幕文件
+func ReadXMLFile(file string) ([]*StandardDanmaku, error) {
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	var data DataXML
+	if err = xml.Unmarshal(content, &data); err != nil {
+		return nil, err
+	}
+	var result = make([]*StandardDanmaku, 0, len(data.Danmaku))
+	for _, d := range data.Danmaku {
+		// 第几秒,弹幕类型,字体大小,颜色,[平台]
+		attr := strings.Split(d.Attributes, ",")
+		if len(attr) < 4 {
+			continue
+		}
+		offset, _ := strconv.ParseFloat(attr[0], 64)
+		mode, _ := strconv.Atoi(attr[1])
+		fontSize, _ := strconv.ParseInt(attr[2], 10, 32)
+		color, _ := strconv.Atoi(attr[3])
+		result = append(result, &StandardDanmaku{
+			OffsetMills: int64(offset*1000 + 0.5),
+			Mode:        mode,
+			FontSize:    int32(fontSize),
+			Color:       color,
+			Content:     d.Content,
+			Platform:    Platform(data.SourceProvider),
+		})
+	}
+	return result, nil
+}
+
+// MergeNew 已有弹幕追加新出现的弹幕 按照出现时间排序 返回合并结果以及新增数量
+func MergeNew(existing, fresh []*StandardDanmaku, annotation string) ([]*StandardDanmaku, int) {
+	parse := annotationParser(annotation)
+	key := func(d *StandardDanmaku) string {
+		content, _ := parse(d.Content)
+		return strconv.FormatInt(d.OffsetMills/10, 10) + "\x00" + NormalizeContent(content)
+	}
+	var seen = make(map[string]bool, len(existing))
+	for _, d := range existing {
+		seen[key(d)] = true
+	}
+	var result = make([]*StandardDanmaku, len(existing), len(existing)+len(fresh))
+	copy(result, existing)
+	for _, d := range fresh {
+		k := key(d)
+		if seen[k] {
+			continue
+		}
+		seen[k] = true
+		result = append(result, d)
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].OffsetMills < result[j].OffsetMills
+	})
+	return result, len(result) - len(existing)
+}
+
+// annotationParser 解析合并标注 返回原始弹幕内容以及重复次数 未标注的次数为1
+func annotationParser(annotation string) func(string) (string, int) {
+	if annotation == MergeAnnotationNone {
+		return func(s string) (string, int) { return s, 1 }
+	}
+	if annotation == "" {
+		annotation = defaultMergeAnnotation
+	}
+	pattern := regexp.QuoteMeta(annotation)
+	pattern = strings.Replace(pattern, regexp.QuoteMeta("{content}"), "(?P<content>.*)", 1)
+	pattern = strings.Replace(pattern, regexp.QuoteMeta("{count}"), `(?P<count>\d+)`, 1)
+	pattern = strings.ReplaceAll(pattern, regexp.QuoteMeta("{count}"), `\d+`)
+	re, err := regexp.Compile("^" + pattern + "$")
+	if err != nil || re.SubexpIndex("content") < 0 {
+		return func(s string) (string, int) { return s, 1 }
+	}
+	contentIndex, countIndex := re.SubexpIndex("content"), re.SubexpIndex("count")
+	return func(s string) (string, int) {
+		m := re.FindStringSubmatch(s)
+		if m == nil {
+			return s, 1
+		}
+		count := 1
+		if countIndex >= 0 {
+			if n, e := strconv.Atoi(m[countIndex]); e == nil && n > 1 {
+				count = n
+			}
+		}
+		return m[contentIndex], count
+	}
+}
@@ -0,0 +1,167 @@
+package danmaku
+
+import (
+	"danmaku-tool/internal/config"
+	"slices"
+	"testing"
+)
+
+func TestMergeNew(t *testing.T) {
+	tests := []struct {
+		name       string
+		existing   []*StandardDanmaku
+		fresh      []*StandardDanmaku
+		annotation string
+		want       []string
+		wantAdded  int
+	}{
+		{
+			name:      "append sorted",
+			existing:  []*StandardDanmaku{dm(0, "a", 0), dm(2000, "c", 0)},
+			fresh:     []*StandardDanmaku{dm(1000, "b", 0), dm(3000, "d", 0)},
+			want:      []string{"a", "b", "c", "d"},
+			wantAdded: 2,
+		},
+		{
+			name:      "same time and content",
+			existing:  []*StandardDanmaku{dm(1000, "a", 0)},
+			fresh:     []*StandardDanmaku{dm(1005, "a", 0), dm(1000, "b", 0)},
+			want:      []string{"a", "b"},
+			wantAdded: 1,
+		},
+		{
+			name:      "different time",
+			existing:  []*StandardDanmaku{dm(1000, "a", 0)},
+			fresh:     []*StandardDanmaku{dm(1010, "a", 0)},
+			want:      []string{"a", "a"},
+			wantAdded: 1,
+		},
+		{
+			name:      "normalized content",
+			existing:  []*StandardDanmaku{dm(0, "哈哈哈", 0)},
+			fresh:     []*StandardDanmaku{dm(0, "哈哈哈哈哈", 0), dm(0, "ＡＢＣ", 0)},
+			want:      []string{"哈哈哈", "ＡＢＣ"},
+			wantAdded: 1,
+		},
+		{
+			name:      "annotated existing",
+			existing:  []*StandardDanmaku{dm(0, "awsl ×3", 0)},
+			fresh:     []*StandardDanmaku{dm(0, "awsl", 0), dm(0, "awsl ×5", 0)},
+			want:      []string{"awsl ×3"},
+			wantAdded: 0,
+		},
+		{
+			name:       "custom annotation",
+			existing:   []*StandardDanmaku{dm(0, "awsl(3)", 0)},
+			fresh:      []*StandardDanmaku{dm(0, "awsl", 0)},
+			annotation: "{content}({count})",
+			want:       []string{"awsl(3)"},
+			wantAdded:  0,
+		},
+		{
+			name:       "no annotation",
+			existing:   []*StandardDanmaku{dm(0, "awsl ×3", 0)},
+			fresh:      []*StandardDanmaku{dm(0, "awsl", 0)},
+			annotation: MergeAnnotationNone,
+			want:       []string{"awsl ×3", "awsl"},
+			wantAdded:  1,
+		},
+		{
+			name:      "fresh duplicates",
+			existing:  nil,
+			fresh:     []*StandardDanmaku{dm(0, "a", 0), dm(0, "a", 0)},
+			want:      []string{"a"},
+			wantAdded: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, added := MergeNew(tt.existing, tt.fresh, tt.annotation)
+			if added != tt.wantAdded {
+				t.Errorf("added = %d, want %d", added, tt.wantAdded)
+			}
+			if !slices.Equal(contents(got), tt.want) {
+				t.Errorf("got %q, want %q", contents(got), tt.want)
+			}
+		})
+	}
+}
+
+func TestAnnotationParser(t *testing.T) {
+	tests := []struct {
+		annotation, content string
+		want                string
+		wantCount           int
+	}{
+		{annotation: "", content: "awsl ×3", want: "awsl", wantCount: 3},
+		{annotation: "", content: "awsl", want: "awsl", wantCount: 1},
+		{annotation: "", content: "a ×2 ×4", want: "a ×2", wantCount: 4},
+		{annotation: "[{count}] {content}", content: "[12] 前方高能", want: "前方高能", wantCount: 12},
+		{annotation: "{content}({count})", content: "(1+1)(2)", want: "(1+1)", wantCount: 2},
+		{annotation: MergeAnnotationNone, content: "awsl ×3", want: "awsl ×3", wantCount: 1},
+		{annotation: "{count}", content: "3", want: "3", wantCount: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.annotation+"/"+tt.content, func(t *testing.T) {
+			got, count := annotationParser(tt.annotation)(tt.content)
+			if got != tt.want || count != tt.wantCount {
+				t.Errorf("got (%q, %d), want (%q, %d)", got, count, tt.want, tt.wantCount)
+			}
+		})
+	}
+}
+
+func TestExpandAnnotated(t *testing.T) {
+	dms := []*StandardDanmaku{dm(0, "a ×3", 7), dm(10, "b", 1)}
+	got := ExpandAnnotated(dms, "")
+	if !slices.Equal(contents(got), []string{"a", "a", "a", "b"}) {
+		t.Fatalf("got %q", contents(got))
+	}
+	var likes int64
+	for _, d := range got[:3] {
+		likes += d.Likes
+	}
+	if likes != 7 || got[0].Likes != 7 {
+		t.Errorf("likes should stay on the first copy, got %d", got[0].Likes)
+	}
+	if dms[0].Content != "a ×3" {
+		t.Errorf("original danmaku modified: %q", dms[0].Content)
+	}
+}
+
+// 多次刷新合并后依旧满足合并以及密度限制
+func TestReprocessMerged(t *testing.T) {
+	conf := &config.PlatformConfig{
+		MergeDanmakuInMills:  1000,
+		DensityMaxPerWindow:  3,
+		DensityWindowInMills: 1000,
+	}
+	var stored []*StandardDanmaku
+	for round := 0; round < 5; round++ {
+		fresh := burst(10, 0, 50)
+		for _, d := range fresh {
+			d.Content += "_" + string(rune('a'+round))
+		}
+		fresh = append(fresh, dm(100, "awsl", 0), dm(200, "awsl", 0))
+		fresh = reprocessMerged(fresh, conf, 0)
+
+		merged, added := MergeNew(stored, fresh, conf.MergeAnnotation)
+		if added > 0 {
+			merged = reprocessMerged(merged, conf, 0)
+		}
+		stored = merged
+		if n := maxInWindow(stored, conf.DensityWindowInMills); n > conf.DensityMaxPerWindow {
+			t.Fatalf("round %d: %d danmaku in one window, want at most %d", round, n, conf.DensityMaxPerWindow)
+		}
+	}
+	var awsl []string
+	for _, d := range stored {
+		if content, _ := annotationParser("")(d.Content); content == "awsl" {
+			awsl = append(awsl, d.Content)
+		}
+	}
+	// 重复刷新不会叠加标注
+	if len(awsl) > 1 || (len(awsl) == 1 && awsl[0] != "awsl ×2") {
+		t.Errorf("awsl merged into %q, want single \"awsl ×2\"", awsl)
+	}
+}
@@ -0,0 +1,63 @@
+package danmaku
+
+import (
+	"danmaku-tool/internal/config"
+	"danmaku-tool/internal/utils"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SetMedia 使用剧集以及单集信息填充保存路径模板需要的字段
+func (s *SerializerData) SetMedia(m *Media, ep *MediaEpisode) {
+	s.Title = m.Title
+	s.Year = m.Year
+	if s.Year == 0 && m.PubTime > 0 {
+		s.Year = time.Unix(m.PubTime, 0).Year()
+	}
+	s.EpisodeNumber, _ = strconv.Atoi(ep.EpisodeId)
+	s.EpisodeTitle = ep.Title
+}
+
+// outputPath 按照 save-template 计算保存目录以及文件名 未配置模板或者缺少标题时使用调用方的默认路径
+func outputPath(platform Platform, data *SerializerData, savePath, filename string) (string, string) {
+	conf := config.GetConfig()
+	if conf.SaveTemplate == "" {
+		return savePath, filename
+	}
+	tpl, err := utils.ParsePathTemplate(conf.SaveTemplate, config.SaveTemplateFields)
+	if err != nil {
+		utils.ErrorLog(serializerC, "invalid save-template, use default path", "error", err)
+		return savePath, filename
+	}
+	if data.Title == "" && tpl.Uses("title") {
+		utils.WarnLog(serializerC, "no media title, use default path", "platform", platform, "file", filename)
+		return savePath, filename
+	}
+	// 标题中的季信息单独作为 {season}
+	season := data.SeasonNumber
+	if season <= 0 {
+		season = MatchSeason(data.Title)
+	}
+	if season <= 0 {
+		season = 1
+	}
+	title := strings.TrimSpace(SeasonTitleMatch.ReplaceAllLiteralString(utils.StripHTMLTags(data.Title), ""))
+	rel, err := tpl.Render(map[string]any{
+		"platform": string(platform),
+		"title":    title,
+		"year":     data.Year,
+		"season":   season,
+		"ep":       data.EpisodeNumber,
+		"ep_title": data.EpisodeTitle,
+		"media_id": data.SeasonId,
+		"ep_id":    data.EpisodeId,
+	})
+	if err != nil {
+		utils.WarnLog(serializerC, "render save-template failed, use default path", "platform", platform, "file", filename, "error", err)
+		return savePath, filename
+	}
+	full := filepath.Join(conf.SavePath, filepath.FromSlash(rel))
+	return filepath.Dir(full), filepath.Base(full)
+}
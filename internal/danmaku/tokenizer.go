@@ -0,0 +1,51 @@
+package danmaku
+
+import (
+	"danmaku-tool/internal/config"
+	"danmaku-tool/internal/utils"
+	"regexp"
+	"sync/atomic"
+)
+
+const tokenizerC = "tokenizer"
+
+// tokenizerRule 编译后的标题黑名单规则
+type tokenizerRule struct {
+	re          *regexp.Regexp
+	replacement string
+	platform    string
+	mode        string
+}
+
+// compiledTokenizer 与配置绑定 配置热加载替换后首次使用时重新编译
+type compiledTokenizer struct {
+	conf  *config.DanmakuConfig
+	rules []tokenizerRule
+}
+
+var tokenizer atomic.Pointer[compiledTokenizer]
+
+func getTokenizerRules() []tokenizerRule {
+	conf := config.GetConfig()
+	if c := tokenizer.Load(); c != nil && c.conf == conf {
+		return c.rules
+	}
+	var rules []tokenizerRule
+	if conf.Tokenizer.Enable {
+		for _, r := range conf.Tokenizer.Blacklist {
+			re, err := regexp.Compile(r.Regex)
+			if err != nil {
+				utils.ErrorLog(tokenizerC, "invalid tokenizer regex", "regex", r.Regex, "error", err)
+				continue
+			}
+			rules = append(rules, tokenizerRule{
+				re:          re,
+				replacement: r.Replacement,
+				platform:    r.Platform,
+				mode:        r.Mode,
+			})
+		}
+	}
+	tokenizer.Store(&compiledTokenizer{conf: conf, rules: rules})
+	return rules
+}
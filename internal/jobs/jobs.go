@@ -0,0 +1,346 @@
+package jobs
+
+import (
+	"context"
+	"danmaku-tool/internal/config"
+	"danmaku-tool/internal/danmaku"
+	"danmaku-tool/internal/utils"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+/*
+	server模式后台抓取任务 通过管理接口提交 固定数量的worker按照提交顺序执行
+	任务以json格式保存在配置文件目录 重启后未结束的任务重新排队 已经完成的单集不会重复抓取
+	取消正在执行的任务会停止请求当前单集剩余的分片 当前单集不保存
+	弹幕文件与scrape命令抓取单集保存的一致 与订阅相同
+*/
+
+const jobsC = "jobs"
+
+const (
+	fileName       = "jobs.json"
+	defaultWorkers = 2
+	defaultHistory = 100
+)
+
+// 任务状态
+const (
+	StatusQueued   = "queued"
+	StatusRunning  = "running"
+	StatusDone     = "done"
+	StatusFailed   = "failed"
+	StatusCanceled = "canceled"
+)
+
+// 单集状态
+const (
+	EpisodePending = "pending"
+	EpisodeRunning = "running"
+	EpisodeDone    = "done"
+	EpisodeFailed  = "failed"
+)
+
+var (
+	ErrDisabled = errors.New("job queue is not running")
+	ErrNotFound = errors.New("job not found")
+	ErrFinished = errors.New("job already finished")
+)
+
+// Request 提交任务的参数 platform+id 或者 title+season
+type Request struct {
+	Platform string `json:"platform"` // 使用标题搜索时为空则选择优先级最高的平台
+	Id       string `json:"id"`       // 剧集id 同 MediaService.Media
+	Title    string `json:"title"`
+	Season   int    `json:"season"` // <=0 从标题中解析
+}
+
+// Job 抓取任务 使用标题提交的任务开始执行后会填充平台以及剧集id
+type Job struct {
+	Id         int64      `json:"id"`
+	Platform   string     `json:"platform"`
+	MediaId    string     `json:"mediaId"`
+	Title      string     `json:"title"`
+	Season     int        `json:"season,omitempty"`
+	Status     string     `json:"status"`
+	Error      string     `json:"error,omitempty"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	StartedAt  *time.Time `json:"startedAt,omitempty"`
+	FinishedAt *time.Time `json:"finishedAt,omitempty"`
+	Episodes   []*Episode `json:"episodes"`
+
+	cancel context.CancelFunc
+}
+
+// Episode 单集进度
+type Episode struct {
+	Id              string   `json:"id"`
+	EpisodeId       string   `json:"episodeId"`
+	Title           string   `json:"title"`
+	Status          string   `json:"status"`
+	Count           int      `json:"count"`           // 抓取的原始弹幕数量
+	SegmentFailures int      `json:"segmentFailures"` // 分片请求失败次数
+	Files           []string `json:"files"`
+	Error           string   `json:"error,omitempty"`
+}
+
+// Summary 任务列表使用 不包含单集详情
+type Summary struct {
+	Id              int64      `json:"id"`
+	Platform        string     `json:"platform"`
+	MediaId         string     `json:"mediaId"`
+	Title           string     `json:"title"`
+	Status          string     `json:"status"`
+	Error           string     `json:"error,omitempty"`
+	CreatedAt       time.Time  `json:"createdAt"`
+	FinishedAt      *time.Time `json:"finishedAt,omitempty"`
+	Total           int        `json:"total"`
+	Done            int        `json:"done"`
+	Failed          int        `json:"failed"`
+	SegmentFailures int        `json:"segmentFailures"`
+}
+
+func (j *Job) finished() bool {
+	return j.Status == StatusDone || j.Status == StatusFailed || j.Status == StatusCanceled
+}
+
+func (j *Job) clone() *Job {
+	c := *j
+	c.cancel = nil
+	c.Episodes = make([]*Episode, 0, len(j.Episodes))
+	for _, ep := range j.Episodes {
+		e := *ep
+		e.Files = append([]string(nil), ep.Files...)
+		c.Episodes = append(c.Episodes, &e)
+	}
+	return &c
+}
+
+func (j *Job) summary() Summary {
+	s := Summary{
+		Id:         j.Id,
+		Platform:   j.Platform,
+		MediaId:    j.MediaId,
+		Title:      j.Title,
+		Status:     j.Status,
+		Error:      j.Error,
+		CreatedAt:  j.CreatedAt,
+		FinishedAt: j.FinishedAt,
+		Total:      len(j.Episodes),
+	}
+	for _, ep := range j.Episodes {
+		switch ep.Status {
+		case EpisodeDone:
+			s.Done++
+		case EpisodeFailed:
+			s.Failed++
+		}
+		s.SegmentFailures += ep.SegmentFailures
+	}
+	return s
+}
+
+// manager 任务队列 所有修改都持有锁并写入文件
+type manager struct {
+	path    string
+	history int
+	lock    sync.Mutex
+	cond    *sync.Cond
+	jobs    []*Job // 按照id升序
+	nextId  int64
+	closed  bool
+}
+
+var queue *manager
+
+// DefaultPath 配置文件目录下的 jobs.json
+func DefaultPath() string {
+	return filepath.Join(filepath.Dir(config.ConfPath), fileName)
+}
+
+func newManager(path string, history int) (*manager, error) {
+	m := &manager{path: path, history: history, nextId: 1}
+	m.cond = sync.NewCond(&m.lock)
+	file, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return m, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err = json.Unmarshal(file, &m.jobs); err != nil {
+		return nil, fmt.Errorf("invalid jobs file %s: %w", path, err)
+	}
+	for _, j := range m.jobs {
+		if j.Id >= m.nextId {
+			m.nextId = j.Id + 1
+		}
+		// 上次退出时未结束的任务重新排队
+		if j.Status == StatusRunning {
+			j.Status = StatusQueued
+		}
+		for _, ep := range j.Episodes {
+			if ep.Status == EpisodeRunning {
+				ep.Status = EpisodePending
+			}
+		}
+	}
+	return m, nil
+}
+
+// save 调用方持有锁
+func (m *manager) save() {
+	data, err := json.MarshalIndent(m.jobs, "", "  ")
+	if err == nil {
+		tmp := m.path + ".tmp"
+		if err = os.WriteFile(tmp, data, 0644); err == nil {
+			err = os.Rename(tmp, m.path)
+		}
+	}
+	if err != nil {
+		utils.ErrorLog(jobsC, "save jobs failed", "error", err)
+	}
+}
+
+// update 修改任务并保存
+func (m *manager) update(f func()) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	f()
+	m.save()
+}
+
+// trim 只保留最近的已结束任务 调用方持有锁
+func (m *manager) trim() {
+	var finished int
+	for _, j := range m.jobs {
+		if j.finished() {
+			finished++
+		}
+	}
+	if finished <= m.history {
+		return
+	}
+	var jobs = make([]*Job, 0, len(m.jobs))
+	for _, j := range m.jobs {
+		if j.finished() && finished > m.history {
+			finished--
+			continue
+		}
+		jobs = append(jobs, j)
+	}
+	m.jobs = jobs
+}
+
+func (m *manager) find(id int64) *Job {
+	for _, j := range m.jobs {
+		if j.Id == id {
+			return j
+		}
+	}
+	return nil
+}
+
+// Submit 提交任务 只检查参数 剧集信息在执行时获取
+func Submit(req Request) (*Job, error) {
+	if queue == nil {
+		return nil, ErrDisabled
+	}
+	req.Platform = strings.TrimSpace(req.Platform)
+	req.Id = strings.TrimSpace(req.Id)
+	req.Title = strings.TrimSpace(req.Title)
+	if req.Id != "" {
+		if req.Platform == "" {
+			return nil, fmt.Errorf("platform is required when id is provided")
+		}
+		if danmaku.GetMediaService(req.Platform) == nil {
+			return nil, fmt.Errorf("platform %s is not available or does not support media info", req.Platform)
+		}
+	} else if req.Title == "" {
+		return nil, fmt.Errorf("id or title is required")
+	}
+
+	m := queue
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	job := &Job{
+		Id:        m.nextId,
+		Platform:  req.Platform,
+		MediaId:   req.Id,
+		Title:     req.Title,
+		Season:    req.Season,
+		Status:    StatusQueued,
+		CreatedAt: time.Now(),
+		Episodes:  []*Episode{},
+	}
+	m.nextId++
+	m.jobs = append(m.jobs, job)
+	m.save()
+	m.cond.Signal()
+	utils.InfoLog(jobsC, "job submitted", "job", job.Id, "platform", job.Platform, "id", job.MediaId, "title", job.Title)
+	return job.clone(), nil
+}
+
+// List 所有任务 status为空则不过滤
+func List(status string) ([]Summary, error) {
+	if queue == nil {
+		return nil, ErrDisabled
+	}
+	queue.lock.Lock()
+	defer queue.lock.Unlock()
+	var result = make([]Summary, 0, len(queue.jobs))
+	for _, j := range queue.jobs {
+		if status == "" || j.Status == status {
+			result = append(result, j.summary())
+		}
+	}
+	return result, nil
+}
+
+// Get 任务详情
+func Get(id int64) (*Job, error) {
+	if queue == nil {
+		return nil, ErrDisabled
+	}
+	queue.lock.Lock()
+	defer queue.lock.Unlock()
+	job := queue.find(id)
+	if job == nil {
+		return nil, ErrNotFound
+	}
+	return job.clone(), nil
+}
+
+// Cancel 取消任务 排队中的任务直接取消 执行中的任务停止抓取当前单集
+func Cancel(id int64) (*Job, error) {
+	if queue == nil {
+		return nil, ErrDisabled
+	}
+	m := queue
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	job := m.find(id)
+	if job == nil {
+		return nil, ErrNotFound
+	}
+	switch {
+	case job.finished():
+		return nil, ErrFinished
+	case job.Status == StatusQueued:
+		now := time.Now()
+		job.Status = StatusCanceled
+		job.FinishedAt = &now
+		m.trim()
+		m.save()
+	case job.cancel != nil:
+		job.cancel()
+	}
+	utils.InfoLog(jobsC, "job canceled", "job", job.Id)
+	return job.clone(), nil
+}
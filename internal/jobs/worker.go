@@ -0,0 +1,231 @@
+package jobs
+
+import (
+	"context"
+	"danmaku-tool/internal/config"
+	"danmaku-tool/internal/danmaku"
+	"danmaku-tool/internal/utils"
+	"fmt"
+	"time"
+)
+
+func init() {
+	danmaku.RegisterInitializer(&runner{})
+}
+
+// runner server模式启动worker worker数量修改需要重启
+type runner struct{}
+
+func (r *runner) ServerInit() error {
+	conf := config.GetConfig().Server.Jobs
+	history := conf.History
+	if history <= 0 {
+		history = defaultHistory
+	}
+	m, err := newManager(DefaultPath(), history)
+	if err != nil {
+		return err
+	}
+	workers := conf.Workers
+	if workers <= 0 {
+		workers = defaultWorkers
+	}
+	for i := 0; i < workers; i++ {
+		go m.work()
+	}
+	queue = m
+	utils.InfoLog(jobsC, "job workers started", "workers", workers, "jobs", len(m.jobs))
+	return nil
+}
+
+// Finalize 停止worker 执行中的任务保持running状态 下次启动时重新排队
+func (r *runner) Finalize() error {
+	m := queue
+	if m == nil {
+		return nil
+	}
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.closed = true
+	for _, j := range m.jobs {
+		if j.cancel != nil {
+			j.cancel()
+		}
+	}
+	m.cond.Broadcast()
+	m.save()
+	return nil
+}
+
+// next 等待并取出最早的排队任务 关闭后返回nil
+func (m *manager) next() (*Job, context.Context) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	for !m.closed {
+		for _, j := range m.jobs {
+			if j.Status == StatusQueued {
+				ctx, cancel := context.WithCancel(context.Background())
+				now := time.Now()
+				j.Status = StatusRunning
+				j.StartedAt = &now
+				j.Error = ""
+				j.cancel = cancel
+				m.save()
+				return j, ctx
+			}
+		}
+		m.cond.Wait()
+	}
+	return nil, nil
+}
+
+func (m *manager) work() {
+	for {
+		job, ctx := m.next()
+		if job == nil {
+			return
+		}
+		err := m.run(ctx, job)
+		m.lock.Lock()
+		canceled := ctx.Err() != nil
+		job.cancel()
+		job.cancel = nil
+		if m.closed {
+			m.lock.Unlock()
+			return
+		}
+		now := time.Now()
+		job.FinishedAt = &now
+		switch {
+		case canceled:
+			job.Status = StatusCanceled
+		case err != nil:
+			job.Status = StatusFailed
+			job.Error = err.Error()
+		default:
+			job.Status = StatusDone
+		}
+		m.trim()
+		m.save()
+		m.lock.Unlock()
+		utils.InfoLog(jobsC, "job finished", "job", job.Id, "status", job.Status, "cost_ms", now.Sub(*job.StartedAt).Milliseconds())
+	}
+}
+
+// run 获取剧集信息后依次抓取未完成的单集 返回的错误为任务失败原因
+func (m *manager) run(ctx context.Context, job *Job) error {
+	media, err := resolve(job)
+	if err != nil {
+		return err
+	}
+	scraper := danmaku.GetScraper(string(media.Platform))
+	if scraper == nil {
+		return fmt.Errorf("platform %s is not available", media.Platform)
+	}
+	var episodes []*Episode
+	m.update(func() {
+		job.Platform = string(media.Platform)
+		job.MediaId = media.Id
+		job.Title = media.Title
+		job.Episodes = mergeEpisodes(job.Episodes, media.Episodes)
+		episodes = job.Episodes
+	})
+
+	var failed int
+	for _, ep := range episodes {
+		if ctx.Err() != nil {
+			return nil
+		}
+		if ep.Status == EpisodeDone {
+			continue
+		}
+		if !m.scrapeEpisode(ctx, job, ep, scraper, media) {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d episodes failed", failed, len(episodes))
+	}
+	return nil
+}
+
+// resolve 获取剧集信息 使用标题提交的任务选择第一个匹配结果
+func resolve(job *Job) (*danmaku.Media, error) {
+	if job.MediaId != "" {
+		service := danmaku.GetMediaService(job.Platform)
+		if service == nil {
+			return nil, fmt.Errorf("platform %s is not available or does not support media info", job.Platform)
+		}
+		return service.Media(job.MediaId)
+	}
+	season := job.Season
+	if season <= 0 {
+		season = -1
+	}
+	media := danmaku.MatchMedia(danmaku.MatchParam{
+		Title:     job.Title,
+		SeasonId:  season,
+		EpisodeId: -1,
+		Mode:      danmaku.Equals,
+	})
+	for _, m := range media {
+		if job.Platform == "" || string(m.Platform) == job.Platform {
+			return m, nil
+		}
+	}
+	return nil, fmt.Errorf("no media matched: %s", job.Title)
+}
+
+// mergeEpisodes 保留已有单集的进度 追加新出现的单集
+func mergeEpisodes(existing []*Episode, episodes []*danmaku.MediaEpisode) []*Episode {
+	var progress = make(map[string]*Episode, len(existing))
+	for _, ep := range existing {
+		progress[ep.Id] = ep
+	}
+	var result = make([]*Episode, 0, len(episodes))
+	for _, ep := range episodes {
+		if p, ok := progress[ep.Id]; ok {
+			result = append(result, p)
+			continue
+		}
+		result = append(result, &Episode{Id: ep.Id, EpisodeId: ep.EpisodeId, Title: ep.Title, Status: EpisodePending})
+	}
+	return result
+}
+
+// scrapeEpisode 抓取并保存单集弹幕 返回是否成功 取消时停止请求剩余的分片
+func (m *manager) scrapeEpisode(ctx context.Context, job *Job, ep *Episode, scraper danmaku.Scraper, media *danmaku.Media) bool {
+	m.update(func() {
+		ep.Status = EpisodeRunning
+		ep.Error = ""
+	})
+
+	ctx, segmentFailures := danmaku.WithSegmentCounter(ctx)
+	files, count, err := danmaku.ScrapeEpisode(ctx, scraper, media, &danmaku.MediaEpisode{Id: ep.Id, EpisodeId: ep.EpisodeId, Title: ep.Title})
+	failures := segmentFailures()
+	if ctx.Err() != nil {
+		// 取消的单集保持待抓取 重新提交后继续
+		m.update(func() {
+			ep.Status = EpisodePending
+		})
+		return false
+	}
+
+	m.update(func() {
+		ep.Count = count
+		ep.SegmentFailures = failures
+		ep.Files = files
+		if err != nil {
+			ep.Status = EpisodeFailed
+			ep.Error = err.Error()
+		} else {
+			ep.Status = EpisodeDone
+		}
+	})
+	if err != nil {
+		utils.WarnLog(jobsC, "episode scrape failed", "job", job.Id, "ep", ep.Id, "error", err)
+		return false
+	}
+	utils.InfoLog(jobsC, "episode scraped", "job", job.Id, "ep", ep.EpisodeId, "size", count, "segment_failures", failures)
+	return true
+}
@@ -0,0 +1,49 @@
+package metrics
+
+import "time"
+
+const (
+	OpMatch      = "match"
+	OpGetDanmaku = "get_danmaku"
+	OpSegment    = "segment" // 单集弹幕分片请求
+
+	resultSuccess = "success"
+	resultError   = "error"
+)
+
+var (
+	HttpRequests = NewCounterVec("danmaku_http_requests_total",
+		"Total number of http requests by route.", "method", "route", "status")
+	HttpRequestDuration = NewHistogramVec("danmaku_http_request_duration_seconds",
+		"Http request latency by route.", nil, "method", "route")
+
+	platformCalls = NewCounterVec("danmaku_platform_calls_total",
+		"Total number of platform calls by operation and result.", "platform", "op", "result")
+	platformCallDuration = NewHistogramVec("danmaku_platform_call_duration_seconds",
+		"Platform call latency by operation.", nil, "platform", "op")
+
+	PlatformRetries = NewCounterVec("danmaku_platform_retries_total",
+		"Total number of platform request retries by reason.", "platform", "reason")
+
+	CoalescedRequests = NewCounterVec("danmaku_coalesced_requests_total",
+		"Total number of requests served by an in-flight identical request.", "op")
+
+	Prefetches = NewCounterVec("danmaku_prefetch_total",
+		"Total number of comment prefetches by result.", "result")
+
+	Refreshes = NewCounterVec("danmaku_refresh_total",
+		"Total number of scheduled comment refreshes by result.", "result")
+
+	CommentsReturned = NewCounterVec("danmaku_comments_returned_total",
+		"Total number of comments returned by the comment api.", "platform")
+)
+
+// ObservePlatformCall 记录平台调用次数、错误以及耗时
+func ObservePlatformCall(platform, op string, start time.Time, err error) {
+	result := resultSuccess
+	if err != nil {
+		result = resultError
+	}
+	platformCalls.Inc(platform, op, result)
+	platformCallDuration.Observe(time.Since(start).Seconds(), platform, op)
+}
@@ -0,0 +1,243 @@
+package metrics
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+/*
+	prometheus 文本格式的简易实现 只支持 counter gauge histogram
+	https://prometheus.io/docs/instrumenting/exposition_formats/
+
+	指标在包初始化时注册，label 值按注册时的 label 名称顺序传入
+*/
+
+const (
+	typeCounter   = "counter"
+	typeGauge     = "gauge"
+	typeHistogram = "histogram"
+)
+
+type collector interface {
+	name() string
+	write(sb *strings.Builder)
+}
+
+var registry = struct {
+	lock       sync.RWMutex
+	collectors []collector
+}{}
+
+// register 同名指标重复注册时覆盖旧的
+func register(c collector) {
+	registry.lock.Lock()
+	defer registry.lock.Unlock()
+	for i, v := range registry.collectors {
+		if v.name() == c.name() {
+			registry.collectors[i] = c
+			return
+		}
+	}
+	registry.collectors = append(registry.collectors, c)
+}
+
+// DefaultBuckets 默认耗时分布 单位秒
+var DefaultBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60}
+
+const labelSeparator = "\x00"
+
+type desc struct {
+	metricName string
+	help       string
+	labels     []string
+}
+
+func (d *desc) name() string {
+	return d.metricName
+}
+
+func (d *desc) header(sb *strings.Builder, metricType string) {
+	sb.WriteString("# HELP " + d.metricName + " " + d.help + "\n")
+	sb.WriteString("# TYPE " + d.metricName + " " + metricType + "\n")
+}
+
+func (d *desc) key(values []string) string {
+	if len(values) != len(d.labels) {
+		panic(fmt.Sprintf("metric %s expects %d labels, got %d", d.metricName, len(d.labels), len(values)))
+	}
+	return strings.Join(values, labelSeparator)
+}
+
+// formatLabels {a="1",b="2"} extra 用于 histogram 的 le
+func (d *desc) formatLabels(key string, extra ...string) string {
+	var pairs []string
+	if len(d.labels) > 0 {
+		for i, v := range strings.Split(key, labelSeparator) {
+			pairs = append(pairs, d.labels[i]+"="+strconv.Quote(v))
+		}
+	}
+	for i := 0; i+1 < len(extra); i += 2 {
+		pairs = append(pairs, extra[i]+"="+strconv.Quote(extra[i+1]))
+	}
+	if len(pairs) == 0 {
+		return ""
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+func formatValue(v float64) string {
+	if math.IsInf(v, 1) {
+		return "+Inf"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+func sortedKeys[T any](m map[string]T) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// CounterVec 只增计数器
+type CounterVec struct {
+	desc
+	lock   sync.Mutex
+	values map[string]float64
+}
+
+func NewCounterVec(name, help string, labels ...string) *CounterVec {
+	c := &CounterVec{desc: desc{metricName: name, help: help, labels: labels}, values: map[string]float64{}}
+	register(c)
+	return c
+}
+
+func (c *CounterVec) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+func (c *CounterVec) Add(v float64, labelValues ...string) {
+	if v < 0 {
+		return
+	}
+	key := c.key(labelValues)
+	c.lock.Lock()
+	c.values[key] += v
+	c.lock.Unlock()
+}
+
+func (c *CounterVec) write(sb *strings.Builder) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.header(sb, typeCounter)
+	for _, k := range sortedKeys(c.values) {
+		sb.WriteString(c.metricName + c.formatLabels(k) + " " + formatValue(c.values[k]) + "\n")
+	}
+}
+
+type histogramValue struct {
+	counts []uint64 // 与 buckets 一一对应 非累计
+	count  uint64
+	sum    float64
+}
+
+// HistogramVec 分布统计
+type HistogramVec struct {
+	desc
+	buckets []float64
+	lock    sync.Mutex
+	values  map[string]*histogramValue
+}
+
+func NewHistogramVec(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	if len(buckets) == 0 {
+		buckets = DefaultBuckets
+	}
+	h := &HistogramVec{
+		desc:    desc{metricName: name, help: help, labels: labels},
+		buckets: buckets,
+		values:  map[string]*histogramValue{},
+	}
+	register(h)
+	return h
+}
+
+func (h *HistogramVec) Observe(v float64, labelValues ...string) {
+	key := h.key(labelValues)
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	hv, ok := h.values[key]
+	if !ok {
+		hv = &histogramValue{counts: make([]uint64, len(h.buckets))}
+		h.values[key] = hv
+	}
+	if i := sort.SearchFloat64s(h.buckets, v); i < len(h.buckets) {
+		hv.counts[i]++
+	}
+	hv.count++
+	hv.sum += v
+}
+
+func (h *HistogramVec) write(sb *strings.Builder) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	h.header(sb, typeHistogram)
+	for _, k := range sortedKeys(h.values) {
+		hv := h.values[k]
+		var cumulative uint64
+		for i, le := range h.buckets {
+			cumulative += hv.counts[i]
+			sb.WriteString(h.metricName + "_bucket" + h.formatLabels(k, "le", formatValue(le)) + " " + strconv.FormatUint(cumulative, 10) + "\n")
+		}
+		sb.WriteString(h.metricName + "_bucket" + h.formatLabels(k, "le", "+Inf") + " " + strconv.FormatUint(hv.count, 10) + "\n")
+		sb.WriteString(h.metricName + "_sum" + h.formatLabels(k) + " " + formatValue(hv.sum) + "\n")
+		sb.WriteString(h.metricName + "_count" + h.formatLabels(k) + " " + strconv.FormatUint(hv.count, 10) + "\n")
+	}
+}
+
+// funcCollector 采集时回调获取当前值 用于外部组件自身已有的统计数据
+type funcCollector struct {
+	desc
+	metricType string
+	fn         func() float64
+}
+
+// NewGaugeFunc 采集时获取当前值
+func NewGaugeFunc(name, help string, fn func() float64) {
+	register(&funcCollector{desc: desc{metricName: name, help: help}, metricType: typeGauge, fn: fn})
+}
+
+// NewCounterFunc 采集时获取当前值 fn 返回值需要单调递增
+func NewCounterFunc(name, help string, fn func() float64) {
+	register(&funcCollector{desc: desc{metricName: name, help: help}, metricType: typeCounter, fn: fn})
+}
+
+func (f *funcCollector) write(sb *strings.Builder) {
+	f.header(sb, f.metricType)
+	sb.WriteString(f.metricName + " " + formatValue(f.fn()) + "\n")
+}
+
+// Handler prometheus 采集接口
+func Handler(w http.ResponseWriter, _ *http.Request) {
+	registry.lock.RLock()
+	collectors := make([]collector, len(registry.collectors))
+	copy(collectors, registry.collectors)
+	registry.lock.RUnlock()
+	sort.SliceStable(collectors, func(i, j int) bool {
+		return collectors[i].name() < collectors[j].name()
+	})
+
+	var sb strings.Builder
+	for _, c := range collectors {
+		c.write(&sb)
+	}
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	_, _ = w.Write([]byte(sb.String()))
+}
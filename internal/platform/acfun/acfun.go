@@ -0,0 +1,286 @@
+package acfun
+
+import (
+	"danmaku-tool/internal/danmaku"
+	"danmaku-tool/internal/utils"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+type client struct {
+	danmaku.PlatformClient
+
+	// 解析后的番剧页面 bangumiId -> *bangumiEntry 匹配以及实时获取弹幕时复用
+	bangumis sync.Map
+}
+
+// 番剧页面缓存时间
+const bangumiCacheTTL = 10 * time.Minute
+
+type bangumiEntry struct {
+	data    *BangumiData
+	list    *BangumiList
+	expires time.Time
+}
+
+func init() {
+	danmaku.RegisterInitializer(&client{})
+}
+
+func (c *client) Init() error {
+	if err := danmaku.InitPlatformClient(&c.PlatformClient, danmaku.Acfun); err != nil {
+		return err
+	}
+	danmaku.RegisterScraper(c)
+	return nil
+}
+
+func (c *client) Platform() danmaku.Platform {
+	return danmaku.Acfun
+}
+
+// ResolveURL 支持 acfun.cn 番剧以及单集链接
+func (c *client) ResolveURL(u *url.URL) (string, error) {
+	if !danmaku.MatchHost(u, "acfun.cn") {
+		return "", nil
+	}
+	// https://www.acfun.cn/bangumi/aa6002917_36188_1740687
+	if m := bangumiUrlRegex.FindStringSubmatch(u.Path); len(m) > 1 {
+		return m[1], nil
+	}
+	return "", fmt.Errorf("only support bangumi url: %s", u)
+}
+
+/*
+	AcFun 番剧链接格式
+	https://www.acfun.cn/bangumi/aa6002917 aa 后面是 bangumiId
+	https://www.acfun.cn/bangumi/aa6002917_36188_1740687 最后一段是 itemId
+
+	弹幕接口使用的是单集的 videoId，需要从番剧页面获取，所以单集id使用 {bangumiId}_{itemId} 格式保存
+*/
+
+const idSeparator = "_"
+
+func combineId(bangumiId, itemId int64) string {
+	return strconv.FormatInt(bangumiId, 10) + idSeparator + strconv.FormatInt(itemId, 10)
+}
+
+func splitId(id string) (bangumiId, itemId string) {
+	id = strings.TrimPrefix(id, "aa")
+	parts := strings.Split(id, idSeparator)
+	if len(parts) > 1 {
+		return parts[0], parts[len(parts)-1]
+	}
+	return parts[0], ""
+}
+
+func (c *client) setReq(req *http.Request) {
+	req.Header.Set("Cookie", c.Cookie())
+	req.Header.Set("Referer", "https://www.acfun.cn/")
+}
+
+func (c *client) search(keyword string) (*SearchResult, error) {
+	params := url.Values{
+		"keyword": {keyword},
+		"pCursor": {"1"},
+	}
+	api := "https://www.acfun.cn/rest/pc-direct/search/bgm?" + params.Encode()
+	req, err := http.NewRequest(http.MethodGet, api, nil)
+	if err != nil {
+		return nil, err
+	}
+	c.setReq(req)
+	resp, err := c.DoReq(req)
+	if err != nil {
+		return nil, err
+	}
+
+	var result SearchResult
+	err = utils.SafeDecodeOkResp(resp, &result)
+	if err != nil {
+		return nil, err
+	}
+	if result.Result != 0 {
+		return nil, fmt.Errorf("search error: %d %s", result.Result, result.ErrorMsg)
+	}
+	return &result, nil
+}
+
+// bangumiInfo 从番剧页面解析番剧和单集信息
+func (c *client) bangumiInfo(bangumiId string) (*BangumiData, *BangumiList, error) {
+	api := "https://www.acfun.cn/bangumi/aa" + bangumiId
+	req, err := http.NewRequest(http.MethodGet, api, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	c.setReq(req)
+	resp, err := c.DoReq(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer utils.SafeClose(resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		return nil, nil, fmt.Errorf("bangumi page status: %s", resp.Status)
+	}
+
+	bodyBytes, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, nil, err
+	}
+	htmlContent := string(bodyBytes)
+
+	dataMatches := bangumiDataRegex.FindStringSubmatch(htmlContent)
+	if len(dataMatches) < 2 {
+		return nil, nil, fmt.Errorf("%s match bangumi data fail from html", bangumiId)
+	}
+	var data BangumiData
+	if err = json.Unmarshal([]byte(dataMatches[1]), &data); err != nil {
+		return nil, nil, err
+	}
+
+	listMatches := bangumiListRegex.FindStringSubmatch(htmlContent)
+	if len(listMatches) < 2 {
+		return nil, nil, fmt.Errorf("%s match bangumi list fail from html", bangumiId)
+	}
+	var list BangumiList
+	if err = json.Unmarshal([]byte(listMatches[1]), &list); err != nil {
+		return nil, nil, err
+	}
+
+	return &data, &list, nil
+}
+
+// cachedBangumiInfo 优先使用缓存的番剧页面 抓取保存弹幕时直接使用 bangumiInfo 获取最新单集
+func (c *client) cachedBangumiInfo(bangumiId string) (*BangumiData, *BangumiList, error) {
+	now := time.Now()
+	if v, ok := c.bangumis.Load(bangumiId); ok {
+		if e := v.(*bangumiEntry); now.Before(e.expires) {
+			return e.data, e.list, nil
+		}
+	}
+	data, list, err := c.bangumiInfo(bangumiId)
+	if err != nil {
+		return nil, nil, err
+	}
+	// 写入时顺便清理过期的番剧 避免常驻内存
+	c.bangumis.Range(func(key, value any) bool {
+		if now.After(value.(*bangumiEntry).expires) {
+			c.bangumis.Delete(key)
+		}
+		return true
+	})
+	c.bangumis.Store(bangumiId, &bangumiEntry{data: data, list: list, expires: now.Add(bangumiCacheTTL)})
+	return data, list, nil
+}
+
+func (c *client) Media(id string) (*danmaku.Media, error) {
+	bangumiId, _ := splitId(id)
+	data, list, err := c.cachedBangumiInfo(bangumiId)
+	if err != nil {
+		return nil, err
+	}
+
+	year, _ := strconv.ParseInt(data.BangumiYear, 10, 64)
+	result := &danmaku.Media{
+		Id:       strconv.FormatInt(data.BangumiId, 10),
+		Title:    data.BangumiTitle,
+		Desc:     data.BangumiIntro,
+		Cover:    data.CoverImageV,
+		Year:     int(year),
+		Type:     danmaku.Series,
+		Episodes: parseEpisodes(list.Items),
+		Platform: danmaku.Acfun,
+	}
+	if len(result.Episodes) == 1 {
+		result.Type = danmaku.Movie
+	}
+
+	return result, nil
+}
+
+func parseEpisodes(items []BangumiItem) []*danmaku.MediaEpisode {
+	var eps = make([]*danmaku.MediaEpisode, 0, len(items))
+	for i, item := range items {
+		if danmaku.InvalidEpTitle(item.EpisodeName) || danmaku.InvalidEpTitle(item.Title) {
+			continue
+		}
+		// 第1话 解析数字集数 解析不出则使用下标
+		epId := strconv.FormatInt(int64(i+1), 10)
+		if matches := episodeNumberRegex.FindStringSubmatch(item.EpisodeName); len(matches) > 1 {
+			epId = matches[1]
+		}
+		eps = append(eps, &danmaku.MediaEpisode{
+			Id:        combineId(item.BangumiId, item.ItemId),
+			EpisodeId: epId,
+			Title:     item.Title,
+		})
+	}
+	return eps
+}
+
+type task struct {
+	videoId int64
+	segment int64
+}
+
+// 弹幕按时间分片获取 单位ms
+const segmentInMills = 60 * 1000
+
+func (c *client) scrape(videoId int64, segment int64) ([]*danmaku.StandardDanmaku, error) {
+	formData := url.Values{
+		"resourceId":          {strconv.FormatInt(videoId, 10)},
+		"resourceType":        {"9"},
+		"positionFromInclude": {strconv.FormatInt(segment*segmentInMills, 10)},
+		"positionToExclude":   {strconv.FormatInt((segment+1)*segmentInMills, 10)},
+		"enableAdvanced":      {"true"},
+	}
+	api := "https://www.acfun.cn/rest/pc-direct/new-danmaku/pollByPosition"
+	req, err := http.NewRequest(http.MethodPost, api, strings.NewReader(formData.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	c.setReq(req)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp, err := c.DoReq(req)
+	if err != nil {
+		return nil, err
+	}
+
+	var danmakuResult DanmakuResult
+	err = utils.SafeDecodeOkResp(resp, &danmakuResult)
+	if err != nil {
+		return nil, err
+	}
+	if danmakuResult.Result != 0 {
+		return nil, fmt.Errorf("scrape danmaku error: %d %s", danmakuResult.Result, danmakuResult.ErrorMsg)
+	}
+
+	var result = make([]*danmaku.StandardDanmaku, 0, len(danmakuResult.Added))
+	for _, d := range danmakuResult.Added {
+		mode := d.Mode
+		if mode != danmaku.TopMode && mode != danmaku.BottomMode {
+			mode = danmaku.NormalMode
+		}
+		color := d.Color
+		if color <= 0 {
+			color = danmaku.WhiteColor
+		}
+		result = append(result, &danmaku.StandardDanmaku{
+			Content:     d.Body,
+			OffsetMills: d.Position,
+			Mode:        mode,
+			Color:       color,
+			FontSize:    d.Size,
+			Platform:    danmaku.Acfun,
+			Likes:       d.LikeCount,
+		})
+	}
+	return result, nil
+}
@@ -0,0 +1,225 @@
+package acfun
+
+import (
+	"context"
+	"danmaku-tool/internal/config"
+	"danmaku-tool/internal/danmaku"
+	"danmaku-tool/internal/utils"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+)
+
+func (c *client) Scrape(realId string) error {
+	// aa6002917 抓取整部番剧 aa6002917_36188_1740687 只抓取单集
+	bangumiId, itemId := splitId(realId)
+	if bangumiId == "" {
+		return fmt.Errorf("invalid id: %s", realId)
+	}
+	data, list, err := c.bangumiInfo(bangumiId)
+	if err != nil {
+		return err
+	}
+
+	utils.InfoLog(danmaku.Acfun, "scrape start", "id", realId)
+	savePath := filepath.Join(config.GetConfig().SavePath, danmaku.Acfun, bangumiId)
+	for i, item := range list.Items {
+		if itemId != "" && strconv.FormatInt(item.ItemId, 10) != itemId {
+			continue
+		}
+		if danmaku.UpToDate(savePath, strconv.FormatInt(item.ItemId, 10)) {
+			continue
+		}
+		c.scrapeEpisode(context.Background(), bangumiId, data, i, item)
+	}
+
+	utils.InfoLog(danmaku.Acfun, "danmaku scraped done", "title", data.BangumiTitle)
+	return nil
+}
+
+// ScrapeEpisode id格式同 GetDanmaku 文件与 Scrape 一致
+func (c *client) ScrapeEpisode(ctx context.Context, id string) ([]string, int, error) {
+	bangumiId, itemId := splitId(id)
+	if bangumiId == "" || itemId == "" {
+		return nil, 0, fmt.Errorf("invalid id: %s", id)
+	}
+	data, list, err := c.cachedBangumiInfo(bangumiId)
+	if err != nil {
+		return nil, 0, err
+	}
+	for i, item := range list.Items {
+		if strconv.FormatInt(item.ItemId, 10) == itemId {
+			return c.scrapeEpisode(ctx, bangumiId, data, i, item)
+		}
+	}
+	return nil, 0, fmt.Errorf("%s episode not found", id)
+}
+
+// scrapeEpisode 抓取并保存单集 index为单集在列表中的下标
+func (c *client) scrapeEpisode(ctx context.Context, bangumiId string, data *BangumiData, index int, item BangumiItem) ([]string, int, error) {
+	result := c.scrapeDanmaku(ctx, item)
+	if err := ctx.Err(); err != nil {
+		return nil, 0, err
+	}
+	year, _ := strconv.Atoi(data.BangumiYear)
+	serializer := &danmaku.SerializerData{
+		EpisodeId:       strconv.FormatInt(item.ItemId, 10),
+		SeasonId:        bangumiId,
+		DurationInMills: item.DurationMillis,
+		Data:            result,
+		Title:           data.BangumiTitle,
+		Year:            year,
+		EpisodeNumber:   index + 1,
+		EpisodeTitle:    item.Title,
+	}
+	if matches := episodeNumberRegex.FindStringSubmatch(item.EpisodeName); len(matches) > 1 {
+		serializer.EpisodeNumber, _ = strconv.Atoi(matches[1])
+	}
+	savePath := filepath.Join(config.GetConfig().SavePath, danmaku.Acfun, bangumiId)
+	files := danmaku.WriteFile(danmaku.Acfun, serializer, savePath, strconv.FormatInt(item.ItemId, 10))
+	utils.InfoLog(danmaku.Acfun, "ep scraped done", "itemId", item.ItemId, "size", len(result))
+	return files, len(result), nil
+}
+
+func (c *client) Match(param danmaku.MatchParam) ([]*danmaku.Media, error) {
+	keyword := param.Title
+	searchResult, err := c.search(keyword)
+	if err != nil {
+		return nil, err
+	}
+
+	// 并发获取番剧页面 4并发 按照搜索结果顺序返回
+	var medias = make([]*danmaku.Media, len(searchResult.BgmList))
+	sem := make(chan struct{}, 4)
+	var wg sync.WaitGroup
+	for i, bangumi := range searchResult.BgmList {
+		year, ok := param.MatchYearString(yearRegex.FindString(bangumi.ReleaseTime))
+		if !ok && param.ProductionYear > 0 {
+			continue
+		}
+
+		match := param.MatchTitle(bangumi.BgmTitle)
+		utils.DebugLog(danmaku.Acfun, fmt.Sprintf("[%s] match [%s]: %v", bangumi.BgmTitle, param.Title, match))
+		if !match {
+			continue
+		}
+
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(index int, bangumi SearchBangumi, year int) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			bangumiId := strconv.FormatInt(bangumi.Id, 10)
+			_, list, e := c.cachedBangumiInfo(bangumiId)
+			if e != nil {
+				utils.ErrorLog(danmaku.Acfun, e.Error(), "bangumiId", bangumiId)
+				return
+			}
+			eps := parseEpisodes(list.Items)
+			// 匹配剧场版 使用下标作为S00的epId
+			if param.SeasonId == 0 {
+				for i, ep := range eps {
+					ep.EpisodeId = strconv.FormatInt(int64(i+1), 10)
+				}
+			}
+
+			medias[index] = &danmaku.Media{
+				Id:       bangumiId,
+				Type:     parseMediaType(bangumi.BgmType),
+				TypeDesc: bangumi.BgmTypeName,
+				Title:    danmaku.ClearTitle(bangumi.BgmTitle),
+				Desc:     bangumi.Intro,
+				Cover:    bangumi.CoverImageV,
+				Year:     year,
+				Episodes: eps,
+				Platform: danmaku.Acfun,
+			}
+		}(i, bangumi, year)
+	}
+	wg.Wait()
+
+	var result = make([]*danmaku.Media, 0, 10)
+	for _, m := range medias {
+		if m != nil {
+			result = append(result, m)
+		}
+	}
+	return result, nil
+}
+
+// GetDanmaku id格式 {bangumiId}_{itemId}
+func (c *client) GetDanmaku(id string) ([]*danmaku.StandardDanmaku, error) {
+	bangumiId, itemId := splitId(id)
+	if bangumiId == "" || itemId == "" {
+		return nil, fmt.Errorf("invalid id: %s", id)
+	}
+	_, list, err := c.cachedBangumiInfo(bangumiId)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, item := range list.Items {
+		if strconv.FormatInt(item.ItemId, 10) != itemId {
+			continue
+		}
+		result := c.scrapeDanmaku(context.Background(), item)
+		utils.InfoLog(danmaku.Acfun, "get danmaku done", "size", len(result))
+		return result, nil
+	}
+
+	return nil, fmt.Errorf("%s item not found", id)
+}
+
+func (c *client) scrapeDanmaku(ctx context.Context, item BangumiItem) []*danmaku.StandardDanmaku {
+	segments := item.DurationMillis/segmentInMills + 1
+
+	tasks := make(chan task, c.MaxWorker())
+	ch := make(chan []*danmaku.StandardDanmaku, c.MaxWorker())
+	var wg sync.WaitGroup
+	for w := 0; w < c.MaxWorker(); w++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for t := range tasks {
+				// 取消后跳过剩余的分片
+				if ctx.Err() != nil {
+					continue
+				}
+				start := time.Now()
+				data, e := c.scrape(t.videoId, t.segment)
+				danmaku.ObserveSegment(ctx, danmaku.Acfun, start, e)
+				if e != nil {
+					utils.ErrorLog(danmaku.Acfun, fmt.Sprintf("%d scrape segment %d error: %s", t.videoId, t.segment, e.Error()))
+					continue
+				}
+				if len(data) <= 0 {
+					continue
+				}
+				ch <- data
+			}
+		}(w)
+	}
+
+	go func() {
+		for seg := int64(0); seg < segments; seg++ {
+			tasks <- task{
+				videoId: item.VideoId,
+				segment: seg,
+			}
+		}
+		close(tasks)
+	}()
+
+	go func() {
+		wg.Wait()
+		close(ch)
+	}()
+	var result = make([]*danmaku.StandardDanmaku, 0, 10000)
+	for m := range ch {
+		result = append(result, m...)
+	}
+	return result
+}
@@ -0,0 +1,85 @@
+package acfun
+
+import (
+	"danmaku-tool/internal/danmaku"
+	"regexp"
+)
+
+var bangumiDataRegex = regexp.MustCompile(`window\.bangumiData\s*=\s*(\{.*});`)
+var bangumiListRegex = regexp.MustCompile(`window\.bangumiList\s*=\s*(\{.*});`)
+var bangumiUrlRegex = regexp.MustCompile(`^/bangumi/(aa\d+(?:_\d+)*)`)
+var episodeNumberRegex = regexp.MustCompile(`第\s*(\d+)\s*[话集]`)
+var yearRegex = regexp.MustCompile(`\d{4}`)
+
+type SearchResult struct {
+	Result   int             `json:"result"`
+	ErrorMsg string          `json:"error_msg"`
+	BgmList  []SearchBangumi `json:"bgmList"`
+}
+
+type SearchBangumi struct {
+	Id          int64  `json:"id"`          // bangumiId aa{id}
+	BgmTitle    string `json:"bgmTitle"`    // 注意有html标签 <span class="keyword">进击的巨人</span>
+	CoverImageV string `json:"coverImageV"` // 竖版封面
+	Intro       string `json:"intro"`
+	// 2013-04-07 或者 2013 年份信息
+	ReleaseTime string `json:"releaseTime"`
+	// 1 番剧 2 电影 ...
+	BgmType      int    `json:"bgmType"`
+	BgmTypeName  string `json:"bgmTypeName"`
+	EpisodeCount int    `json:"episodeCount"`
+}
+
+// BangumiData 番剧页面 window.bangumiData
+type BangumiData struct {
+	BangumiId    int64  `json:"bangumiId"`
+	BangumiTitle string `json:"bangumiTitle"`
+	BangumiIntro string `json:"bangumiIntro"`
+	CoverImageV  string `json:"bangumiCoverImageV"`
+	// 年份信息 2013
+	BangumiYear string `json:"bangumiYear"`
+	// 当前播放的单集
+	ItemId  int64 `json:"itemId"`
+	VideoId int64 `json:"videoId"`
+}
+
+// BangumiList 番剧页面 window.bangumiList 包含全部单集
+type BangumiList struct {
+	Items []BangumiItem `json:"items"`
+}
+
+type BangumiItem struct {
+	BangumiId      int64  `json:"bangumiId"`
+	ItemId         int64  `json:"itemId"`
+	VideoId        int64  `json:"videoId"`        // 弹幕 resourceId
+	EpisodeName    string `json:"episodeName"`    // 第1话
+	Title          string `json:"title"`          // 单集标题
+	DurationMillis int64  `json:"durationMillis"` // ms
+	Priority       int    `json:"priority"`       // 排序 和集数对应
+	// 付费 预告等类型
+	HideEpisodeName bool `json:"hideEpisodeName"`
+}
+
+type DanmakuResult struct {
+	Result   int    `json:"result"`
+	ErrorMsg string `json:"error_msg"`
+	Added    []struct {
+		DanmakuId int64  `json:"danmakuId"`
+		Position  int64  `json:"position"` // 偏移 ms
+		Body      string `json:"body"`
+		// 1滚动 4底部 5顶部 和b站一致
+		Mode  int   `json:"mode"`
+		Color int   `json:"color"`
+		Size  int32 `json:"size"`
+		// 点赞数
+		LikeCount int64 `json:"likeCount"`
+	} `json:"added"`
+}
+
+func parseMediaType(bgmType int) danmaku.MediaType {
+	switch bgmType {
+	case 2:
+		return danmaku.Movie
+	}
+	return danmaku.Series
+}
@@ -9,7 +9,11 @@ import (
 	"io"
 	"net/http"
 	"net/url"
+	"regexp"
 	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
 
 	"google.golang.org/protobuf/proto"
 )
@@ -17,8 +21,8 @@ import (
 type client struct {
 	danmaku.PlatformClient
 
-	// 接口签名token信息
-	token tokenKey
+	// 接口签名token信息 健康检查和请求并发更新
+	token atomic.Pointer[tokenKey]
 }
 
 func (c *client) Media(id string) (*danmaku.Media, error) {
@@ -52,6 +56,46 @@ func (c *client) Media(id string) (*danmaku.Media, error) {
 	return result, nil
 }
 
+// AirTime 支持 ep/ss/BV 以及 GetDanmaku 使用的数字epId ss返回最新一集的发布时间
+func (c *client) AirTime(id string) (time.Time, error) {
+	if strings.HasPrefix(id, "BV") {
+		video, err := c.view(id)
+		if err != nil {
+			return time.Time{}, err
+		}
+		return unixTime(video.Data.PubDate), nil
+	}
+	if ssId, ok := strings.CutPrefix(id, "ss"); ok {
+		series, err := c.baseInfo("", ssId)
+		if err != nil {
+			return time.Time{}, err
+		}
+		var latest int64
+		for _, ep := range series.Result.Episodes {
+			latest = max(latest, ep.PubTime)
+		}
+		return unixTime(latest), nil
+	}
+	epId := strings.TrimPrefix(id, "ep")
+	series, err := c.baseInfo(epId, "")
+	if err != nil {
+		return time.Time{}, err
+	}
+	for _, ep := range series.Result.Episodes {
+		if strconv.FormatInt(ep.EPId, 10) == epId {
+			return unixTime(ep.PubTime), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("ep%s not found", epId)
+}
+
+func unixTime(sec int64) time.Time {
+	if sec <= 0 {
+		return time.Time{}
+	}
+	return time.Unix(sec, 0)
+}
+
 func (c *client) Init() error {
 	if err := danmaku.InitPlatformClient(&c.PlatformClient, danmaku.Bilibili); err != nil {
 		return err
@@ -68,6 +112,55 @@ func (c *client) Platform() danmaku.Platform {
 	return danmaku.Bilibili
 }
 
+var (
+	bangumiPathRegex = regexp.MustCompile(`^/bangumi/play/((?:ep|ss)\d+)`)
+	videoPathRegex   = regexp.MustCompile(`^/video/(BV[0-9A-Za-z]{10})`)
+)
+
+// ResolveURL 支持番剧ep/ss链接 视频BV号链接 以及b23.tv短链接
+func (c *client) ResolveURL(u *url.URL) (string, error) {
+	if danmaku.MatchHost(u, "b23.tv") {
+		target, err := c.shortLink(u)
+		if err != nil {
+			return "", err
+		}
+		if danmaku.MatchHost(target, "b23.tv") {
+			return "", fmt.Errorf("short link redirect loop: %s", target)
+		}
+		return c.ResolveURL(target)
+	}
+	if !danmaku.MatchHost(u, "bilibili.com") {
+		return "", nil
+	}
+	// https://www.bilibili.com/bangumi/play/ep747309 https://m.bilibili.com/bangumi/play/ss36204
+	if m := bangumiPathRegex.FindStringSubmatch(u.Path); len(m) > 1 {
+		return m[1], nil
+	}
+	// https://www.bilibili.com/video/BV1xx411c7mD
+	if m := videoPathRegex.FindStringSubmatch(u.Path); len(m) > 1 {
+		return m[1], nil
+	}
+	return "", fmt.Errorf("only support bangumi ep/ss or video BV url: %s", u)
+}
+
+// shortLink b23.tv 短链接302跳转的实际地址
+func (c *client) shortLink(u *url.URL) (*url.URL, error) {
+	req, err := http.NewRequest(http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.DoReqWithoutRedirect(req)
+	if err != nil {
+		return nil, err
+	}
+	defer utils.SafeClose(resp.Body)
+	location := resp.Header.Get("Location")
+	if location == "" {
+		return nil, fmt.Errorf("short link %s no redirect: %s", u, resp.Status)
+	}
+	return u.Parse(location)
+}
+
 func (c *client) searchByType(searchType string, keyword string) (*SearchResult, error) {
 	api := "https://api.bilibili.com/x/web-interface/wbi/search/type?"
 	params := url.Values{
@@ -87,7 +180,7 @@ func (c *client) searchByType(searchType string, keyword string) (*SearchResult,
 		return nil, err
 	}
 
-	req.Header.Set("Cookie", c.Cookie)
+	req.Header.Set("Cookie", c.Cookie())
 	resp, err := c.DoReq(req)
 	if err != nil {
 		return nil, err
@@ -135,7 +228,30 @@ func (c *client) baseInfo(epId string, ssId string) (*SeriesInfo, error) {
 	return &series, nil
 }
 
-func (c *client) scrape(oid, pid, segmentIndex int64) []*DanmakuElem {
+func (c *client) view(bvid string) (*VideoInfo, error) {
+	api := "https://api.bilibili.com/x/web-interface/view?bvid=" + url.QueryEscape(bvid)
+	req, err := http.NewRequest(http.MethodGet, api, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Cookie", c.Cookie())
+	resp, err := c.DoReq(req)
+	if err != nil {
+		return nil, err
+	}
+
+	var video VideoInfo
+	err = utils.SafeDecodeOkResp(resp, &video)
+	if err != nil {
+		return nil, err
+	}
+	if video.Code != 0 {
+		return nil, fmt.Errorf("view resp error code: %v, message: %s", video.Code, video.Message)
+	}
+	return &video, nil
+}
+
+func (c *client) scrape(oid, pid, segmentIndex int64) ([]*DanmakuElem, error) {
 	params := url.Values{
 		"type":          {"1"},
 		"oid":           {strconv.FormatInt(oid, 10)},
@@ -146,24 +262,21 @@ func (c *client) scrape(oid, pid, segmentIndex int64) []*DanmakuElem {
 
 	req, err := http.NewRequest(http.MethodGet, api, nil)
 	if err != nil {
-		utils.ErrorLog(danmaku.Bilibili, err.Error())
-		return nil
+		return nil, err
 	}
 
 	// 2. 【关键】设置 Accept-Encoding: gzip，告诉服务器客户端支持 Gzip 压缩
 	req.Header.Set("Accept-Encoding", "gzip")
-	req.Header.Set("Cookie", c.Cookie)
+	req.Header.Set("Cookie", c.Cookie())
 
 	resp, err := c.DoReq(req)
 	if err != nil {
-		utils.ErrorLog(danmaku.Bilibili, err.Error())
-		return nil
+		return nil, err
 	}
 	defer utils.SafeClose(resp.Body)
 
 	if resp.StatusCode != http.StatusOK {
-		utils.ErrorLog(danmaku.Bilibili, fmt.Sprintf("scrape segment status: %s", resp.Status))
-		return nil
+		return nil, fmt.Errorf("scrape segment status: %s", resp.Status)
 	}
 
 	// 没有权限会返回json 400错误，但是status=200
@@ -173,33 +286,27 @@ func (c *client) scrape(oid, pid, segmentIndex int64) []*DanmakuElem {
 			var raw = json.RawMessage{}
 			err = json.NewDecoder(resp.Body).Decode(&raw)
 			if err != nil {
-				utils.ErrorLog(danmaku.Bilibili, err.Error())
-			} else {
-				utils.ErrorLog(danmaku.Bilibili, fmt.Sprintf("unknown error: %s", string(raw)))
+				return nil, err
 			}
-		} else {
-			utils.ErrorLog(danmaku.Bilibili, fmt.Sprintf("unknown content type: %s", contentType))
+			return nil, fmt.Errorf("unknown error: %s", string(raw))
 		}
-		return nil
+		return nil, fmt.Errorf("unknown content type: %s", contentType)
 	}
 
 	gzipReader, err := gzip.NewReader(resp.Body)
 	if err != nil {
-		utils.ErrorLog(danmaku.Bilibili, err.Error())
-		return nil
+		return nil, err
 	}
 	defer utils.SafeClose(gzipReader)
 	reply := &DmSegMobileReply{}
 	jsonBytes, err := io.ReadAll(gzipReader)
 	if err != nil {
-		utils.ErrorLog(danmaku.Bilibili, err.Error())
-		return nil
+		return nil, err
 	}
 	if err := proto.Unmarshal(jsonBytes, reply); err != nil {
-		utils.ErrorLog(danmaku.Bilibili, err.Error())
-		return nil
+		return nil, err
 	}
-	return reply.GetElems()
+	return reply.GetElems(), nil
 }
 
 type task struct {
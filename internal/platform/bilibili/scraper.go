@@ -1,12 +1,14 @@
 package bilibili
 
 import (
+	"context"
 	"danmaku-tool/internal/config"
 	"danmaku-tool/internal/danmaku"
 	"danmaku-tool/internal/utils"
 	"fmt"
 	"path"
 	"path/filepath"
+	"regexp"
 	"strconv"
 	"strings"
 	"sync"
@@ -26,8 +28,11 @@ func (c *client) Scrape(realId string) error {
 	if strings.HasPrefix(realId, "ss") {
 		ssId = strings.Replace(realId, "ss", "", 1)
 	}
+	if strings.HasPrefix(realId, "BV") {
+		return c.scrapeVideo(realId)
+	}
 	if epId == "" && ssId == "" {
-		return fmt.Errorf("only support epid or ssid")
+		return fmt.Errorf("only support epid, ssid or bvid")
 	}
 
 	series, err := c.baseInfo(epId, ssId)
@@ -54,24 +59,11 @@ func (c *client) Scrape(realId string) error {
 			continue
 		}
 
-		data, err := c.GetDanmaku(strconv.FormatInt(ep.EPId, 10))
-		if err != nil {
-			utils.InfoLog(danmaku.Bilibili, fmt.Sprintf("%d scrape error: %s", ep.EPId, err.Error()))
+		if danmaku.UpToDate(savePath, strconv.FormatInt(ep.EPId, 10)) {
 			continue
 		}
 		epTitle = ep.Title
-
-		serializer := &danmaku.SerializerData{
-			EpisodeId:       strconv.FormatInt(ep.EPId, 10),
-			SeasonId:        strconv.FormatInt(series.Result.SeasonId, 10),
-			DurationInMills: ep.Duration,
-			Data:            data,
-			ResX:            ep.Dimension.Width,
-			ResY:            ep.Dimension.Height,
-		}
-
-		danmaku.WriteFile(danmaku.Bilibili, serializer, savePath, strconv.FormatInt(ep.EPId, 10))
-		utils.InfoLog(danmaku.Bilibili, "ep scraped done", "epId", ep.EPId, "size", len(data))
+		c.scrapeEpisode(context.Background(), series, ep)
 	}
 
 	var t = series.Result.Title
@@ -83,6 +75,95 @@ func (c *client) Scrape(realId string) error {
 	return nil
 }
 
+// ScrapeEpisode id为 Media 返回的epId 文件与 Scrape 一致
+func (c *client) ScrapeEpisode(ctx context.Context, id string) ([]string, int, error) {
+	series, err := c.baseInfo(id, "")
+	if err != nil {
+		return nil, 0, err
+	}
+	for _, ep := range series.Result.Episodes {
+		if strconv.FormatInt(ep.EPId, 10) == id {
+			return c.scrapeEpisode(ctx, series, ep)
+		}
+	}
+	return nil, 0, fmt.Errorf("ep%s not found", id)
+}
+
+// scrapeEpisode 抓取并保存单集 savePath/{platform}/{ssid}/{epid}.xml
+func (c *client) scrapeEpisode(ctx context.Context, series *SeriesInfo, ep SeriesEpisode) ([]string, int, error) {
+	data := c.danmakuOfCid(ctx, ep.CId, ep.Duration)
+	if err := ctx.Err(); err != nil {
+		return nil, 0, err
+	}
+	serializer := &danmaku.SerializerData{
+		EpisodeId:       strconv.FormatInt(ep.EPId, 10),
+		SeasonId:        strconv.FormatInt(series.Result.SeasonId, 10),
+		DurationInMills: ep.Duration,
+		Data:            data,
+		ResX:            ep.Dimension.Width,
+		ResY:            ep.Dimension.Height,
+		Title:           series.Result.Title,
+		EpisodeTitle:    ep.ShowTitle,
+	}
+	serializer.EpisodeNumber, _ = strconv.Atoi(ep.Title)
+	if ep.PubTime > 0 {
+		serializer.Year = time.Unix(ep.PubTime, 0).Year()
+	}
+
+	savePath := filepath.Join(config.GetConfig().SavePath, danmaku.Bilibili, serializer.SeasonId)
+	files := danmaku.WriteFile(danmaku.Bilibili, serializer, savePath, serializer.EpisodeId)
+	utils.InfoLog(danmaku.Bilibili, "ep scraped done", "epId", ep.EPId, "size", len(data))
+	return files, len(data), nil
+}
+
+var bangumiEpRegex = regexp.MustCompile(`/bangumi/play/(ep\d+)`)
+
+// scrapeVideo 抓取普通视频的所有分P 番剧的BV号按照ep抓取
+func (c *client) scrapeVideo(bvid string) error {
+	video, err := c.view(bvid)
+	if err != nil {
+		return err
+	}
+	if m := bangumiEpRegex.FindStringSubmatch(video.Data.RedirectUrl); len(m) > 1 {
+		utils.DebugLog(danmaku.Bilibili, "video redirect to bangumi", "bvid", bvid, "ep", m[1])
+		return c.Scrape(m[1])
+	}
+
+	utils.InfoLog(danmaku.Bilibili, "scrape start", "id", bvid)
+	// savePath/{platform}/{bvid}/{cid}.xml
+	savePath := filepath.Join(config.GetConfig().SavePath, danmaku.Bilibili, bvid)
+	for _, page := range video.Data.Pages {
+		cid := strconv.FormatInt(page.CId, 10)
+		if danmaku.UpToDate(savePath, cid) {
+			continue
+		}
+		data := c.danmakuOfCid(context.Background(), page.CId, page.Duration*1000)
+
+		serializer := &danmaku.SerializerData{
+			EpisodeId:       cid,
+			SeasonId:        bvid,
+			DurationInMills: page.Duration * 1000,
+			Data:            data,
+			ResX:            page.Dimension.Width,
+			ResY:            page.Dimension.Height,
+			Title:           video.Data.Title,
+			EpisodeNumber:   page.Page,
+		}
+		// 单P视频的分P标题通常与视频标题相同
+		if len(video.Data.Pages) > 1 {
+			serializer.EpisodeTitle = page.Part
+		}
+		if video.Data.PubDate > 0 {
+			serializer.Year = time.Unix(video.Data.PubDate, 0).Year()
+		}
+
+		danmaku.WriteFile(danmaku.Bilibili, serializer, savePath, cid)
+		utils.InfoLog(danmaku.Bilibili, "page scraped done", "cid", page.CId, "page", page.Page, "size", len(data))
+	}
+	utils.InfoLog(danmaku.Bilibili, "danmaku scraped done", "title", video.Data.Title)
+	return nil
+}
+
 func (c *client) Match(param danmaku.MatchParam) ([]*danmaku.Media, error) {
 	keyword := param.Title
 	var data = make([]*danmaku.Media, 0, 10)
@@ -205,62 +286,78 @@ func (c *client) GetDanmaku(realId string) ([]*danmaku.StandardDanmaku, error) {
 		if strconv.FormatInt(ep.EPId, 10) != realId {
 			continue
 		}
+		result = append(result, c.danmakuOfCid(context.Background(), ep.CId, ep.Duration)...)
+	}
 
-		var videoDuration = ep.Duration/1000 + 1 // in seconds
-		var segments int64
-		if videoDuration%360 == 0 {
-			segments = videoDuration / 360
-		} else {
-			segments = videoDuration/360 + 1
-		}
+	utils.InfoLog(danmaku.Bilibili, "get danmaku done", "size", len(result))
 
-		tasks := make(chan task, c.MaxWorker)
-		ch := make(chan []*danmaku.StandardDanmaku, c.MaxWorker)
-		var wg sync.WaitGroup
-		for w := 0; w < c.MaxWorker; w++ {
-			wg.Add(1)
-			go func(i int) {
-				defer wg.Done()
-				for t := range tasks {
-					data := c.scrape(t.cid, 0, t.segment)
-					if data == nil {
-						continue
-					}
-					var standardData = make([]*danmaku.StandardDanmaku, 0, len(data))
-					for _, d := range data {
-						standardData = append(standardData, &danmaku.StandardDanmaku{
-							Content:     d.Content,
-							OffsetMills: int64(d.Progress),
-							Mode:        int(d.Mode),
-							Color:       int(d.Color),
-							FontSize:    d.Fontsize,
-						})
-					}
-					ch <- standardData
-				}
-			}(w)
-		}
+	return result, nil
+}
+
+// danmakuOfCid 按照6分钟分片并发抓取视频弹幕 duration in Millisecond
+func (c *client) danmakuOfCid(ctx context.Context, cid, duration int64) []*danmaku.StandardDanmaku {
+	var videoDuration = duration/1000 + 1 // in seconds
+	var segments int64
+	if videoDuration%360 == 0 {
+		segments = videoDuration / 360
+	} else {
+		segments = videoDuration/360 + 1
+	}
 
-		go func() {
-			for seg := int64(1); seg <= segments; seg++ {
-				tasks <- task{
-					cid:     ep.CId,
-					segment: seg,
+	tasks := make(chan task, c.MaxWorker())
+	ch := make(chan []*danmaku.StandardDanmaku, c.MaxWorker())
+	var wg sync.WaitGroup
+	for w := 0; w < c.MaxWorker(); w++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for t := range tasks {
+				// 取消后跳过剩余的分片
+				if ctx.Err() != nil {
+					continue
+				}
+				start := time.Now()
+				data, e := c.scrape(t.cid, 0, t.segment)
+				danmaku.ObserveSegment(ctx, danmaku.Bilibili, start, e)
+				if e != nil {
+					utils.ErrorLog(danmaku.Bilibili, fmt.Sprintf("%d scrape segment %d error: %s", t.cid, t.segment, e.Error()))
+					continue
 				}
+				if len(data) <= 0 {
+					continue
+				}
+				var standardData = make([]*danmaku.StandardDanmaku, 0, len(data))
+				for _, d := range data {
+					standardData = append(standardData, &danmaku.StandardDanmaku{
+						Content:     d.Content,
+						OffsetMills: int64(d.Progress),
+						Mode:        int(d.Mode),
+						Color:       int(d.Color),
+						FontSize:    d.Fontsize,
+					})
+				}
+				ch <- standardData
 			}
-			close(tasks)
-		}()
-
-		go func() {
-			wg.Wait()
-			close(ch)
-		}()
-		for m := range ch {
-			result = append(result, m...)
-		}
+		}(w)
 	}
 
-	utils.InfoLog(danmaku.Bilibili, "get danmaku done", "size", len(result))
+	go func() {
+		for seg := int64(1); seg <= segments; seg++ {
+			tasks <- task{
+				cid:     cid,
+				segment: seg,
+			}
+		}
+		close(tasks)
+	}()
 
-	return result, nil
+	go func() {
+		wg.Wait()
+		close(ch)
+	}()
+	var result []*danmaku.StandardDanmaku
+	for m := range ch {
+		result = append(result, m...)
+	}
+	return result
 }
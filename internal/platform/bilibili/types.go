@@ -8,24 +8,7 @@ type SeriesInfo struct {
 	Result  struct {
 		Cover string `json:"cover"`
 		// 当前EP所在Season所有EPs 电影也会返回数据 只有一条
-		Episodes []struct { // 0 第一集 1 第二集 预告可能也会在里面
-			AId         int64  `json:"aid"`
-			BVId        string `json:"bvid"`
-			CId         int64  `json:"cid"`
-			Duration    int64  `json:"duration"` // in Millisecond
-			EPId        int64  `json:"ep_id"`
-			SectionType int    `json:"section_type"` // 1 是预告之类的 0是正常剧集？？
-			Link        string `json:"link"`
-			Title       string `json:"title"`      // 1 集数编号
-			ShowTitle   string `json:"show_title"` // 第1话 阿七的特别任务
-			PubTime     int64  `json:"pub_time"`
-			// 分辨率信息
-			Dimension struct {
-				Height int `json:"height"`
-				Rotate int `json:"rotate"`
-				Width  int `json:"width"`
-			} `json:"dimension"`
-		} `json:"episodes"`
+		Episodes []SeriesEpisode `json:"episodes"` // 0 第一集 1 第二集 预告可能也会在里面
 		// 同系列所有季信息
 		Seasons []struct {
 			MediaId     int64  `json:"media_id"`
@@ -51,6 +34,26 @@ type SeriesInfo struct {
 	} `json:"result"`
 }
 
+// SeriesEpisode 剧集中的单集
+type SeriesEpisode struct {
+	AId         int64  `json:"aid"`
+	BVId        string `json:"bvid"`
+	CId         int64  `json:"cid"`
+	Duration    int64  `json:"duration"` // in Millisecond
+	EPId        int64  `json:"ep_id"`
+	SectionType int    `json:"section_type"` // 1 是预告之类的 0是正常剧集？？
+	Link        string `json:"link"`
+	Title       string `json:"title"`      // 1 集数编号
+	ShowTitle   string `json:"show_title"` // 第1话 阿七的特别任务
+	PubTime     int64  `json:"pub_time"`
+	// 分辨率信息
+	Dimension struct {
+		Height int `json:"height"`
+		Rotate int `json:"rotate"`
+		Width  int `json:"width"`
+	} `json:"dimension"`
+}
+
 func parseMediaType(mediaType int) danmaku.MediaType {
 	switch mediaType {
 	case 2:
@@ -95,3 +98,27 @@ type SearchResult struct {
 func isSeries(mediaType int) bool {
 	return mediaType != 2
 }
+
+// VideoInfo 普通视频信息 番剧的BV号会返回跳转的ep链接
+type VideoInfo struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+	Data    struct {
+		BVId        string `json:"bvid"`
+		AId         int64  `json:"aid"`
+		Title       string `json:"title"`
+		PubDate     int64  `json:"pubdate"` // in seconds
+		RedirectUrl string `json:"redirect_url"`
+		// 分P信息
+		Pages []struct {
+			CId       int64  `json:"cid"`
+			Page      int    `json:"page"`
+			Part      string `json:"part"`
+			Duration  int64  `json:"duration"` // in seconds
+			Dimension struct {
+				Height int `json:"height"`
+				Width  int `json:"width"`
+			} `json:"dimension"`
+		} `json:"pages"`
+	} `json:"data"`
+}
@@ -13,34 +13,53 @@ import (
 	"time"
 )
 
-func (c *client) setToken() error {
+// 未登录时nav接口返回-101 依旧会返回wbi签名信息
+const navNotLoggedIn = -101
+
+// fetchToken 获取wbi签名token cookie可以不配置 loggedIn 为cookie是否有效
+func (c *client) fetchToken() (token *tokenKey, loggedIn bool, err error) {
 	keyUrl := "https://api.bilibili.com/x/web-interface/nav"
 	req, err := http.NewRequest(http.MethodGet, keyUrl, nil)
 	if err != nil {
-		return err
+		return nil, false, err
 	}
-	req.Header.Set("Cookie", c.Cookie)
+	req.Header.Set("Cookie", c.Cookie())
 	resp, err := c.DoReq(req)
 	if err != nil {
-		return err
+		return nil, false, err
 	}
 
 	var nav navInfo
 	err = utils.SafeDecodeOkResp(resp, &nav)
 	if err != nil {
-		return err
+		return nil, false, err
 	}
-	if nav.Code != 0 {
-		return fmt.Errorf("get nav fail: %v %s", nav.Code, nav.Message)
+	if nav.Code != 0 && nav.Code != navNotLoggedIn {
+		return nil, false, fmt.Errorf("get nav fail: %v %s", nav.Code, nav.Message)
 	}
 	matchImg := tokenRegex.FindStringSubmatch(nav.Data.WbiImg.ImgUrl)
 	matchSub := tokenRegex.FindStringSubmatch(nav.Data.WbiImg.SubUrl)
 	if len(matchImg) <= 1 || len(matchSub) <= 1 {
-		return fmt.Errorf("wrong img url token %s", nav.Data.WbiImg.ImgUrl)
+		return nil, false, fmt.Errorf("wrong img url token %s", nav.Data.WbiImg.ImgUrl)
+	}
+	token = &tokenKey{
+		imgKey:         matchImg[1],
+		subKey:         matchSub[1],
+		lastUpdateTime: time.Now(),
 	}
-	c.token.imgKey = matchImg[1]
-	c.token.subKey = matchSub[1]
+	c.token.Store(token)
+	return token, nav.Code == 0, nil
+}
 
+// Probe 获取wbi签名token 未配置cookie时匿名可用 配置了cookie但未登录则说明cookie失效
+func (c *client) Probe() error {
+	_, loggedIn, err := c.fetchToken()
+	if err != nil {
+		return err
+	}
+	if !loggedIn && c.Cookie() != "" {
+		return fmt.Errorf("cookie is invalid or expired")
+	}
 	return nil
 }
 
@@ -57,26 +76,26 @@ type navInfo struct {
 	} `json:"data"`
 }
 
+// tokenKey 获取后不再修改 通过 atomic.Pointer 整体替换
 type tokenKey struct {
 	subKey, imgKey string
 	lastUpdateTime time.Time
 }
 
 func (c *client) sign(values url.Values) (url.Values, error) {
-	tokenExpire := time.Since(c.token.lastUpdateTime).Hours() > 24
-	if c.token.imgKey == "" || c.token.subKey == "" || tokenExpire {
-		err := c.setToken()
-		if err != nil {
+	token := c.token.Load()
+	if token == nil || time.Since(token.lastUpdateTime).Hours() > 24 {
+		var err error
+		if token, _, err = c.fetchToken(); err != nil {
 			return nil, err
 		}
-		c.token.lastUpdateTime = time.Now()
 	}
 
 	values = removeUnwantedChars(values, '!', '\'', '(', ')', '*')
 	values.Set("wts", strconv.FormatInt(time.Now().Unix(), 10))
 
 	var mixin [32]byte
-	wbi := c.token.imgKey + c.token.subKey
+	wbi := token.imgKey + token.subKey
 	for i := range mixin {
 		mixin[i] = wbi[mixinKeyEncTab[i]]
 	}
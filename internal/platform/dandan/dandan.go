@@ -0,0 +1,184 @@
+package dandan
+
+import (
+	"crypto/sha256"
+	"danmaku-tool/internal/config"
+	"danmaku-tool/internal/danmaku"
+	"danmaku-tool/internal/utils"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+/*
+	上游 dandan api 数据源 可以是官方 https://api.dandanplay.net 也可以是其他兼容dandan api的弹幕服务
+	比如另一个 danmaku-tool 服务：http://host:8089/api/v1/{token}
+
+	官方api需要配置 app-id 和 app-secret 进行签名：
+	X-Signature = base64(sha256(appId + timestamp + path + appSecret))
+*/
+
+type client struct {
+	danmaku.PlatformClient
+	// 上游服务配置 配置热加载时整体替换
+	upstream atomic.Pointer[upstream]
+}
+
+type upstream struct {
+	baseUrl          string
+	appId, appSecret string
+}
+
+func init() {
+	danmaku.RegisterInitializer(&client{})
+}
+
+func (c *client) Init() error {
+	conf := config.GetPlatformConfig(danmaku.Dandan)
+	if conf == nil || conf.Url == "" {
+		return fmt.Errorf("[%s] url is not configured", danmaku.Dandan)
+	}
+	if err := danmaku.InitPlatformClient(&c.PlatformClient, danmaku.Dandan); err != nil {
+		return err
+	}
+	c.upstream.Store(&upstream{
+		baseUrl:   strings.TrimSuffix(conf.Url, "/"),
+		appId:     conf.AppId,
+		appSecret: conf.AppSecret,
+	})
+	danmaku.RegisterScraper(c)
+	return nil
+}
+
+func (c *client) Platform() danmaku.Platform {
+	return danmaku.Dandan
+}
+
+func (c *client) sign(req *http.Request) {
+	u := c.upstream.Load()
+	if u.appId == "" || u.appSecret == "" {
+		return
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	hash := sha256.Sum256([]byte(u.appId + timestamp + req.URL.Path + u.appSecret))
+	req.Header.Set("X-AppId", u.appId)
+	req.Header.Set("X-Timestamp", timestamp)
+	req.Header.Set("X-Signature", base64.StdEncoding.EncodeToString(hash[:]))
+}
+
+func doGet[T any](c *client, path string, params url.Values) (*T, error) {
+	api := c.upstream.Load().baseUrl + path
+	if len(params) > 0 {
+		api += "?" + params.Encode()
+	}
+	req, err := http.NewRequest(http.MethodGet, api, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	c.sign(req)
+	resp, err := c.DoReq(req)
+	if err != nil {
+		return nil, err
+	}
+
+	var result T
+	err = utils.SafeDecodeOkResp(resp, &result)
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+func (c *client) searchAnime(keyword string) (*SearchAnimeResult, error) {
+	result, err := doGet[SearchAnimeResult](c, "/api/v2/search/anime", url.Values{"keyword": {keyword}})
+	if err != nil {
+		return nil, err
+	}
+	if !result.Success {
+		return nil, fmt.Errorf("search anime error: %d %s", result.ErrorCode, result.err())
+	}
+	return result, nil
+}
+
+func (c *client) bangumi(animeId string) (*BangumiResult, error) {
+	result, err := doGet[BangumiResult](c, "/api/v2/bangumi/"+url.PathEscape(animeId), nil)
+	if err != nil {
+		return nil, err
+	}
+	if !result.Success || result.Bangumi == nil {
+		return nil, fmt.Errorf("bangumi error: %d %s", result.ErrorCode, result.err())
+	}
+	return result, nil
+}
+
+func (c *client) comment(episodeId string, withRelated bool) (*CommentResult, error) {
+	params := url.Values{
+		"withRelated": {strconv.FormatBool(withRelated)},
+		"chConvert":   {"0"},
+	}
+	return doGet[CommentResult](c, "/api/v2/comment/"+url.PathEscape(episodeId), params)
+}
+
+func (c *client) Media(id string) (*danmaku.Media, error) {
+	result, err := c.bangumi(id)
+	if err != nil {
+		return nil, err
+	}
+	bangumi := result.Bangumi
+
+	var eps = make([]*danmaku.MediaEpisode, 0, len(bangumi.Episodes))
+	for _, ep := range bangumi.Episodes {
+		if danmaku.InvalidEpTitle(ep.EpisodeTitle) {
+			continue
+		}
+		eps = append(eps, &danmaku.MediaEpisode{
+			Id:        strconv.FormatInt(ep.EpisodeId, 10),
+			EpisodeId: ep.EpisodeNumber,
+			Title:     ep.EpisodeTitle,
+		})
+	}
+
+	media := &danmaku.Media{
+		Id:       strconv.FormatInt(bangumi.AnimeId, 10),
+		Title:    platformSuffixRegex.ReplaceAllString(bangumi.Title, ""),
+		Desc:     bangumi.Summary,
+		Cover:    bangumi.ImageUrl,
+		Type:     parseMediaType(bangumi.Type),
+		TypeDesc: bangumi.TypeDesc,
+		Episodes: eps,
+		Platform: danmaku.Dandan,
+	}
+	return media, nil
+}
+
+func parseComment(p, m string) (*danmaku.StandardDanmaku, error) {
+	attrs := strings.Split(p, ",")
+	if len(attrs) < 3 {
+		return nil, fmt.Errorf("invalid p attribute: %s", p)
+	}
+	offset, err := strconv.ParseFloat(attrs[0], 64)
+	if err != nil {
+		return nil, err
+	}
+	mode, err := strconv.ParseInt(attrs[1], 10, 64)
+	if err != nil {
+		return nil, err
+	}
+	color, err := strconv.ParseInt(attrs[2], 10, 64)
+	if err != nil {
+		return nil, err
+	}
+	return &danmaku.StandardDanmaku{
+		OffsetMills: int64(offset * 1000),
+		Mode:        int(mode),
+		Color:       int(color),
+		Content:     m,
+		Platform:    danmaku.Dandan,
+	}, nil
+}
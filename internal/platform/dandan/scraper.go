@@ -0,0 +1,126 @@
+package dandan
+
+import (
+	"danmaku-tool/internal/config"
+	"danmaku-tool/internal/danmaku"
+	"danmaku-tool/internal/utils"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"sync"
+)
+
+func (c *client) Match(param danmaku.MatchParam) ([]*danmaku.Media, error) {
+	searchResult, err := c.searchAnime(param.Title)
+	if err != nil {
+		return nil, err
+	}
+
+	// 搜索结果不包含剧集信息 4并发获取 按照搜索结果顺序返回
+	var medias = make([]*danmaku.Media, len(searchResult.Animes))
+	sem := make(chan struct{}, 4)
+	var wg sync.WaitGroup
+	for i, anime := range searchResult.Animes {
+		title := platformSuffixRegex.ReplaceAllString(anime.AnimeTitle, "")
+		var year int
+		if len(anime.StartDate) >= 4 {
+			year, _ = param.MatchYearString(anime.StartDate[:4])
+		}
+		if !param.MatchYear(year) {
+			continue
+		}
+
+		match := param.MatchTitle(title)
+		utils.DebugLog(danmaku.Dandan, fmt.Sprintf("[%s] match [%s]: %v", title, param.Title, match))
+		if !match {
+			continue
+		}
+
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(index int, animeId int64, title string, year int, cover, typeDesc string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			media, e := c.Media(strconv.FormatInt(animeId, 10))
+			if e != nil {
+				utils.ErrorLog(danmaku.Dandan, e.Error(), "animeId", animeId)
+				return
+			}
+			media.Title = title
+			media.Year = year
+			media.Cover = cover
+			if typeDesc != "" {
+				media.TypeDesc = typeDesc
+			}
+			medias[index] = media
+		}(i, anime.AnimeId, title, year, anime.ImageUrl, anime.TypeDesc)
+	}
+	wg.Wait()
+
+	var result = make([]*danmaku.Media, 0, len(medias))
+	for _, m := range medias {
+		if m != nil {
+			result = append(result, m)
+		}
+	}
+	return result, nil
+}
+
+// GetDanmaku 保存文件以及订阅等场景需要完整弹幕 附带关联弹幕
+func (c *client) GetDanmaku(id string) ([]*danmaku.StandardDanmaku, error) {
+	return c.GetDanmakuWithRelated(id, true)
+}
+
+// GetDanmakuWithRelated withRelated 是否附带上游关联的第三方弹幕
+func (c *client) GetDanmakuWithRelated(id string, withRelated bool) ([]*danmaku.StandardDanmaku, error) {
+	commentResult, err := c.comment(id, withRelated)
+	if err != nil {
+		return nil, err
+	}
+
+	var result = make([]*danmaku.StandardDanmaku, 0, len(commentResult.Comments))
+	for _, comment := range commentResult.Comments {
+		d, e := parseComment(comment.P, comment.M)
+		if e != nil {
+			utils.DebugLog(danmaku.Dandan, e.Error(), "episodeId", id)
+			continue
+		}
+		result = append(result, d)
+	}
+
+	utils.InfoLog(danmaku.Dandan, "get danmaku done", "size", len(result))
+	return result, nil
+}
+
+// Scrape id为 animeId 抓取所有剧集弹幕
+func (c *client) Scrape(id string) error {
+	media, err := c.Media(id)
+	if err != nil {
+		return err
+	}
+
+	utils.InfoLog(danmaku.Dandan, "scrape start", "id", id)
+	savePath := filepath.Join(config.GetConfig().SavePath, danmaku.Dandan, media.Id)
+	for _, ep := range media.Episodes {
+		if danmaku.UpToDate(savePath, ep.Id) {
+			continue
+		}
+		data, e := c.GetDanmaku(ep.Id)
+		if e != nil {
+			utils.ErrorLog(danmaku.Dandan, fmt.Sprintf("%s scrape error: %s", ep.Id, e.Error()))
+			continue
+		}
+		serializer := &danmaku.SerializerData{
+			EpisodeId: ep.Id,
+			SeasonId:  media.Id,
+			Data:      data,
+		}
+		serializer.SetMedia(media, ep)
+		danmaku.WriteFile(danmaku.Dandan, serializer, savePath, ep.Id)
+		utils.InfoLog(danmaku.Dandan, "ep scraped done", "episodeId", ep.Id, "size", len(data))
+	}
+
+	utils.InfoLog(danmaku.Dandan, "danmaku scraped done", "title", media.Title)
+	return nil
+}
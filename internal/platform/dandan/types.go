@@ -0,0 +1,70 @@
+package dandan
+
+import (
+	"danmaku-tool/internal/danmaku"
+	"regexp"
+)
+
+// 兼容 danmaku-tool 自身返回的标题 进击的巨人 [bilibili]
+var platformSuffixRegex = regexp.MustCompile(`\s*\[\w+]$`)
+
+type ResultInfo struct {
+	Success      bool   `json:"success"`
+	ErrorCode    int    `json:"errorCode"`
+	ErrorMessage string `json:"errorMessage"`
+}
+
+func (r ResultInfo) err() string {
+	if r.ErrorMessage != "" {
+		return r.ErrorMessage
+	}
+	return "unknown error"
+}
+
+type SearchAnimeResult struct {
+	ResultInfo
+	Animes []struct {
+		AnimeId      int64  `json:"animeId"`
+		BangumiId    string `json:"bangumiId"`
+		AnimeTitle   string `json:"animeTitle"`
+		Type         string `json:"type"` // tvseries movie ova ...
+		TypeDesc     string `json:"typeDescription"`
+		ImageUrl     string `json:"imageUrl"`
+		StartDate    string `json:"startDate"` // 2013-04-07T00:00:00
+		EpisodeCount int    `json:"episodeCount"`
+	} `json:"animes"`
+}
+
+type BangumiResult struct {
+	ResultInfo
+	Bangumi *struct {
+		AnimeId  int64  `json:"animeId"`
+		Title    string `json:"animeTitle"`
+		Type     string `json:"type"`
+		TypeDesc string `json:"typeDescription"`
+		ImageUrl string `json:"imageUrl"`
+		Summary  string `json:"summary"`
+		Episodes []struct {
+			EpisodeId     int64  `json:"episodeId"`
+			EpisodeTitle  string `json:"episodeTitle"`
+			EpisodeNumber string `json:"episodeNumber"` // 1 或者 S1 C1 等特殊集
+		} `json:"episodes"`
+	} `json:"bangumi"`
+}
+
+type CommentResult struct {
+	Count    int64 `json:"count"`
+	Comments []struct {
+		CID int64  `json:"cid"`
+		P   string `json:"p"` // 出现时间(秒),模式,颜色,用户ID
+		M   string `json:"m"`
+	} `json:"comments"`
+}
+
+func parseMediaType(t string) danmaku.MediaType {
+	switch t {
+	case "movie":
+		return danmaku.Movie
+	}
+	return danmaku.Series
+}
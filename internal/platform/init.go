@@ -4,3 +4,7 @@ import _ "danmaku-tool/internal/platform/tencent"
 import _ "danmaku-tool/internal/platform/bilibili"
 import _ "danmaku-tool/internal/platform/youku"
 import _ "danmaku-tool/internal/platform/iqiyi"
+import _ "danmaku-tool/internal/platform/mgtv"
+import _ "danmaku-tool/internal/platform/acfun"
+import _ "danmaku-tool/internal/platform/dandan"
+import _ "danmaku-tool/internal/platform/plugin"
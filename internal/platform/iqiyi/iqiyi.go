@@ -1,6 +1,7 @@
 package iqiyi
 
 import (
+	"context"
 	"danmaku-tool/internal/danmaku"
 	"danmaku-tool/internal/utils"
 	"encoding/base64"
@@ -36,6 +37,18 @@ func (c *client) Platform() danmaku.Platform {
 	return danmaku.Iqiyi
 }
 
+// ResolveURL 支持 iqiyi.com 单集播放链接 专辑页没有单集信息
+func (c *client) ResolveURL(u *url.URL) (string, error) {
+	if !danmaku.MatchHost(u, "iqiyi.com") {
+		return "", nil
+	}
+	// https://www.iqiyi.com/v_19rrk2gwkw.html
+	if m := playUrlRegex.FindStringSubmatch(u.Path); len(m) > 1 {
+		return m[1], nil
+	}
+	return "", fmt.Errorf("only support v_ play url: %s", u)
+}
+
 /*
 	爱奇艺是 使用 albumId 和 tvId，使用转换方法都能转成数字id
 	https://www.iqiyi.com/v_19rrk2gwkw.html v_ 后面字符串就是 tvId
@@ -68,20 +81,26 @@ func (c *client) videoBaseInfo(tvId int64) (*VideoBaseInfoResult, error) {
 	return &baseInfo, nil
 }
 
-func (c *client) scrapeDanmaku(baseInfo *VideoBaseInfoResult, tvId int64) []*danmaku.StandardDanmaku {
+func (c *client) scrapeDanmaku(ctx context.Context, baseInfo *VideoBaseInfoResult, tvId int64) []*danmaku.StandardDanmaku {
 
 	duration := baseInfo.Data.DurationSec
 	segmentsLen := duration/segmentInterval + 1
 
-	tasks := make(chan task, c.MaxWorker)
-	ch := make(chan []*danmaku.StandardDanmaku, c.MaxWorker)
+	tasks := make(chan task, c.MaxWorker())
+	ch := make(chan []*danmaku.StandardDanmaku, c.MaxWorker())
 	var wg sync.WaitGroup
-	for w := 0; w < c.MaxWorker; w++ {
+	for w := 0; w < c.MaxWorker(); w++ {
 		wg.Add(1)
 		go func(i int) {
 			defer wg.Done()
 			for t := range tasks {
+				// 取消后跳过剩余的分片
+				if ctx.Err() != nil {
+					continue
+				}
+				start := time.Now()
 				data, err := c.scrape(t.tvId, t.segment)
+				danmaku.ObserveSegment(ctx, danmaku.Iqiyi, start, err)
 				if err != nil {
 					utils.ErrorLog(danmaku.Iqiyi, fmt.Sprintf("%d scrape segment %d error: %s", tvId, t.segment, err.Error()))
 					continue
@@ -158,11 +177,13 @@ func (c *client) scrape(tvId int64, segment int) ([]*danmaku.StandardDanmaku, er
 			if err == nil {
 				colorValue = int(value)
 			}
+			likes, _ := strconv.ParseInt(info.LikeCount, 10, 64)
 			result = append(result, &danmaku.StandardDanmaku{
 				Content:     info.Content,
 				Color:       colorValue,
 				OffsetMills: int64(offsetInSeconds * 1000),
 				Mode:        danmaku.NormalMode,
+				Likes:       likes,
 			})
 		}
 	}
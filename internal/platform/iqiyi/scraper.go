@@ -1,6 +1,7 @@
 package iqiyi
 
 import (
+	"context"
 	"danmaku-tool/internal/config"
 	"danmaku-tool/internal/danmaku"
 	"danmaku-tool/internal/utils"
@@ -170,7 +171,7 @@ func (c *client) GetDanmaku(id string) ([]*danmaku.StandardDanmaku, error) {
 	if err != nil {
 		return nil, err
 	}
-	result := c.scrapeDanmaku(baseInfo, tvId)
+	result := c.scrapeDanmaku(context.Background(), baseInfo, tvId)
 	return result, nil
 }
 
@@ -184,17 +185,53 @@ func (c *client) Scrape(idStr string) error {
 	if err != nil {
 		return err
 	}
-	result := c.scrapeDanmaku(baseInfo, tvId)
+	path := filepath.Join(config.GetConfig().SavePath, danmaku.Iqiyi, strconv.FormatInt(baseInfo.Data.AlbumId, 10))
+	if danmaku.UpToDate(path, strconv.FormatInt(baseInfo.Data.TVId, 10)) {
+		return nil
+	}
+	c.scrapeEpisode(context.Background(), baseInfo, tvId)
+	return nil
+}
+
+// ScrapeEpisode id为 Media 返回的tvId 电影为base64编码的tvId 文件与 Scrape 一致
+func (c *client) ScrapeEpisode(ctx context.Context, id string) ([]string, int, error) {
+	tvId, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		tvIdBytes, e := base64.StdEncoding.DecodeString(id)
+		if e != nil {
+			return nil, 0, fmt.Errorf("invalid id: %s", id)
+		}
+		if tvId, err = strconv.ParseInt(string(tvIdBytes), 10, 64); err != nil {
+			return nil, 0, fmt.Errorf("invalid id: %s", id)
+		}
+	}
+	baseInfo, err := c.videoBaseInfo(tvId)
+	if err != nil {
+		return nil, 0, err
+	}
+	return c.scrapeEpisode(ctx, baseInfo, tvId)
+}
+
+// scrapeEpisode 抓取并保存单集 savePath/{platform}/{albumId}/{tvId}.xml
+func (c *client) scrapeEpisode(ctx context.Context, baseInfo *VideoBaseInfoResult, tvId int64) ([]string, int, error) {
+	result := c.scrapeDanmaku(ctx, baseInfo, tvId)
+	if err := ctx.Err(); err != nil {
+		return nil, 0, err
+	}
 
 	serializer := &danmaku.SerializerData{
 		EpisodeId:       strconv.FormatInt(baseInfo.Data.TVId, 10),
 		SeasonId:        strconv.FormatInt(baseInfo.Data.AlbumId, 10),
 		Data:            result,
 		DurationInMills: int64(baseInfo.Data.DurationSec * 1000),
+		Title:           baseInfo.Data.AlbumName,
+		EpisodeNumber:   baseInfo.Data.Order,
+		EpisodeTitle:    baseInfo.Data.Name,
+	}
+	if serializer.Title == "" {
+		serializer.Title = baseInfo.Data.Name
 	}
 
-	path := filepath.Join(config.GetConfig().SavePath, danmaku.Iqiyi, strconv.FormatInt(baseInfo.Data.AlbumId, 10))
-	danmaku.WriteFile(danmaku.Iqiyi, serializer, path, strconv.FormatInt(baseInfo.Data.TVId, 10))
-
-	return nil
+	path := filepath.Join(config.GetConfig().SavePath, danmaku.Iqiyi, serializer.SeasonId)
+	return danmaku.WriteFile(danmaku.Iqiyi, serializer, path, serializer.EpisodeId), len(result), nil
 }
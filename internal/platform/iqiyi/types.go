@@ -4,6 +4,7 @@ import "regexp"
 
 var tvIdRegex = regexp.MustCompile(`^qips://.*tvid=(\d+);`)
 var albumRegex = regexp.MustCompile(`albumid=(\d+);`)
+var playUrlRegex = regexp.MustCompile(`^/v_([0-9a-z]+)\.html`)
 
 type VideoBaseInfoResult struct {
 	Code string `json:"code"` // A00000 成功
@@ -0,0 +1,428 @@
+package mgtv
+
+import (
+	"context"
+	"danmaku-tool/internal/danmaku"
+	"danmaku-tool/internal/utils"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+type client struct {
+	danmaku.PlatformClient
+}
+
+func init() {
+	danmaku.RegisterInitializer(&client{})
+}
+
+func (c *client) Init() error {
+	if err := danmaku.InitPlatformClient(&c.PlatformClient, danmaku.Mgtv); err != nil {
+		return err
+	}
+	danmaku.RegisterScraper(c)
+	return nil
+}
+
+func (c *client) Platform() danmaku.Platform {
+	return danmaku.Mgtv
+}
+
+// ResolveURL 支持 mgtv.com 单集播放链接
+func (c *client) ResolveURL(u *url.URL) (string, error) {
+	if !danmaku.MatchHost(u, "mgtv.com") {
+		return "", nil
+	}
+	// https://www.mgtv.com/b/584515/19961598.html
+	if m := playUrlRegex.FindStringSubmatch(u.Path); len(m) > 2 {
+		return m[1] + idSeparator + m[2], nil
+	}
+	return "", fmt.Errorf("only support /b/ play url: %s", u)
+}
+
+/*
+	芒果TV 视频链接格式
+	https://www.mgtv.com/b/{collection_id}/{video_id}.html
+	collection_id 是剧集id video_id 则是单集视频id
+
+	弹幕接口需要同时提供 collection_id 和 video_id，所以单集id使用 {collection_id}/{video_id} 格式保存
+*/
+
+const idSeparator = "/"
+
+func combineId(cid, vid string) string {
+	return cid + idSeparator + vid
+}
+
+func splitId(id string) (cid, vid string) {
+	parts := strings.SplitN(id, idSeparator, 2)
+	if len(parts) == 2 {
+		return parts[0], parts[1]
+	}
+	return parts[0], ""
+}
+
+var chinaZone = time.FixedZone("CST", 8*3600)
+
+// AirTime id格式同 Scrape 只有剧集id时返回最新一集的发布时间
+func (c *client) AirTime(id string) (time.Time, error) {
+	cid, vid := splitId(id)
+	if cid == "" {
+		return time.Time{}, fmt.Errorf("invalid id: %s", id)
+	}
+	items, _, err := c.episodes(cid)
+	if err != nil {
+		return time.Time{}, err
+	}
+	var latest time.Time
+	for _, ep := range items {
+		if vid != "" && ep.VideoId != vid {
+			continue
+		}
+		// 2023-05-05 12:00:00.0 北京时间
+		aired, e := time.ParseInLocation("2006-01-02 15:04:05", strings.TrimSuffix(ep.TS, ".0"), chinaZone)
+		if e == nil && aired.After(latest) {
+			latest = aired
+		}
+	}
+	if latest.IsZero() {
+		return time.Time{}, fmt.Errorf("%s has no publish time", id)
+	}
+	return latest, nil
+}
+
+func (c *client) setReq(req *http.Request) {
+	req.Header.Set("Cookie", c.Cookie())
+	req.Header.Set("Origin", "https://www.mgtv.com")
+	req.Header.Set("Referer", "https://www.mgtv.com/")
+}
+
+func (c *client) search(keyword string) (*SearchResult, error) {
+	params := url.Values{
+		"q":        {keyword},
+		"pc":       {"30"},
+		"pn":       {"1"},
+		"sort":     {"-99"},
+		"ty":       {"0"},
+		"du":       {"0"},
+		"pt":       {"0"},
+		"corr":     {"1"},
+		"abroad":   {"0"},
+		"_support": {"10000000000000000"},
+	}
+	api := "https://mobileso.bz.mgtv.com/msite/search/v2?" + params.Encode()
+	req, err := http.NewRequest(http.MethodGet, api, nil)
+	if err != nil {
+		return nil, err
+	}
+	c.setReq(req)
+	resp, err := c.DoReq(req)
+	if err != nil {
+		return nil, err
+	}
+
+	var result SearchResult
+	err = utils.SafeDecodeOkResp(resp, &result)
+	if err != nil {
+		return nil, err
+	}
+	if result.Code != 200 {
+		return nil, fmt.Errorf("search error: %d %s", result.Code, result.Msg)
+	}
+	return &result, nil
+}
+
+// episodes 获取剧集下所有正片信息 接口分页返回
+func (c *client) episodes(cid string) ([]EpisodeItem, *EpisodeListResult, error) {
+	var eps = make([]EpisodeItem, 0, 100)
+	var first *EpisodeListResult
+	for page, totalPage := 1, 1; page <= totalPage; page++ {
+		params := url.Values{
+			"_support":      {"10000000"},
+			"version":       {"5.5.35"},
+			"collection_id": {cid},
+			"page":          {strconv.FormatInt(int64(page), 10)},
+			"size":          {"50"},
+		}
+		api := "https://pcweb.api.mgtv.com/episode/list?" + params.Encode()
+		req, err := http.NewRequest(http.MethodGet, api, nil)
+		if err != nil {
+			return nil, nil, err
+		}
+		c.setReq(req)
+		resp, err := c.DoReq(req)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		var result EpisodeListResult
+		err = utils.SafeDecodeOkResp(resp, &result)
+		if err != nil {
+			return nil, nil, err
+		}
+		if result.Code != 200 {
+			return nil, nil, fmt.Errorf("episode list error: %d %s", result.Code, result.Msg)
+		}
+		if first == nil {
+			first = &result
+		}
+		totalPage = result.Data.TotalPage
+		for _, ep := range result.Data.List {
+			if !ep.valid() {
+				continue
+			}
+			eps = append(eps, ep)
+		}
+	}
+	return eps, first, nil
+}
+
+func (c *client) videoInfo(cid, vid string) (*VideoInfoResult, error) {
+	params := url.Values{
+		"allowedRC": {"1"},
+		"cid":       {cid},
+		"vid":       {vid},
+		"change":    {"3"},
+		"datatype":  {"1"},
+		"type":      {"1"},
+		"_support":  {"10000000"},
+	}
+	api := "https://pcweb.api.mgtv.com/video/info?" + params.Encode()
+	req, err := http.NewRequest(http.MethodGet, api, nil)
+	if err != nil {
+		return nil, err
+	}
+	c.setReq(req)
+	resp, err := c.DoReq(req)
+	if err != nil {
+		return nil, err
+	}
+
+	var result VideoInfoResult
+	err = utils.SafeDecodeOkResp(resp, &result)
+	if err != nil {
+		return nil, err
+	}
+	if result.Code != 200 {
+		return nil, fmt.Errorf("video info error: %d %s", result.Code, result.Msg)
+	}
+	return &result, nil
+}
+
+// barrageControl 获取弹幕cdn信息 弹幕按分钟分片存放在cdn上
+func (c *client) barrageControl(cid, vid string) (*BarrageControlResult, error) {
+	params := url.Values{
+		"version":  {"8.1.39"},
+		"abroad":   {"0"},
+		"uuid":     {""},
+		"os":       {"10.15.7"},
+		"platform": {"0"},
+		"mac":      {""},
+		"vid":      {vid},
+		"pid":      {""},
+		"cid":      {cid},
+		"ticket":   {""},
+	}
+	api := "https://galaxy.bz.mgtv.com/getctlbarrage?" + params.Encode()
+	req, err := http.NewRequest(http.MethodGet, api, nil)
+	if err != nil {
+		return nil, err
+	}
+	c.setReq(req)
+	resp, err := c.DoReq(req)
+	if err != nil {
+		return nil, err
+	}
+
+	var result BarrageControlResult
+	err = utils.SafeDecodeOkResp(resp, &result)
+	if err != nil {
+		return nil, err
+	}
+	if result.Status != 0 {
+		return nil, fmt.Errorf("barrage control error: %d %s", result.Status, result.Msg)
+	}
+	return &result, nil
+}
+
+type task struct {
+	cid, vid string
+	segment  int64
+}
+
+func (c *client) scrapeDanmaku(ctx context.Context, cid, vid string, durationInSeconds int64) []*danmaku.StandardDanmaku {
+	// 1分钟分片
+	segmentsLen := durationInSeconds/60 + 1
+
+	// cdn 获取失败则使用备用接口
+	var cdn, cdnVersion string
+	if ctl, err := c.barrageControl(cid, vid); err == nil && ctl.Data.CdnList != "" && ctl.Data.CdnVersion != "" {
+		cdn = strings.Split(ctl.Data.CdnList, ",")[0]
+		cdnVersion = ctl.Data.CdnVersion
+	} else if err != nil {
+		utils.WarnLog(danmaku.Mgtv, fmt.Sprintf("barrage control fail, fallback to rdbarrage: %s", err.Error()), "vid", vid)
+	}
+
+	tasks := make(chan task, c.MaxWorker())
+	ch := make(chan []*danmaku.StandardDanmaku, c.MaxWorker())
+	var wg sync.WaitGroup
+	for w := 0; w < c.MaxWorker(); w++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for t := range tasks {
+				// 取消后跳过剩余的分片
+				if ctx.Err() != nil {
+					continue
+				}
+				var api string
+				if cdn != "" {
+					api = fmt.Sprintf("https://%s/%s/%d.json", cdn, cdnVersion, t.segment)
+				} else {
+					params := url.Values{
+						"vid":  {t.vid},
+						"cid":  {t.cid},
+						"time": {strconv.FormatInt(t.segment*60*1000, 10)},
+					}
+					api = "https://galaxy.bz.mgtv.com/rdbarrage?" + params.Encode()
+				}
+				start := time.Now()
+				data, e := c.scrape(api)
+				danmaku.ObserveSegment(ctx, danmaku.Mgtv, start, e)
+				if e != nil {
+					utils.ErrorLog(danmaku.Mgtv, fmt.Sprintf("%s scrape segment %d error: %s", t.vid, t.segment, e.Error()))
+					continue
+				}
+				if len(data) <= 0 {
+					continue
+				}
+				ch <- data
+			}
+		}(w)
+	}
+
+	go func() {
+		for i := int64(0); i < segmentsLen; i++ {
+			tasks <- task{
+				cid:     cid,
+				vid:     vid,
+				segment: i,
+			}
+		}
+		close(tasks)
+	}()
+
+	go func() {
+		wg.Wait()
+		close(ch)
+	}()
+	var result = make([]*danmaku.StandardDanmaku, 0, 50000)
+	for d := range ch {
+		result = append(result, d...)
+	}
+
+	return result
+}
+
+func (c *client) scrape(api string) ([]*danmaku.StandardDanmaku, error) {
+	req, err := http.NewRequest(http.MethodGet, api, nil)
+	if err != nil {
+		return nil, err
+	}
+	c.setReq(req)
+	resp, err := c.DoReq(req)
+	if err != nil {
+		return nil, err
+	}
+
+	var danmakuResult DanmakuResult
+	err = utils.SafeDecodeOkResp(resp, &danmakuResult)
+	if err != nil {
+		return nil, err
+	}
+	if danmakuResult.Status != 0 {
+		return nil, fmt.Errorf("scrape danmaku error: %d %s", danmakuResult.Status, danmakuResult.Msg)
+	}
+
+	var result = make([]*danmaku.StandardDanmaku, 0, len(danmakuResult.Data.Items))
+	for _, d := range danmakuResult.Data.Items {
+		mode := danmaku.NormalMode
+		switch d.Position {
+		case 1:
+			mode = danmaku.TopMode
+		case 2:
+			mode = danmaku.BottomMode
+		}
+		color := danmaku.WhiteColor
+		rgb := d.Color.ColorLeft
+		if rgb.R > 0 || rgb.G > 0 || rgb.B > 0 {
+			color = rgb.R<<16 | rgb.G<<8 | rgb.B
+		}
+		result = append(result, &danmaku.StandardDanmaku{
+			Content:     d.Content,
+			OffsetMills: d.Time,
+			Mode:        mode,
+			Color:       color,
+			Platform:    danmaku.Mgtv,
+			Likes:       d.UpCount,
+		})
+	}
+
+	return result, nil
+}
+
+func (c *client) Media(id string) (*danmaku.Media, error) {
+	cid, _ := splitId(id)
+	items, info, err := c.episodes(cid)
+	if err != nil {
+		return nil, err
+	}
+	if len(items) < 1 {
+		return nil, fmt.Errorf("%s no episodes", cid)
+	}
+
+	media := &danmaku.Media{
+		Id:       cid,
+		Title:    info.Data.Info.Title,
+		Desc:     info.Data.Info.Desc,
+		TypeDesc: info.Data.Info.Type,
+		Cover:    items[0].Image,
+		Type:     danmaku.Series,
+		Platform: danmaku.Mgtv,
+		Episodes: c.parseEpisodes(items),
+	}
+	if len(media.Episodes) == 1 && media.TypeDesc == "电影" {
+		media.Type = danmaku.Movie
+	}
+
+	return media, nil
+}
+
+func (c *client) parseEpisodes(items []EpisodeItem) []*danmaku.MediaEpisode {
+	var eps = make([]*danmaku.MediaEpisode, 0, len(items))
+	for i, ep := range items {
+		if danmaku.InvalidEpTitle(ep.T2) || danmaku.InvalidEpTitle(ep.T3) {
+			continue
+		}
+		// 综艺 t1 是日期 使用下标作为集数
+		epId := ep.T1
+		if _, e := strconv.ParseInt(epId, 10, 64); e != nil {
+			epId = strconv.FormatInt(int64(i+1), 10)
+		}
+		title := ep.T3
+		if title == "" {
+			title = ep.T2
+		}
+		eps = append(eps, &danmaku.MediaEpisode{
+			Id:        combineId(ep.ClipId, ep.VideoId),
+			EpisodeId: epId,
+			Title:     title,
+		})
+	}
+	return eps
+}
@@ -0,0 +1,199 @@
+package mgtv
+
+import (
+	"context"
+	"danmaku-tool/internal/config"
+	"danmaku-tool/internal/danmaku"
+	"danmaku-tool/internal/utils"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"sync"
+)
+
+func (c *client) Match(param danmaku.MatchParam) ([]*danmaku.Media, error) {
+	keyword := param.Title
+	searchResult, err := c.search(keyword)
+	if err != nil {
+		return nil, err
+	}
+
+	// 并发获取剧集列表 4并发
+	sem := make(chan struct{}, 4)
+	ch := make(chan *danmaku.Media, 4)
+	wg := sync.WaitGroup{}
+	// 先开始接收结果 否则结果超过缓冲时持有sem的goroutine会阻塞 导致无法继续启动
+	var result = make([]*danmaku.Media, 0, 10)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for m := range ch {
+			result = append(result, m)
+		}
+	}()
+	for _, content := range searchResult.Data.Contents {
+		if content.Type != "media" {
+			continue
+		}
+		for _, item := range content.Data {
+			// 过滤站外视频
+			if item.Source != "imgo" {
+				continue
+			}
+			typeName, year := parseDesc(item.Desc)
+			if !param.MatchYear(year) {
+				continue
+			}
+			title := utils.StripHTMLTags(item.Title)
+			match := param.MatchTitle(title)
+			utils.DebugLog(danmaku.Mgtv, fmt.Sprintf("[%s] match [%s]: %v", title, param.Title, match))
+			if !match {
+				continue
+			}
+			urlMatches := playUrlRegex.FindStringSubmatch(item.Url)
+			if len(urlMatches) < 3 {
+				continue
+			}
+
+			wg.Add(1)
+			sem <- struct{}{}
+			go func(cid, title, typeName, cover, desc string, year int) {
+				defer wg.Done()
+				defer func() { <-sem }()
+
+				items, _, e := c.episodes(cid)
+				if e != nil {
+					utils.ErrorLog(danmaku.Mgtv, e.Error(), "cid", cid)
+					return
+				}
+				eps := c.parseEpisodes(items)
+				var mediaType danmaku.MediaType = danmaku.Series
+				if typeName == "电影" {
+					mediaType = danmaku.Movie
+				}
+				// 匹配剧场版 使用下标作为S00的epId
+				if param.SeasonId == 0 {
+					for i, ep := range eps {
+						ep.EpisodeId = strconv.FormatInt(int64(i+1), 10)
+					}
+				}
+				ch <- &danmaku.Media{
+					Id:       cid,
+					Type:     mediaType,
+					TypeDesc: typeName,
+					Title:    title,
+					Desc:     desc,
+					Cover:    cover,
+					Year:     year,
+					Episodes: eps,
+					Platform: danmaku.Mgtv,
+				}
+			}(urlMatches[1], title, typeName, item.Img, item.Story, year)
+		}
+	}
+	wg.Wait()
+	close(ch)
+	<-done
+	return result, nil
+}
+
+// GetDanmaku id格式 {collection_id}/{video_id}
+func (c *client) GetDanmaku(id string) ([]*danmaku.StandardDanmaku, error) {
+	cid, vid := splitId(id)
+	if cid == "" || vid == "" {
+		return nil, fmt.Errorf("invalid id: %s", id)
+	}
+	info, err := c.videoInfo(cid, vid)
+	if err != nil {
+		return nil, err
+	}
+	duration := parseDuration(info.Data.Info.Time)
+	if duration <= 0 {
+		return nil, fmt.Errorf("%s invalid duration: %s", id, info.Data.Info.Time)
+	}
+
+	result := c.scrapeDanmaku(context.Background(), cid, vid, duration)
+	utils.InfoLog(danmaku.Mgtv, "get danmaku done", "size", len(result))
+	return result, nil
+}
+
+// Scrape 支持 {collection_id} 抓取整部剧集 或者 {collection_id}/{video_id} 抓取单集
+func (c *client) Scrape(id string) error {
+	cid, vid := splitId(id)
+	if cid == "" {
+		return fmt.Errorf("invalid id: %s", id)
+	}
+	items, info, err := c.episodes(cid)
+	if err != nil {
+		return err
+	}
+
+	utils.InfoLog(danmaku.Mgtv, "scrape start", "id", id)
+	savePath := filepath.Join(config.GetConfig().SavePath, danmaku.Mgtv, cid)
+	for i, ep := range items {
+		if vid != "" && ep.VideoId != vid {
+			continue
+		}
+		if danmaku.UpToDate(savePath, ep.VideoId) {
+			continue
+		}
+		if _, _, e := c.scrapeEpisode(context.Background(), cid, info, i, ep); e != nil {
+			utils.ErrorLog(danmaku.Mgtv, e.Error(), "vid", ep.VideoId)
+		}
+	}
+
+	utils.InfoLog(danmaku.Mgtv, "danmaku scraped done", "cid", cid)
+	return nil
+}
+
+// ScrapeEpisode id格式 {collection_id}/{video_id} 文件与 Scrape 一致
+func (c *client) ScrapeEpisode(ctx context.Context, id string) ([]string, int, error) {
+	cid, vid := splitId(id)
+	if cid == "" || vid == "" {
+		return nil, 0, fmt.Errorf("invalid id: %s", id)
+	}
+	items, info, err := c.episodes(cid)
+	if err != nil {
+		return nil, 0, err
+	}
+	for i, ep := range items {
+		if ep.VideoId == vid {
+			return c.scrapeEpisode(ctx, cid, info, i, ep)
+		}
+	}
+	return nil, 0, fmt.Errorf("%s episode not found", id)
+}
+
+// scrapeEpisode 抓取并保存单集 index为单集在列表中的下标
+func (c *client) scrapeEpisode(ctx context.Context, cid string, info *EpisodeListResult, index int, ep EpisodeItem) ([]string, int, error) {
+	duration := parseDuration(ep.Time)
+	if duration <= 0 {
+		return nil, 0, fmt.Errorf("%s invalid duration: %s", ep.VideoId, ep.Time)
+	}
+	data := c.scrapeDanmaku(ctx, cid, ep.VideoId, duration)
+	if err := ctx.Err(); err != nil {
+		return nil, 0, err
+	}
+	serializer := &danmaku.SerializerData{
+		EpisodeId:       ep.VideoId,
+		SeasonId:        cid,
+		Data:            data,
+		DurationInMills: duration * 1000,
+		Title:           info.Data.Info.Title,
+		EpisodeTitle:    ep.T3,
+	}
+	// 综艺 t1 是日期 使用下标作为集数
+	if n, e := strconv.Atoi(ep.T1); e == nil {
+		serializer.EpisodeNumber = n
+	} else {
+		serializer.EpisodeNumber = index + 1
+	}
+	if serializer.EpisodeTitle == "" {
+		serializer.EpisodeTitle = ep.T2
+	}
+	savePath := filepath.Join(config.GetConfig().SavePath, danmaku.Mgtv, cid)
+	files := danmaku.WriteFile(danmaku.Mgtv, serializer, savePath, ep.VideoId)
+
+	utils.InfoLog(danmaku.Mgtv, "ep scraped done", "vid", ep.VideoId, "size", len(data))
+	return files, len(data), nil
+}
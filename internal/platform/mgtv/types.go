@@ -0,0 +1,149 @@
+package mgtv
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// /b/{collection_id}/{video_id}.html
+var playUrlRegex = regexp.MustCompile(`/b/(\d+)/(\d+)\.html`)
+var yearRegex = regexp.MustCompile(`(\d{4})`)
+
+type SearchResult struct {
+	Code int    `json:"code"`
+	Msg  string `json:"msg"`
+	Data struct {
+		Contents []struct {
+			// media 为影视结果 其他如 ad/short 忽略
+			Type string `json:"type"`
+			Data []struct {
+				Title  string   `json:"title"`  // 带 <B> 标签 <B>乘风</B>2023
+				Url    string   `json:"url"`    // /b/{collection_id}/{video_id}.html
+				Source string   `json:"source"` // imgo 为芒果自有 其他为外站
+				Img    string   `json:"img"`
+				Desc   []string `json:"desc"` // 类型:综艺/ 地区:内地/ 年份:2023
+				Story  string   `json:"story"`
+				// 用于区分站外
+				VideoCount int `json:"videoCount"`
+			} `json:"data"`
+		} `json:"contents"`
+	} `json:"data"`
+}
+
+// parseDesc 解析desc中的 类型 年份 信息
+func parseDesc(desc []string) (typeName string, year int) {
+	for _, d := range desc {
+		d = strings.ReplaceAll(d, " ", "")
+		switch {
+		case strings.HasPrefix(d, "类型:"):
+			typeName = strings.TrimSuffix(strings.TrimPrefix(d, "类型:"), "/")
+		case strings.HasPrefix(d, "年份:"):
+			matches := yearRegex.FindStringSubmatch(d)
+			if len(matches) > 1 {
+				y, _ := strconv.ParseInt(matches[1], 10, 64)
+				year = int(y)
+			}
+		}
+	}
+	return
+}
+
+type EpisodeListResult struct {
+	Code int    `json:"code"`
+	Msg  string `json:"msg"`
+	Data struct {
+		Total       int `json:"total"`
+		TotalPage   int `json:"total_page"`
+		CurrentPage int `json:"current_page"`
+		Info        struct {
+			Title string `json:"title"`
+			Desc  string `json:"desc"`
+			Type  string `json:"type"`
+		} `json:"info"`
+		List []EpisodeItem `json:"list"`
+	} `json:"data"`
+}
+
+type EpisodeItem struct {
+	ClipId      string `json:"clip_id"`  // collection_id
+	VideoId     string `json:"video_id"` // 视频id
+	T1          string `json:"t1"`       // 集数 1 或者 综艺日期 2023-05-05
+	T2          string `json:"t2"`       // 标题 第1集
+	T3          string `json:"t3"`       // 副标题
+	Time        string `json:"time"`     // 时长 45:12
+	IsIntact    string `json:"isIntact"` // 1 正片
+	IsNew       string `json:"isnew"`    // 2 预告
+	Image       string `json:"img"`
+	TS          string `json:"ts"` // 发布时间 2023-05-05 12:00:00.0
+	ContentType string `json:"contentType"`
+}
+
+// 正片才需要，预告花絮一类都过滤掉
+func (e EpisodeItem) valid() bool {
+	return e.IsIntact == "1" && e.IsNew != "2"
+}
+
+type VideoInfoResult struct {
+	Code int    `json:"code"`
+	Msg  string `json:"msg"`
+	Data struct {
+		Info struct {
+			Title        string `json:"title"`
+			VideoName    string `json:"videoName"`
+			Series       string `json:"series"`
+			Desc         string `json:"desc"`
+			Time         string `json:"time"` // 45:12
+			ClipId       string `json:"clipId"`
+			VideoId      string `json:"videoId"`
+			CollectionId string `json:"collectionId"`
+		} `json:"info"`
+	} `json:"data"`
+}
+
+type BarrageControlResult struct {
+	Status int    `json:"status"`
+	Msg    string `json:"msg"`
+	Data   struct {
+		// bullet-ali.hitv.com,bullet-ws.hitv.com
+		CdnList    string `json:"cdn_list"`
+		CdnVersion string `json:"cdn_version"`
+	} `json:"data"`
+}
+
+type DanmakuResult struct {
+	Status int    `json:"status"`
+	Msg    string `json:"msg"`
+	Data   struct {
+		Interval int `json:"interval"`
+		Items    []struct {
+			Id      int64  `json:"id"`
+			Content string `json:"content"`
+			Time    int64  `json:"time"` // 偏移 ms
+			Type    int    `json:"type"`
+			// 0 滚动 1 顶部 2 底部
+			Position int `json:"v2_position"`
+			Color    struct {
+				ColorLeft struct {
+					R int `json:"r"`
+					G int `json:"g"`
+					B int `json:"b"`
+				} `json:"color_left"`
+			} `json:"v2_color"`
+			UpCount int64 `json:"v2_up_count"`
+		} `json:"items"`
+	} `json:"data"`
+}
+
+// parseDuration 解析 45:12 或者 1:45:12 格式时长 返回秒
+func parseDuration(t string) int64 {
+	var seconds int64
+	for _, part := range strings.Split(t, ":") {
+		v, err := strconv.ParseInt(strings.TrimSpace(part), 10, 64)
+		if err != nil {
+			return 0
+		}
+		seconds = seconds*60 + v
+	}
+	return seconds
+}
@@ -0,0 +1,241 @@
+package plugin
+
+import (
+	"bytes"
+	"context"
+	"danmaku-tool/internal/config"
+	"danmaku-tool/internal/danmaku"
+	"danmaku-tool/internal/utils"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os/exec"
+	"slices"
+	"time"
+)
+
+const pluginC = "plugin"
+
+// loader 根据配置文件中带有 plugin 配置的平台注册外部插件
+type loader struct{}
+
+func init() {
+	danmaku.RegisterInitializer(&loader{})
+}
+
+func (l *loader) Init() error {
+	builtin := danmaku.GetPlatforms()
+	for _, conf := range config.GetConfig().Platforms {
+		if conf.Plugin == nil {
+			continue
+		}
+		if slices.Contains(builtin, conf.Name) {
+			utils.ErrorLog(pluginC, "plugin name conflicts with builtin platform", "platform", conf.Name)
+			continue
+		}
+		if conf.Plugin.Command == "" && conf.Plugin.Url == "" {
+			utils.ErrorLog(pluginC, "plugin command or url is not configured", "platform", conf.Name)
+			continue
+		}
+		a := &adapter{
+			platform: danmaku.Platform(conf.Name),
+			command:  conf.Plugin.Command,
+			args:     conf.Plugin.Args,
+			url:      conf.Plugin.Url,
+		}
+		if err := danmaku.InitPlatformClient(&a.PlatformClient, a.platform); err != nil {
+			utils.ErrorLog(pluginC, err.Error(), "platform", conf.Name)
+			continue
+		}
+		danmaku.RegisterScraper(a)
+		utils.DebugLog(pluginC, "plugin registered", "platform", conf.Name)
+	}
+	return nil
+}
+
+// Reload 配置热加载 重新注册插件平台 已注册的同名平台会被替换
+func (l *loader) Reload() error {
+	return l.Init()
+}
+
+// adapter 将外部插件包装为 danmaku.MediaService
+type adapter struct {
+	danmaku.PlatformClient
+	platform danmaku.Platform
+	command  string
+	args     []string
+	url      string
+}
+
+func (a *adapter) Init() error {
+	return nil
+}
+
+func (a *adapter) Platform() danmaku.Platform {
+	return a.platform
+}
+
+func (a *adapter) call(method string, params any, result any) error {
+	payload, err := json.Marshal(Request{Method: method, Params: params})
+	if err != nil {
+		return err
+	}
+
+	var output []byte
+	if a.command != "" {
+		output, err = a.exec(payload)
+	} else {
+		output, err = a.post(payload)
+	}
+	if err != nil {
+		return fmt.Errorf("[%s] %s call error: %w", a.platform, method, err)
+	}
+
+	var resp Response
+	if err = json.Unmarshal(output, &resp); err != nil {
+		return fmt.Errorf("[%s] %s decode error: %w", a.platform, method, err)
+	}
+	if resp.Error != "" {
+		return fmt.Errorf("[%s] %s error: %s", a.platform, method, resp.Error)
+	}
+	if len(resp.Result) == 0 {
+		return nil
+	}
+	return json.Unmarshal(resp.Result, result)
+}
+
+func (a *adapter) exec(payload []byte) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), a.HttpClient().Timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, a.command, a.args...)
+	cmd.Stdin = bytes.NewReader(payload)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		if stderr.Len() > 0 {
+			return nil, fmt.Errorf("%w: %s", err, stderr.String())
+		}
+		return nil, err
+	}
+	return output, nil
+}
+
+func (a *adapter) post(payload []byte) ([]byte, error) {
+	req, err := http.NewRequest(http.MethodPost, a.url, bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := a.DoReq(req)
+	if err != nil {
+		return nil, err
+	}
+	defer utils.SafeClose(resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("error http status: %s", resp.Status)
+	}
+	var buf bytes.Buffer
+	if _, err = buf.ReadFrom(resp.Body); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (a *adapter) Match(param danmaku.MatchParam) ([]*danmaku.Media, error) {
+	var items []*Media
+	err := a.call(methodMatch, MatchParams{
+		Title:           param.Title,
+		SeasonId:        param.SeasonId,
+		EpisodeId:       param.EpisodeId,
+		ProductionYear:  param.ProductionYear,
+		DurationSeconds: param.DurationSeconds,
+		Mode:            string(param.Mode),
+	}, &items)
+	if err != nil {
+		return nil, err
+	}
+
+	var result = make([]*danmaku.Media, 0, len(items))
+	for _, item := range items {
+		if item == nil {
+			continue
+		}
+		if item.Year > 0 && !param.MatchYear(item.Year) {
+			continue
+		}
+		match := param.MatchTitle(item.Title)
+		utils.DebugLog(pluginC, fmt.Sprintf("[%s] match [%s]: %v", item.Title, param.Title, match), "platform", a.platform)
+		if !match {
+			continue
+		}
+		result = append(result, item.toMedia(a.platform))
+	}
+	return result, nil
+}
+
+func (a *adapter) Media(id string) (*danmaku.Media, error) {
+	var media Media
+	if err := a.call(methodMedia, IdParams{Id: id}, &media); err != nil {
+		return nil, err
+	}
+	if media.Id == "" {
+		media.Id = id
+	}
+	return media.toMedia(a.platform), nil
+}
+
+func (a *adapter) GetDanmaku(id string) ([]*danmaku.StandardDanmaku, error) {
+	var items []*Danmaku
+	if err := a.call(methodDanmaku, IdParams{Id: id}, &items); err != nil {
+		return nil, err
+	}
+
+	var result = make([]*danmaku.StandardDanmaku, 0, len(items))
+	for _, d := range items {
+		if d == nil || d.Content == "" {
+			continue
+		}
+		result = append(result, d.toDanmaku(a.platform))
+	}
+	utils.InfoLog(pluginC, "get danmaku done", "platform", a.platform, "size", len(result))
+	return result, nil
+}
+
+// Scrape id为剧集id 抓取所有单集弹幕
+func (a *adapter) Scrape(id string) error {
+	media, err := a.Media(id)
+	if err != nil {
+		return err
+	}
+
+	start := time.Now()
+	// 插件返回的id不保证可以直接作为路径或者文件名
+	savePath, err := danmaku.MediaSavePath(a.platform, media.Id)
+	if err != nil {
+		return err
+	}
+	for _, ep := range media.Episodes {
+		filename := danmaku.EpisodeFilename(ep.Id)
+		if danmaku.UpToDate(savePath, filename) {
+			continue
+		}
+		data, e := a.GetDanmaku(ep.Id)
+		if e != nil {
+			utils.ErrorLog(pluginC, e.Error(), "platform", a.platform, "id", ep.Id)
+			continue
+		}
+		serializer := &danmaku.SerializerData{
+			EpisodeId: ep.Id,
+			SeasonId:  media.Id,
+			Data:      data,
+		}
+		serializer.SetMedia(media, ep)
+		danmaku.WriteFile(a.platform, serializer, savePath, filename)
+		utils.InfoLog(pluginC, "ep scraped done", "platform", a.platform, "id", ep.Id, "size", len(data))
+	}
+
+	utils.InfoLog(pluginC, "danmaku scraped done", "platform", a.platform, "title", media.Title, "cost_ms", time.Since(start).Milliseconds())
+	return nil
+}
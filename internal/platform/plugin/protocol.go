@@ -0,0 +1,129 @@
+package plugin
+
+import (
+	"danmaku-tool/internal/danmaku"
+	"encoding/json"
+)
+
+/*
+	外部插件协议 所有数据均为 JSON
+
+	请求：
+		{"method": "match", "params": {...}}
+
+	响应：
+		{"result": ..., "error": ""}
+		error 不为空则视为调用失败
+
+	command 插件：每次调用都会启动一次进程，请求写入 stdin，响应从 stdout 读取，进程退出码非0视为失败。
+	url 插件：请求以 POST application/json 发送到配置的url，响应状态码需要为200。
+
+	method:
+		match   params: MatchParams  result: []Media  搜索剧集，结果会再经过标题和年份匹配
+		media   params: IdParams     result: Media    获取剧集以及所有单集信息
+		danmaku params: IdParams     result: []Danmaku 获取单集弹幕 id为 Episode.id
+*/
+
+const (
+	methodMatch   = "match"
+	methodMedia   = "media"
+	methodDanmaku = "danmaku"
+)
+
+type Request struct {
+	Method string `json:"method"`
+	Params any    `json:"params"`
+}
+
+type Response struct {
+	Result json.RawMessage `json:"result"`
+	Error  string          `json:"error"`
+}
+
+type MatchParams struct {
+	Title           string `json:"title"`
+	SeasonId        int    `json:"seasonId"`  // -1 代表无季信息
+	EpisodeId       int    `json:"episodeId"` // 季信息存在时有效
+	ProductionYear  int    `json:"productionYear"`
+	DurationSeconds int64  `json:"durationSeconds"`
+	Mode            string `json:"mode"` // equals contains search
+}
+
+type IdParams struct {
+	Id string `json:"id"`
+}
+
+type Media struct {
+	Id       string     `json:"id"`
+	Type     string     `json:"type"` // series movie
+	TypeDesc string     `json:"typeDesc"`
+	Title    string     `json:"title"`
+	Desc     string     `json:"desc"`
+	Cover    string     `json:"cover"`
+	Year     int        `json:"year"`
+	PubTime  int64      `json:"pubTime"` // unix seconds
+	Episodes []*Episode `json:"episodes"`
+}
+
+type Episode struct {
+	Id        string `json:"id"`        // 用于获取弹幕的id
+	EpisodeId string `json:"episodeId"` // 第几集 数字字符串
+	Title     string `json:"title"`
+}
+
+type Danmaku struct {
+	OffsetMills int64  `json:"offsetMills"`
+	Mode        int    `json:"mode"`  // 1滚动 4底部 5顶部
+	Color       int    `json:"color"` // 16777215
+	FontSize    int32  `json:"fontSize"`
+	Content     string `json:"content"`
+}
+
+func (m *Media) toMedia(platform danmaku.Platform) *danmaku.Media {
+	var mediaType danmaku.MediaType = danmaku.Series
+	if m.Type == danmaku.Movie {
+		mediaType = danmaku.Movie
+	}
+	var eps = make([]*danmaku.MediaEpisode, 0, len(m.Episodes))
+	for _, ep := range m.Episodes {
+		if ep == nil || ep.Id == "" {
+			continue
+		}
+		eps = append(eps, &danmaku.MediaEpisode{
+			Id:        ep.Id,
+			EpisodeId: ep.EpisodeId,
+			Title:     ep.Title,
+		})
+	}
+	return &danmaku.Media{
+		Id:       m.Id,
+		Type:     mediaType,
+		TypeDesc: m.TypeDesc,
+		Title:    m.Title,
+		Desc:     m.Desc,
+		Cover:    m.Cover,
+		Year:     m.Year,
+		PubTime:  m.PubTime,
+		Episodes: eps,
+		Platform: platform,
+	}
+}
+
+func (d *Danmaku) toDanmaku(platform danmaku.Platform) *danmaku.StandardDanmaku {
+	mode := d.Mode
+	if mode != danmaku.TopMode && mode != danmaku.BottomMode {
+		mode = danmaku.NormalMode
+	}
+	color := d.Color
+	if color <= 0 {
+		color = danmaku.WhiteColor
+	}
+	return &danmaku.StandardDanmaku{
+		OffsetMills: d.OffsetMills,
+		Mode:        mode,
+		Color:       color,
+		FontSize:    d.FontSize,
+		Content:     d.Content,
+		Platform:    platform,
+	}
+}
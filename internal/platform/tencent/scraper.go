@@ -2,6 +2,7 @@ package tencent
 
 import (
 	"bytes"
+	"context"
 	"danmaku-tool/internal/config"
 	"danmaku-tool/internal/danmaku"
 	"danmaku-tool/internal/utils"
@@ -182,7 +183,7 @@ func (c *client) Match(param danmaku.MatchParam) ([]*danmaku.Media, error) {
 }
 
 func (c *client) GetDanmaku(id string) ([]*danmaku.StandardDanmaku, error) {
-	return c.getDanmakuByVid(id)
+	return c.getDanmakuByVid(context.Background(), id)
 }
 
 func (c *client) Scrape(idStr string) error {
@@ -190,6 +191,8 @@ func (c *client) Scrape(idStr string) error {
 	var cid = idStr
 	// 是否只查询单集 如果是vid且获取到对应的cid，则只查询该ep
 	var onlyCurrentVID = false
+	// 剧集标题 用于保存路径模板
+	var title string
 	if isVID {
 		// 反查cid 然后再继续查询剧集
 		series, err := c.doSeriesRequest("", idStr, SeriesInfoPageId, "")
@@ -206,6 +209,11 @@ func (c *client) Scrape(idStr string) error {
 		}
 		cid = epCID
 		onlyCurrentVID = true
+		title = infos[0].ItemParams.Title
+	} else if series, err := c.doSeriesRequest(cid, "", SeriesInfoPageId, ""); err == nil {
+		if infos, e := series.series(); e == nil && len(infos) > 0 {
+			title = infos[0].ItemParams.Title
+		}
 	}
 
 	eps, err := c.series(cid)
@@ -225,30 +233,76 @@ func (c *client) Scrape(idStr string) error {
 			continue
 		}
 
-		data, e := c.getDanmakuByVid(ep.ItemParams.VID)
-		if e != nil {
-			utils.ErrorLog(danmaku.Tencent, fmt.Sprintf("get danmaku by vid error: %s", e.Error()))
+		path := filepath.Join(config.GetConfig().SavePath, danmaku.Tencent, ep.ItemParams.CID)
+		if danmaku.UpToDate(path, ep.ItemParams.VID) {
 			continue
 		}
-		serializer := &danmaku.SerializerData{
-			EpisodeId: ep.ItemParams.VID,
-			SeasonId:  cid,
-			Data:      data,
-		}
-		v, err := strconv.ParseInt(ep.ItemParams.Duration, 10, 64)
-		if err == nil {
-			serializer.DurationInMills = v * 1000
-		} else {
-			utils.ErrorLog(danmaku.Tencent, "duration is not number", "vid", ep.ItemParams.VID, "duration", ep.ItemParams.Duration)
+		if _, _, e := c.scrapeEpisode(context.Background(), cid, title, ep); e != nil {
+			utils.ErrorLog(danmaku.Tencent, fmt.Sprintf("get danmaku by vid error: %s", e.Error()))
 		}
-
-		path := filepath.Join(config.GetConfig().SavePath, danmaku.Tencent, ep.ItemParams.CID)
-		danmaku.WriteFile(danmaku.Tencent, serializer, path, ep.ItemParams.VID)
-
-		utils.InfoLog(danmaku.Tencent, "ep scraped done", "vid", ep.ItemParams.VID, "size", len(data))
 	}
 
 	utils.InfoLog(danmaku.Tencent, "danmaku scraped done", "cid", cid)
 
 	return nil
 }
+
+// ScrapeEpisode id为 Media 返回的vid 文件与 Scrape 一致
+func (c *client) ScrapeEpisode(ctx context.Context, vid string) ([]string, int, error) {
+	series, err := c.doSeriesRequest("", vid, SeriesInfoPageId, "")
+	if err != nil {
+		return nil, 0, err
+	}
+	infos, err := series.series()
+	if err != nil {
+		return nil, 0, err
+	}
+	if len(infos) < 1 || infos[0].ItemParams.ReportCID == "" {
+		return nil, 0, fmt.Errorf("%s has no cid", vid)
+	}
+	cid := infos[0].ItemParams.ReportCID
+	eps, err := c.series(cid)
+	if err != nil {
+		return nil, 0, err
+	}
+	for _, ep := range eps {
+		if ep.ItemParams.VID == vid {
+			return c.scrapeEpisode(ctx, cid, infos[0].ItemParams.Title, ep)
+		}
+	}
+	return nil, 0, fmt.Errorf("%s episode not found", vid)
+}
+
+// scrapeEpisode 抓取并保存单集 savePath/{platform}/{cid}/{vid}.xml
+func (c *client) scrapeEpisode(ctx context.Context, cid, title string, ep *SeriesItem) ([]string, int, error) {
+	data, err := c.getDanmakuByVid(ctx, ep.ItemParams.VID)
+	if err != nil {
+		return nil, 0, err
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, 0, err
+	}
+	serializer := &danmaku.SerializerData{
+		EpisodeId:    ep.ItemParams.VID,
+		SeasonId:     cid,
+		Data:         data,
+		Title:        title,
+		EpisodeTitle: ep.ItemParams.VideoSubtitle,
+	}
+	serializer.EpisodeNumber, _ = strconv.Atoi(ep.ItemParams.Title)
+	if serializer.EpisodeTitle == "" {
+		serializer.EpisodeTitle = ep.ItemParams.PlayTitle
+	}
+	v, err := strconv.ParseInt(ep.ItemParams.Duration, 10, 64)
+	if err == nil {
+		serializer.DurationInMills = v * 1000
+	} else {
+		utils.ErrorLog(danmaku.Tencent, "duration is not number", "vid", ep.ItemParams.VID, "duration", ep.ItemParams.Duration)
+	}
+
+	path := filepath.Join(config.GetConfig().SavePath, danmaku.Tencent, ep.ItemParams.CID)
+	files := danmaku.WriteFile(danmaku.Tencent, serializer, path, ep.ItemParams.VID)
+
+	utils.InfoLog(danmaku.Tencent, "ep scraped done", "vid", ep.ItemParams.VID, "size", len(data))
+	return files, len(data), nil
+}
@@ -2,6 +2,7 @@ package tencent
 
 import (
 	"bytes"
+	"context"
 	"danmaku-tool/internal/danmaku"
 	"danmaku-tool/internal/utils"
 	"encoding/json"
@@ -11,6 +12,7 @@ import (
 	"sort"
 	"strconv"
 	"sync"
+	"time"
 )
 
 func (c *client) Init() error {
@@ -33,6 +35,32 @@ func (c *client) Platform() danmaku.Platform {
 	return danmaku.Tencent
 }
 
+// ResolveURL 支持 v.qq.com 剧集以及单集链接 单集链接返回vid
+func (c *client) ResolveURL(u *url.URL) (string, error) {
+	if !danmaku.MatchHost(u, "v.qq.com") {
+		return "", nil
+	}
+	// https://v.qq.com/x/cover/mzc00200xxx.html https://v.qq.com/x/cover/mzc00200xxx/v4100xxx.html
+	if m := coverUrlRegex.FindStringSubmatch(u.Path); len(m) > 2 {
+		if m[2] != "" {
+			return m[2], nil
+		}
+		return m[1], nil
+	}
+	// https://v.qq.com/x/page/v4100xxx.html
+	if m := pageUrlRegex.FindStringSubmatch(u.Path); len(m) > 1 {
+		return m[1], nil
+	}
+	// 移动端 https://m.v.qq.com/x/m/play?cid=mzc00200xxx&vid=v4100xxx
+	if vid := u.Query().Get("vid"); vid != "" {
+		return vid, nil
+	}
+	if cid := u.Query().Get("cid"); cid != "" {
+		return cid, nil
+	}
+	return "", fmt.Errorf("only support cover or page url: %s", u)
+}
+
 func (c *client) doSeriesRequest(cid, vid string, pageId, pageContent string) (*SeriesResult, error) {
 	var seriesReqParam = SeriesReqParam{
 		HasCache: 1,
@@ -141,7 +169,7 @@ func (c *client) series(cid string) ([]*SeriesItem, error) {
 	return eps, nil
 }
 
-func (c *client) getDanmakuByVid(vid string) ([]*danmaku.StandardDanmaku, error) {
+func (c *client) getDanmakuByVid(ctx context.Context, vid string) ([]*danmaku.StandardDanmaku, error) {
 	param := map[string]string{
 		"vid":            vid,
 		"engine_version": "2.1.10",
@@ -168,15 +196,25 @@ func (c *client) getDanmakuByVid(vid string) ([]*danmaku.StandardDanmaku, error)
 	}
 	utils.DebugLog(danmaku.Tencent, fmt.Sprintf("danmaku segments size: %v", segmentsLen), "vid", vid)
 
-	tasks := make(chan task, c.MaxWorker)
-	ch := make(chan []*danmaku.StandardDanmaku, c.MaxWorker)
+	tasks := make(chan task, c.MaxWorker())
+	ch := make(chan []*danmaku.StandardDanmaku, c.MaxWorker())
 	var wg sync.WaitGroup
-	for w := 0; w < c.MaxWorker; w++ {
+	for w := 0; w < c.MaxWorker(); w++ {
 		wg.Add(1)
 		go func(i int) {
 			defer wg.Done()
 			for t := range tasks {
-				data := c.scrape(t.vid, t.segment)
+				// 取消后跳过剩余的分片
+				if ctx.Err() != nil {
+					continue
+				}
+				start := time.Now()
+				data, e := c.scrape(t.vid, t.segment)
+				danmaku.ObserveSegment(ctx, danmaku.Tencent, start, e)
+				if e != nil {
+					utils.ErrorLog(danmaku.Tencent, fmt.Sprintf("%s scrape segment %s error: %s", t.vid, t.segment, e.Error()))
+					continue
+				}
 				if len(data) <= 0 {
 					continue
 				}
@@ -212,26 +250,23 @@ type task struct {
 	segment string
 }
 
-func (c *client) scrape(vid, segment string) []*danmaku.StandardDanmaku {
+func (c *client) scrape(vid, segment string) ([]*danmaku.StandardDanmaku, error) {
 	//https://dm.video.qq.com/barrage/segment/{vid}/{segment}
 	api := fmt.Sprintf("https://dm.video.qq.com/barrage/segment/%s/%s", vid, segment)
 
 	req, err := http.NewRequest(http.MethodGet, api, nil)
 	if err != nil {
-		utils.ErrorLog(danmaku.Tencent, err.Error())
-		return nil
+		return nil, err
 	}
 	resp, err := c.DoReq(req)
 	if err != nil {
-		utils.ErrorLog(danmaku.Tencent, err.Error())
-		return nil
+		return nil, err
 	}
 
 	var danmakuResult DanmakuResult
 	err = utils.SafeDecodeOkResp(resp, &danmakuResult)
 	if err != nil {
-		utils.ErrorLog(danmaku.Tencent, err.Error())
-		return nil
+		return nil, err
 	}
 
 	var result = make([]*danmaku.StandardDanmaku, 0, len(danmakuResult.BarrageList))
@@ -270,14 +305,17 @@ func (c *client) scrape(vid, segment string) []*danmaku.StandardDanmaku {
 			Color:       colorValue,
 			Platform:    danmaku.Tencent,
 		}
+		if likes, e := strconv.ParseInt(v.UpCount, 10, 64); e == nil {
+			r.Likes = likes
+		}
 		result = append(result, r)
 	}
 
-	return result
+	return result, nil
 }
 
 func (c *client) setRequest(req *http.Request) {
-	req.Header.Set("Cookie", c.Cookie)
+	req.Header.Set("Cookie", c.Cookie())
 	req.Header.Set("Origin", "https://v.qq.com/")
 	req.Header.Set("Referer", "https://v.qq.com/")
 	// 注意如果json请求不设置该请求头，则会导致部分接口异常返回400，哪怕参数全部正常。
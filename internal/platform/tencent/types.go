@@ -6,6 +6,8 @@ import (
 )
 
 var tencentExcludeRegex = regexp.MustCompile(`(全网搜|外站)`)
+var coverUrlRegex = regexp.MustCompile(`^/x/cover/([0-9a-zA-Z]+)(?:/([0-9a-zA-Z]+))?\.html`)
+var pageUrlRegex = regexp.MustCompile(`^/x/page/([0-9a-zA-Z]+)\.html`)
 
 const SeriesEPPageId = "vsite_episode_list"
 const SeriesInfoPageId = "detail_page_introduction"
@@ -111,7 +113,7 @@ type DanmakuResult struct {
 	BarrageList []struct {
 		Content    string `json:"content"`
 		Id         string `json:"id"`
-		UpCount    string `json:"up_count"`    // 点赞数？
+		UpCount    string `json:"up_count"`    // 点赞数
 		CreateTime string `json:"create_time"` // 1715077975
 		// {\"color\":\"ffffff\",\"gradient_colors\":[\"44EB1F\",\"44EB1F\"],\"position\":1}
 		// 颜色信息 json
@@ -60,6 +60,15 @@ func (c *client) refreshToken() {
 	c.tkLastUpdate = time.Now()
 }
 
+// Probe 检查接口签名token是否可以获取
+func (c *client) Probe() error {
+	c.refreshToken()
+	if c.cna == "" || c.token == "" || c.tokenEnc == "" {
+		return fmt.Errorf("get youku token fail")
+	}
+	return nil
+}
+
 var tkRegex = regexp.MustCompile(`_m_h5_tk=([a-z0-9]{32}_[0-9]{13});`)
 var encTkRegex = regexp.MustCompile(`_m_h5_tk_enc=([a-z0-9]{32});`)
 
@@ -1,6 +1,7 @@
 package youku
 
 import (
+	"context"
 	"danmaku-tool/internal/config"
 	"danmaku-tool/internal/danmaku"
 	"danmaku-tool/internal/utils"
@@ -25,16 +26,22 @@ func (c *client) Scrape(vid string) error {
 	// 1分钟分片
 	segmentsLen := int(durationInSeconds/60 + 1)
 
-	var result = c.scrapeDanmaku(vid, segmentsLen)
+	path := filepath.Join(config.GetConfig().SavePath, danmaku.Youku, info.ShowId)
+	if danmaku.UpToDate(path, vid) {
+		return nil
+	}
+	var result = c.scrapeDanmaku(context.Background(), vid, segmentsLen)
 
 	serializer := &danmaku.SerializerData{
 		EpisodeId:       vid,
 		SeasonId:        info.ShowId,
 		Data:            result,
 		DurationInMills: int64(durationInSeconds * 1000),
+		Title:           info.ShowName,
+		EpisodeTitle:    info.Title,
 	}
+	serializer.EpisodeNumber, _ = strconv.Atoi(info.ShowVideoStage)
 
-	path := filepath.Join(config.GetConfig().SavePath, danmaku.Youku, info.ShowId)
 	danmaku.WriteFile(danmaku.Youku, serializer, path, vid)
 
 	return nil
@@ -196,5 +203,5 @@ func (c *client) GetDanmaku(id string) ([]*danmaku.StandardDanmaku, error) {
 		return nil, err
 	}
 
-	return c.scrapeDanmaku(id, int(duration/60+1)), nil
+	return c.scrapeDanmaku(context.Background(), id, int(duration/60+1)), nil
 }
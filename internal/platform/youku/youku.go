@@ -1,6 +1,7 @@
 package youku
 
 import (
+	"context"
 	"danmaku-tool/internal/danmaku"
 	"danmaku-tool/internal/utils"
 	"encoding/json"
@@ -36,6 +37,18 @@ func (c *client) Platform() danmaku.Platform {
 	return danmaku.Youku
 }
 
+// ResolveURL 支持 v.youku.com 单集播放链接
+func (c *client) ResolveURL(u *url.URL) (string, error) {
+	if !danmaku.MatchHost(u, "youku.com") {
+		return "", nil
+	}
+	// https://v.youku.com/v_show/id_XNjM2OTM4MjY0NA==.html?s=ecba3364afbe46aaa122
+	if m := matchVIDRegex.FindStringSubmatch(u.Path); len(m) > 1 {
+		return m[1], nil
+	}
+	return "", fmt.Errorf("only support v_show url: %s", u)
+}
+
 /*
 	优酷的视频url格式  链接中带视频vid
 	https://v.youku.com/v_show/id_XMTA3MDAzODEy.html?s=cc07361a962411de83b1
@@ -86,19 +99,25 @@ func (c *client) videoInfo(vid string) (*VideoInfoFromHtml, *ShowInfoFromHtml, e
 	return &info, &showInfo, nil
 }
 
-func (c *client) scrapeDanmaku(vid string, segmentsLen int) []*danmaku.StandardDanmaku {
+func (c *client) scrapeDanmaku(ctx context.Context, vid string, segmentsLen int) []*danmaku.StandardDanmaku {
 
-	tasks := make(chan task, c.MaxWorker)
+	tasks := make(chan task, c.MaxWorker())
 	// 刷新token
 	c.refreshToken()
-	ch := make(chan []*danmaku.StandardDanmaku, c.MaxWorker)
+	ch := make(chan []*danmaku.StandardDanmaku, c.MaxWorker())
 	var wg sync.WaitGroup
-	for w := 0; w < c.MaxWorker; w++ {
+	for w := 0; w < c.MaxWorker(); w++ {
 		wg.Add(1)
 		go func(i int) {
 			defer wg.Done()
 			for t := range tasks {
+				// 取消后跳过剩余的分片
+				if ctx.Err() != nil {
+					continue
+				}
+				start := time.Now()
 				data, e := c.scrape(t.vid, t.segment)
+				danmaku.ObserveSegment(ctx, danmaku.Youku, start, e)
 				if e != nil {
 					utils.ErrorLog(danmaku.Youku, fmt.Sprintf("%s scrape segment %d error: %s", t.vid, t.segment, e.Error()))
 					continue
@@ -203,10 +222,7 @@ func (c *client) scrape(vid string, segment int) ([]*danmaku.StandardDanmaku, er
 func (c *client) getVID(showId string) string {
 	//	https://v.youku.com/video?s=ecba3364afbe46aaa122 会 302 到视频地址
 	req, _ := http.NewRequest(http.MethodGet, "https://v.youku.com/video?s="+showId, nil)
-	c.HttpClient.CheckRedirect = func(req *http.Request, via []*http.Request) error {
-		return http.ErrUseLastResponse
-	}
-	resp, err := c.DoReq(req)
+	resp, err := c.DoReqWithoutRedirect(req)
 	if err != nil {
 		utils.WarnLog(danmaku.Youku, fmt.Sprintf("get vid req fail: %s", err.Error()))
 		return ""
@@ -0,0 +1,265 @@
+package refresh
+
+import (
+	"danmaku-tool/internal/config"
+	"danmaku-tool/internal/utils"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+)
+
+/*
+	弹幕定时刷新 新番播出后的几天内弹幕会持续增加
+	记录server模式最近请求的单集以及cli抓取过的剧集 播出之后按照递减的频率重新获取（默认 1h 6h 1d 7d）
+	平台提供播出时间时以播出时间计算 否则以首次记录时间计算 记录时已经错过的时间点直接跳过
+	全部刷新完成后不再记录 数据以json格式保存在配置文件目录
+	server和cli共用同一个文件 各自只读写自己类型的记录
+*/
+
+const refreshC = "refresh"
+
+const (
+	KindEpisode = "episode" // server模式请求的单集 id为dandan api的episodeId
+	KindScrape  = "scrape"  // cli抓取 id为scrape命令的id
+)
+
+const (
+	fileName        = "refresh.json"
+	defaultInterval = 300 // 秒
+)
+
+var defaultSchedule = []int{1, 6, 24, 168}
+
+// Target 需要刷新的弹幕
+type Target struct {
+	Kind        string    `json:"kind"`
+	Platform    string    `json:"platform"`
+	Id          string    `json:"id"`
+	FirstSeen   time.Time `json:"firstSeen"`
+	AiredAt     time.Time `json:"airedAt,omitzero"` // 播出时间 平台不提供则为零值
+	LastRefresh time.Time `json:"lastRefresh"`
+	Stage       int       `json:"stage"` // 已经完成的刷新次数
+}
+
+func (t *Target) key() string {
+	return t.Kind + "\x00" + t.Platform + "\x00" + t.Id
+}
+
+// anchor 刷新时间点的起点 优先使用播出时间
+func (t *Target) anchor() time.Time {
+	if !t.AiredAt.IsZero() {
+		return t.AiredAt
+	}
+	return t.FirstSeen
+}
+
+// Next 下一次刷新时间 全部完成则返回零值
+func (t *Target) Next(schedule []time.Duration) time.Time {
+	if t.Stage >= len(schedule) {
+		return time.Time{}
+	}
+	return t.anchor().Add(schedule[t.Stage])
+}
+
+// skipElapsed 跳过 now 之前的刷新时间点
+func (t *Target) skipElapsed(now time.Time, schedule []time.Duration) {
+	elapsed := now.Sub(t.anchor())
+	for t.Stage < len(schedule) && schedule[t.Stage] <= elapsed {
+		t.Stage++
+	}
+}
+
+// Store 单一类型的刷新记录
+type Store struct {
+	path    string
+	kind    string
+	lock    sync.Mutex
+	targets map[string]*Target
+	dirty   bool
+}
+
+// DefaultPath 配置文件目录下的 refresh.json
+func DefaultPath() string {
+	return filepath.Join(filepath.Dir(config.ConfPath), fileName)
+}
+
+// Schedule 刷新时间点 相对播出或者首次记录的时间 升序
+func Schedule(conf config.RefreshConfig) []time.Duration {
+	hours := conf.Schedule
+	if len(hours) == 0 {
+		hours = defaultSchedule
+	}
+	var schedule = make([]time.Duration, 0, len(hours))
+	for _, h := range hours {
+		if h > 0 {
+			schedule = append(schedule, time.Duration(h)*time.Hour)
+		}
+	}
+	slices.Sort(schedule)
+	return schedule
+}
+
+// Interval server模式检查间隔
+func Interval(conf config.RefreshConfig) time.Duration {
+	interval := conf.Interval
+	if interval <= 0 {
+		interval = defaultInterval
+	}
+	return time.Duration(interval) * time.Second
+}
+
+// Open 读取指定类型的刷新记录 文件不存在则为空
+func Open(path, kind string) (*Store, error) {
+	targets, err := readTargets(path)
+	if err != nil {
+		return nil, err
+	}
+	s := &Store{path: path, kind: kind, targets: map[string]*Target{}}
+	for _, t := range targets {
+		if t.Kind == kind {
+			s.targets[t.key()] = t
+		}
+	}
+	return s, nil
+}
+
+func readTargets(path string) ([]*Target, error) {
+	file, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var targets []*Target
+	if err = json.Unmarshal(file, &targets); err != nil {
+		return nil, err
+	}
+	return targets, nil
+}
+
+// Tracked 是否已经记录
+func (s *Store) Tracked(platform, id string) bool {
+	t := &Target{Kind: s.kind, Platform: platform, Id: id}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	_, ok := s.targets[t.key()]
+	return ok
+}
+
+// Track 记录需要刷新的弹幕 aired 为播出时间 未知则传零值
+// 已经记录的保持不变 记录时已经错过的时间点直接跳过 全部错过则不记录
+func (s *Store) Track(platform, id string, aired time.Time, schedule []time.Duration) {
+	now := time.Now()
+	t := &Target{Kind: s.kind, Platform: platform, Id: id, FirstSeen: now, AiredAt: aired}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if _, ok := s.targets[t.key()]; ok {
+		return
+	}
+	t.skipElapsed(now, schedule)
+	if t.Stage >= len(schedule) {
+		utils.DebugLog(refreshC, "aired before the last refresh point, not tracked", "kind", s.kind, "platform", platform, "id", id, "aired", aired)
+		return
+	}
+	s.targets[t.key()] = t
+	s.dirty = true
+	utils.DebugLog(refreshC, "refresh target tracked", "kind", s.kind, "platform", platform, "id", id, "stage", t.Stage)
+}
+
+// Due 到达刷新时间的记录 按照下一次刷新时间排序
+func (s *Store) Due(now time.Time, schedule []time.Duration) []Target {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	var due []Target
+	for _, t := range s.targets {
+		if next := t.Next(schedule); !next.IsZero() && !now.Before(next) {
+			due = append(due, *t)
+		}
+	}
+	sort.Slice(due, func(i, j int) bool {
+		return due[i].Next(schedule).Before(due[j].Next(schedule))
+	})
+	return due
+}
+
+// Done 刷新完成 错过的刷新时间点一并跳过 全部完成则删除记录
+func (s *Store) Done(t Target, now time.Time, schedule []time.Duration) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	target, ok := s.targets[t.key()]
+	if !ok {
+		return
+	}
+	s.dirty = true
+	target.skipElapsed(now, schedule)
+	target.LastRefresh = now
+	if target.Stage >= len(schedule) {
+		delete(s.targets, t.key())
+		utils.DebugLog(refreshC, "refresh target finished", "kind", t.Kind, "platform", t.Platform, "id", t.Id)
+	}
+}
+
+// Remove 删除记录
+func (s *Store) Remove(t Target) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if _, ok := s.targets[t.key()]; ok {
+		delete(s.targets, t.key())
+		s.dirty = true
+	}
+}
+
+// List 所有记录 按照首次记录时间排序
+func (s *Store) List() []Target {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	var list = make([]Target, 0, len(s.targets))
+	for _, t := range s.targets {
+		list = append(list, *t)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].FirstSeen.Before(list[j].FirstSeen)
+	})
+	return list
+}
+
+// Save 有变更才写入文件 保留文件中其他类型的记录
+func (s *Store) Save() error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if !s.dirty {
+		return nil
+	}
+	existing, err := readTargets(s.path)
+	if err != nil {
+		return err
+	}
+	var targets = make([]*Target, 0, len(existing)+len(s.targets))
+	for _, t := range existing {
+		if t.Kind != s.kind {
+			targets = append(targets, t)
+		}
+	}
+	for _, t := range s.targets {
+		targets = append(targets, t)
+	}
+	data, err := json.MarshalIndent(targets, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := s.path + ".tmp"
+	if err = os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	if err = os.Rename(tmp, s.path); err != nil {
+		return err
+	}
+	s.dirty = false
+	return nil
+}
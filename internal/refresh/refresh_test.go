@@ -0,0 +1,166 @@
+package refresh
+
+import (
+	"danmaku-tool/internal/config"
+	"danmaku-tool/internal/utils"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestMain(m *testing.M) {
+	utils.InitLogger(false, false)
+	os.Exit(m.Run())
+}
+
+var testSchedule = []time.Duration{time.Hour, 6 * time.Hour, 24 * time.Hour}
+
+func TestTargetNext(t *testing.T) {
+	seen := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	aired := seen.Add(-3 * time.Hour)
+	tests := []struct {
+		name   string
+		target Target
+		want   time.Time
+	}{
+		{name: "first stage from first seen", target: Target{FirstSeen: seen}, want: seen.Add(time.Hour)},
+		{name: "later stage", target: Target{FirstSeen: seen, Stage: 2}, want: seen.Add(24 * time.Hour)},
+		{name: "anchored to air time", target: Target{FirstSeen: seen, AiredAt: aired, Stage: 1}, want: aired.Add(6 * time.Hour)},
+		{name: "finished", target: Target{FirstSeen: seen, Stage: 3}, want: time.Time{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.target.Next(testSchedule); !got.Equal(tt.want) {
+				t.Errorf("Next = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStoreTrack(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name        string
+		aired       time.Time
+		wantTracked bool
+		wantStage   int
+	}{
+		{name: "unknown air time", wantTracked: true, wantStage: 0},
+		{name: "just aired", aired: now.Add(-time.Minute), wantTracked: true, wantStage: 0},
+		{name: "aired hours ago", aired: now.Add(-2 * time.Hour), wantTracked: true, wantStage: 1},
+		{name: "aired days ago", aired: now.Add(-48 * time.Hour), wantTracked: false},
+		{name: "not aired yet", aired: now.Add(time.Hour), wantTracked: true, wantStage: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := Open(filepath.Join(t.TempDir(), fileName), KindEpisode)
+			if err != nil {
+				t.Fatal(err)
+			}
+			s.Track("bilibili", "1", tt.aired, testSchedule)
+			if got := s.Tracked("bilibili", "1"); got != tt.wantTracked {
+				t.Fatalf("tracked = %v, want %v", got, tt.wantTracked)
+			}
+			if !tt.wantTracked {
+				return
+			}
+			if stage := s.List()[0].Stage; stage != tt.wantStage {
+				t.Errorf("stage = %d, want %d", stage, tt.wantStage)
+			}
+		})
+	}
+}
+
+func TestStoreDue(t *testing.T) {
+	s, err := Open(filepath.Join(t.TempDir(), fileName), KindEpisode)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	s.Track("bilibili", "new", time.Time{}, testSchedule)
+	s.Track("mgtv", "aired", now.Add(-30*time.Minute), testSchedule)
+	// 已经记录的保持不变
+	s.Track("mgtv", "aired", time.Time{}, testSchedule)
+
+	tests := []struct {
+		at   time.Duration
+		want []string
+	}{
+		{at: 0, want: nil},
+		{at: 40 * time.Minute, want: []string{"aired"}},
+		{at: 2 * time.Hour, want: []string{"aired", "new"}},
+	}
+	for _, tt := range tests {
+		var ids []string
+		for _, target := range s.Due(now.Add(tt.at), testSchedule) {
+			ids = append(ids, target.Id)
+		}
+		if !slices.Equal(ids, tt.want) {
+			t.Errorf("due at +%v = %v, want %v", tt.at, ids, tt.want)
+		}
+	}
+
+	// 完成时跳过错过的时间点 全部完成后删除
+	due := s.Due(now.Add(7*time.Hour), testSchedule)
+	for _, target := range due {
+		s.Done(target, now.Add(7*time.Hour), testSchedule)
+	}
+	for _, target := range s.List() {
+		if target.Stage != 2 {
+			t.Errorf("%s stage = %d after done at +7h, want 2", target.Id, target.Stage)
+		}
+	}
+	for _, target := range s.Due(now.Add(25*time.Hour), testSchedule) {
+		s.Done(target, now.Add(25*time.Hour), testSchedule)
+	}
+	if list := s.List(); len(list) != 0 {
+		t.Errorf("%d targets left after all stages done", len(list))
+	}
+}
+
+func TestStoreSaveKeepsOtherKinds(t *testing.T) {
+	path := filepath.Join(t.TempDir(), fileName)
+	episodes, err := Open(path, KindEpisode)
+	if err != nil {
+		t.Fatal(err)
+	}
+	episodes.Track("bilibili", "1", time.Time{}, testSchedule)
+	if err = episodes.Save(); err != nil {
+		t.Fatal(err)
+	}
+	scrapes, err := Open(path, KindScrape)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(scrapes.List()) != 0 {
+		t.Fatalf("scrape store loaded episode targets")
+	}
+	scrapes.Track("mgtv", "cid", time.Time{}, testSchedule)
+	if err = scrapes.Save(); err != nil {
+		t.Fatal(err)
+	}
+	episodes, err = Open(path, KindEpisode)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !episodes.Tracked("bilibili", "1") || episodes.Tracked("mgtv", "cid") {
+		t.Errorf("episode targets changed after saving scrape targets: %v", episodes.List())
+	}
+}
+
+func TestSchedule(t *testing.T) {
+	tests := []struct {
+		hours []int
+		want  []time.Duration
+	}{
+		{hours: nil, want: []time.Duration{time.Hour, 6 * time.Hour, 24 * time.Hour, 168 * time.Hour}},
+		{hours: []int{24, 2, 0, -1}, want: []time.Duration{2 * time.Hour, 24 * time.Hour}},
+	}
+	for _, tt := range tests {
+		if got := Schedule(config.RefreshConfig{Schedule: tt.hours}); !slices.Equal(got, tt.want) {
+			t.Errorf("Schedule(%v) = %v, want %v", tt.hours, got, tt.want)
+		}
+	}
+}
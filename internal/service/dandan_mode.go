@@ -3,6 +3,7 @@ package service
 import (
 	"danmaku-tool/internal/config"
 	"danmaku-tool/internal/danmaku"
+	"slices"
 )
 
 func init() {
@@ -17,6 +18,16 @@ func GetDandanSourceMode() DandanSourceMode {
 	return sourceModes[config.GetConfig().DandanMode]
 }
 
+// GetDandanSourceModes 所有支持的 dandan-mode
+func GetDandanSourceModes() []string {
+	var modes = make([]string, 0, len(sourceModes))
+	for m := range sourceModes {
+		modes = append(modes, m)
+	}
+	slices.Sort(modes)
+	return modes
+}
+
 // DandanSourceMode dandan api 数据源接口
 type DandanSourceMode interface {
 	Match(param MatchParam) (*DanDanResult, error)
@@ -26,6 +37,17 @@ type DandanSourceMode interface {
 	Mode() Mode
 }
 
+// MappingStore id映射数据存储 用于健康检查
+type MappingStore interface {
+	MappingStatus() MappingStatus
+}
+
+type MappingStatus struct {
+	Loaded   bool `json:"loaded"`   // 服务初始化完成
+	Restored bool `json:"restored"` // 是否从文件恢复
+	Size     int  `json:"size"`
+}
+
 type Mode string
 
 const (
@@ -52,6 +74,14 @@ type DanDanResult struct {
 	// match result
 	IsMatched bool    `json:"isMatched"`
 	Matches   []Match `json:"matches"`
+	// 需要预取弹幕的剧集 未开启预取则为空
+	Prefetch []PrefetchItem `json:"-"`
+}
+
+// PrefetchItem 预取的单集
+type PrefetchItem struct {
+	EpisodeId int64
+	Platform  string
 }
 
 type DanDanResultInfo struct {
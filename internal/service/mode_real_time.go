@@ -4,6 +4,7 @@ import (
 	"compress/gzip"
 	"danmaku-tool/internal/config"
 	"danmaku-tool/internal/danmaku"
+	"danmaku-tool/internal/metrics"
 	"danmaku-tool/internal/utils"
 	"encoding/gob"
 	"fmt"
@@ -12,6 +13,7 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -62,6 +64,8 @@ func (c *realTimeData) Load() (bool, error) {
 		c.ForwardMap = make(map[string]int64, 1000)
 		c.ReverseMap = make(map[int64]string, 1000)
 		c.IdAllocator = int64(1)
+		c.Titles = make(map[string]string, 100)
+		c.Bindings = make(map[string]int64)
 		return false, err
 	}
 	defer utils.SafeClose(file)
@@ -75,6 +79,13 @@ func (c *realTimeData) Load() (bool, error) {
 	if e := gob.NewDecoder(gz).Decode(c); e != nil {
 		return false, fmt.Errorf("failed to decode data: %w", e)
 	}
+	// 兼容旧版本数据文件
+	if c.Titles == nil {
+		c.Titles = make(map[string]string, 100)
+	}
+	if c.Bindings == nil {
+		c.Bindings = make(map[string]int64)
+	}
 	fileInfo, _ := file.Stat()
 	utils.InfoLog(realTimeServiceC, fmt.Sprintf("data size: %dx2, next id: %d, cache file size: %d byte", len(c.ForwardMap), c.IdAllocator, fileInfo.Size()))
 
@@ -85,14 +96,31 @@ const realTimeServiceC = "real_time_service"
 
 func (c *realTimeData) ServerInit() error {
 	success, err := c.Load()
+	c.loaded.Store(true)
+	c.restored.Store(success)
 	if err != nil {
 		return err
 	}
 	utils.InfoLog(realTimeServiceC, fmt.Sprintf("restore data from file success: %v", success))
+	metrics.NewGaugeFunc("danmaku_mapping_entries", "Number of episode id mappings in the mapping store.", func() float64 {
+		c.lock.RLock()
+		defer c.lock.RUnlock()
+		return float64(len(c.ForwardMap))
+	})
 	return nil
 }
 
 func (c *realTimeData) Match(param MatchParam) (*DanDanResult, error) {
+	// 手动绑定优先
+	if m, ok := c.bindingMatch(param.FileName); ok {
+		utils.InfoLog(realTimeServiceC, "binding match success", "title", param.FileName, "id", m.EpisodeId)
+		return &DanDanResult{
+			DanDanResultInfo: DanDanResultInfo{Success: true},
+			IsMatched:        true,
+			Matches:          []Match{*m},
+		}, nil
+	}
+
 	matches := danmaku.SeriesRegex.FindStringSubmatch(param.FileName)
 	epId := int64(-1)
 	searchMovies := true
@@ -125,7 +153,11 @@ func (c *realTimeData) Match(param MatchParam) (*DanDanResult, error) {
 		if len(m.Episodes) == 0 {
 			continue
 		}
+		c.setTitle(string(m.Platform), m.Id, m.Title)
 		if searchMovies {
+			if !result.IsMatched {
+				result.Prefetch = c.prefetchItems(m, 0, false)
+			}
 			result.IsMatched = true
 			result.Matches = append(result.Matches, Match{
 				EpisodeId:    c.getGlobalID(string(m.Platform), m.Id, m.Episodes[0].Id),
@@ -134,10 +166,13 @@ func (c *realTimeData) Match(param MatchParam) (*DanDanResult, error) {
 			})
 			utils.InfoLog(realTimeServiceC, "movie match success", "platform", m.Platform, "title", param.FileName)
 		} else {
-			for _, ep := range m.Episodes {
+			for i, ep := range m.Episodes {
 				epStr := strconv.FormatInt(epId, 10)
 				if ep.EpisodeId == epStr {
 					utils.InfoLog(realTimeServiceC, "ep match success", "platform", m.Platform, "title", param.FileName, "ep", ep.EpisodeId)
+					if !result.IsMatched {
+						result.Prefetch = c.prefetchItems(m, i, true)
+					}
 					result.IsMatched = true
 					result.Matches = append(result.Matches, Match{
 						EpisodeId:    c.getGlobalID(string(m.Platform), m.Id, ep.Id),
@@ -152,7 +187,46 @@ func (c *realTimeData) Match(param MatchParam) (*DanDanResult, error) {
 	return result, nil
 }
 
+const defaultPrefetchEpisodes = 1
+
+// prefetchItems 匹配的单集 剧集额外预取之后几集 只预取第一个匹配结果 客户端只会使用第一个
+func (c *realTimeData) prefetchItems(m *danmaku.Media, index int, series bool) []PrefetchItem {
+	conf := config.GetConfig().Server.Prefetch
+	if !conf.Enable {
+		return nil
+	}
+	var next int
+	if series {
+		next = conf.Episodes
+		if next <= 0 {
+			next = defaultPrefetchEpisodes
+		}
+	}
+	end := min(len(m.Episodes), index+1+next)
+	var items = make([]PrefetchItem, 0, end-index)
+	for _, ep := range m.Episodes[index:end] {
+		items = append(items, PrefetchItem{
+			EpisodeId: c.getGlobalID(string(m.Platform), m.Id, ep.Id),
+			Platform:  string(m.Platform),
+		})
+	}
+	return items
+}
+
+// GetDanmaku 相同参数的并发请求共享同一次平台请求以及结果
 func (c *realTimeData) GetDanmaku(param CommentParam) (*CommentResult, error) {
+	key := fmt.Sprintf("%d?from=%d&chConvert=%t&withRelated=%t", param.Id, param.From, param.Convert, param.WithRelated)
+	comment, err, shared := c.commentGroup.Do(key, func() (*CommentResult, error) {
+		return c.getDanmaku(param)
+	})
+	if shared {
+		metrics.CoalescedRequests.Inc(metrics.OpGetDanmaku)
+		utils.DebugLog(realTimeServiceC, "comment request coalesced", "id", param.Id)
+	}
+	return comment, err
+}
+
+func (c *realTimeData) getDanmaku(param CommentParam) (*CommentResult, error) {
 	platform, _, epId, found := c.decodeGlobalID(param.Id)
 	if !found {
 		return nil, fmt.Errorf("invalid param")
@@ -161,17 +235,24 @@ func (c *realTimeData) GetDanmaku(param CommentParam) (*CommentResult, error) {
 	if scraper == nil {
 		return nil, fmt.Errorf("unknown platform")
 	}
-	data, err := scraper.GetDanmaku(epId)
+	start := time.Now()
+	var data []*danmaku.StandardDanmaku
+	var err error
+	// 只在播放器请求时附带关联弹幕
+	if r, ok := scraper.(danmaku.RelatedScraper); ok {
+		data, err = r.GetDanmakuWithRelated(epId, param.WithRelated)
+	} else {
+		data, err = scraper.GetDanmaku(epId)
+	}
+	metrics.ObservePlatformCall(platform, metrics.OpGetDanmaku, start, err)
 	if err != nil {
 		utils.ErrorLog(realTimeServiceC, err.Error())
 		return nil, err
 	}
 
-	// merge danmaku
-	mergeMills := config.GetPlatformConfig(string(scraper.Platform())).MergeDanmakuInMills
-	if mergeMills > 0 {
-		data = danmaku.MergeDanmaku(data, mergeMills, 0)
-	}
+	// filter and merge danmaku
+	data = danmaku.ProcessDanmaku(scraper.Platform(), data, 0)
+	metrics.CommentsReturned.Add(float64(len(data)), platform)
 
 	comment := &CommentResult{
 		Count:    int64(len(data)),
@@ -188,6 +269,16 @@ func (c *realTimeData) GetDanmaku(param CommentParam) (*CommentResult, error) {
 	return comment, nil
 }
 
+func (c *realTimeData) MappingStatus() MappingStatus {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return MappingStatus{
+		Loaded:   c.loaded.Load(),
+		Restored: c.restored.Load(),
+		Size:     len(c.ForwardMap),
+	}
+}
+
 func (c *realTimeData) Mode() Mode {
 	return realTime
 }
@@ -197,7 +288,13 @@ type realTimeData struct {
 	ForwardMap  map[string]int64
 	ReverseMap  map[int64]string
 	IdAllocator int64
+	Titles      map[string]string // platform\x00ssId -> 剧集标题 用于管理接口搜索
+	Bindings    map[string]int64  // 手动绑定 归一化的文件名 -> episodeId
 	lock        sync.RWMutex
+	// 映射数据加载状态
+	loaded, restored atomic.Bool
+	// 合并相同参数的并发弹幕请求
+	commentGroup utils.Group[*CommentResult]
 }
 
 const keySeparator = "\x00"
@@ -238,6 +335,19 @@ func (c *realTimeData) getGlobalID(platform, ssID, epID string) int64 {
 	return newID
 }
 
+func (c *realTimeData) setTitle(platform, ssID, title string) {
+	key := combineKey(platform, ssID, "")
+	c.lock.RLock()
+	exist := c.Titles[key] == title
+	c.lock.RUnlock()
+	if exist {
+		return
+	}
+	c.lock.Lock()
+	c.Titles[key] = title
+	c.lock.Unlock()
+}
+
 func (c *realTimeData) decodeGlobalID(globalID int64) (platform string, ssId, epId string, found bool) {
 	c.lock.RLock()
 	defer c.lock.RUnlock()
@@ -264,6 +374,7 @@ func (c *realTimeData) SearchAnime(title string) *DanDanAnimeResult {
 	anime := make([]AnimeResult, 0, len(media))
 	for _, m := range media {
 		id := c.getGlobalID(string(m.Platform), m.Id, "")
+		c.setTitle(string(m.Platform), m.Id, m.Title)
 		animeTitle := fmt.Sprintf("%s [%s]", m.Title, m.Platform)
 		anime = append(anime, AnimeResult{
 			AnimeId:      id,
@@ -306,6 +417,7 @@ func (c *realTimeData) AnimeInfo(id string) (*DanDanAnimeInfoResult, error) {
 	}
 
 	animeId := c.getGlobalID(string(media.Platform), media.Id, "")
+	c.setTitle(string(media.Platform), media.Id, media.Title)
 	var eps = make([]EpisodeResult, 0, len(media.Episodes))
 	for _, ep := range media.Episodes {
 		eps = append(eps, EpisodeResult{
@@ -0,0 +1,246 @@
+package service
+
+import (
+	"danmaku-tool/internal/danmaku"
+	"fmt"
+	"path"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// MappingAdmin id映射数据管理 用于管理接口
+type MappingAdmin interface {
+	ListMappings(query MappingQuery) ([]*MappingEntry, int)
+	GetMapping(id int64) (*MappingEntry, bool)
+	DeleteMapping(id int64) bool
+	Remap(id int64, platform, ssId, epId string) error
+	Rematch(id int64, title string) ([]*danmaku.Media, error)
+	Bind(fileName string, platform, ssId, epId, title string) (int64, error)
+	Unbind(fileName string) bool
+	ListBindings() []*Binding
+}
+
+type MappingQuery struct {
+	Keyword  string // 匹配标题或者平台id
+	Platform string
+	Offset   int
+	Limit    int
+}
+
+type MappingEntry struct {
+	Id        int64  `json:"id"` // dandan api 使用的 animeId/episodeId
+	Platform  string `json:"platform"`
+	SeasonId  string `json:"seasonId"`
+	EpisodeId string `json:"episodeId"` // 为空则是剧集映射
+	Title     string `json:"title"`
+}
+
+func (c *realTimeData) entry(id int64, key string) *MappingEntry {
+	parts := strings.Split(key, keySeparator)
+	if len(parts) != 3 {
+		return nil
+	}
+	return &MappingEntry{
+		Id:        id,
+		Platform:  parts[0],
+		SeasonId:  parts[1],
+		EpisodeId: parts[2],
+		Title:     c.Titles[combineKey(parts[0], parts[1], "")],
+	}
+}
+
+func (c *realTimeData) ListMappings(query MappingQuery) ([]*MappingEntry, int) {
+	keyword := strings.ToLower(strings.TrimSpace(query.Keyword))
+
+	c.lock.RLock()
+	var result = make([]*MappingEntry, 0, 100)
+	for id, key := range c.ReverseMap {
+		e := c.entry(id, key)
+		if e == nil {
+			continue
+		}
+		if query.Platform != "" && e.Platform != query.Platform {
+			continue
+		}
+		if keyword != "" &&
+			!strings.Contains(strings.ToLower(e.Title), keyword) &&
+			e.SeasonId != keyword && e.EpisodeId != keyword {
+			continue
+		}
+		result = append(result, e)
+	}
+	c.lock.RUnlock()
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Id < result[j].Id
+	})
+	total := len(result)
+	if query.Offset > 0 {
+		result = result[min(query.Offset, total):]
+	}
+	if query.Limit > 0 && len(result) > query.Limit {
+		result = result[:query.Limit]
+	}
+	return result, total
+}
+
+func (c *realTimeData) GetMapping(id int64) (*MappingEntry, bool) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	key, ok := c.ReverseMap[id]
+	if !ok {
+		return nil, false
+	}
+	e := c.entry(id, key)
+	return e, e != nil
+}
+
+func (c *realTimeData) DeleteMapping(id int64) bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	key, ok := c.ReverseMap[id]
+	if !ok {
+		return false
+	}
+	delete(c.ReverseMap, id)
+	// 正向映射可能已经被重新映射到其他id
+	if c.ForwardMap[key] == id {
+		delete(c.ForwardMap, key)
+	}
+	return true
+}
+
+// Remap 将已有的id重新映射到其他平台剧集 客户端已缓存的id无需变更即可获取新的弹幕
+func (c *realTimeData) Remap(id int64, platform, ssId, epId string) error {
+	if danmaku.GetScraper(platform) == nil {
+		return fmt.Errorf("unknown or disabled platform: %s", platform)
+	}
+	if ssId == "" && epId == "" {
+		return fmt.Errorf("seasonId or episodeId is required")
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	old, ok := c.ReverseMap[id]
+	if !ok {
+		return fmt.Errorf("mapping %d not found", id)
+	}
+	if c.ForwardMap[old] == id {
+		delete(c.ForwardMap, old)
+	}
+	key := combineKey(platform, ssId, epId)
+	c.ReverseMap[id] = key
+	c.ForwardMap[key] = id
+	return nil
+}
+
+// Rematch 使用标题重新搜索 返回候选剧集 title为空则使用映射记录的标题
+func (c *realTimeData) Rematch(id int64, title string) ([]*danmaku.Media, error) {
+	if title == "" {
+		e, ok := c.GetMapping(id)
+		if !ok {
+			return nil, fmt.Errorf("mapping %d not found", id)
+		}
+		title = e.Title
+	}
+	if title == "" {
+		return nil, fmt.Errorf("title is required")
+	}
+	media := danmaku.MatchMedia(danmaku.MatchParam{
+		Title:    title,
+		Mode:     danmaku.Search,
+		SeasonId: -1,
+	})
+	for _, m := range media {
+		c.setTitle(string(m.Platform), m.Id, m.Title)
+	}
+	return media, nil
+}
+
+type Binding struct {
+	FileName string        `json:"fileName"`
+	Mapping  *MappingEntry `json:"mapping"`
+}
+
+var videoExts = []string{".mkv", ".mp4", ".avi", ".ts", ".m2ts", ".flv", ".rmvb", ".webm", ".mov", ".wmv"}
+
+// normalizeFileName 绑定使用的文件名 忽略大小写以及视频后缀
+func normalizeFileName(fileName string) string {
+	name := strings.ToLower(strings.TrimSpace(fileName))
+	if slices.Contains(videoExts, path.Ext(name)) {
+		name = strings.TrimSuffix(name, path.Ext(name))
+	}
+	return name
+}
+
+// Bind 将文件名手动绑定到平台单集 dandan api match 时优先使用
+func (c *realTimeData) Bind(fileName string, platform, ssId, epId, title string) (int64, error) {
+	name := normalizeFileName(fileName)
+	if name == "" {
+		return 0, fmt.Errorf("fileName is required")
+	}
+	if danmaku.GetScraper(platform) == nil {
+		return 0, fmt.Errorf("unknown or disabled platform: %s", platform)
+	}
+	if epId == "" {
+		return 0, fmt.Errorf("episodeId is required")
+	}
+	id := c.getGlobalID(platform, ssId, epId)
+	if title != "" {
+		c.setTitle(platform, ssId, title)
+	}
+	c.lock.Lock()
+	c.Bindings[name] = id
+	c.lock.Unlock()
+	return id, nil
+}
+
+func (c *realTimeData) Unbind(fileName string) bool {
+	name := normalizeFileName(fileName)
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if _, ok := c.Bindings[name]; !ok {
+		return false
+	}
+	delete(c.Bindings, name)
+	return true
+}
+
+func (c *realTimeData) ListBindings() []*Binding {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	var result = make([]*Binding, 0, len(c.Bindings))
+	for name, id := range c.Bindings {
+		b := &Binding{FileName: name}
+		if key, ok := c.ReverseMap[id]; ok {
+			b.Mapping = c.entry(id, key)
+		}
+		result = append(result, b)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].FileName < result[j].FileName
+	})
+	return result
+}
+
+func (c *realTimeData) bindingMatch(fileName string) (*Match, bool) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	id, ok := c.Bindings[normalizeFileName(fileName)]
+	if !ok {
+		return nil, false
+	}
+	key, ok := c.ReverseMap[id]
+	if !ok {
+		return nil, false
+	}
+	e := c.entry(id, key)
+	if e == nil {
+		return nil, false
+	}
+	return &Match{
+		EpisodeId:    id,
+		AnimeTitle:   e.Title + " [" + e.Platform + "]",
+		EpisodeTitle: e.EpisodeId,
+	}, true
+}
@@ -0,0 +1,44 @@
+package subscribe
+
+import (
+	"danmaku-tool/internal/config"
+	"danmaku-tool/internal/danmaku"
+	"danmaku-tool/internal/utils"
+	"time"
+)
+
+func init() {
+	danmaku.RegisterInitializer(&watcher{})
+}
+
+// watcher server模式后台检查订阅 开关以及间隔修改需要重启
+type watcher struct{}
+
+func (w *watcher) ServerInit() error {
+	conf := config.GetConfig().Watch
+	if !conf.Enable {
+		return nil
+	}
+	interval := Interval(conf)
+	go Watch(NewWatchlist(DefaultPath()), interval, nil)
+	utils.InfoLog(subscribeC, "watch started", "interval", interval.String())
+	return nil
+}
+
+// Watch 立即检查一次 之后定时检查 stop 关闭后退出
+func Watch(list *Watchlist, interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if count, err := list.Check(); err != nil {
+			utils.ErrorLog(subscribeC, err.Error())
+		} else {
+			utils.InfoLog(subscribeC, "watch check done", "new", count, "next", time.Now().Add(interval).Format(time.DateTime))
+		}
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,206 @@
+package subscribe
+
+import (
+	"context"
+	"danmaku-tool/internal/config"
+	"danmaku-tool/internal/danmaku"
+	"danmaku-tool/internal/utils"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+)
+
+/*
+	剧集订阅 定时获取剧集信息 发现新的单集后抓取弹幕并保存 已经抓取过的单集不会重复抓取
+	订阅列表以json格式保存在配置文件目录 watch命令或者server后台任务共用
+	弹幕文件与scrape命令抓取单集保存的一致
+*/
+
+const subscribeC = "subscribe"
+
+const (
+	fileName              = "watchlist.json"
+	defaultWatchIntervalM = 60 // 分钟
+)
+
+// Subscription 订阅的剧集
+type Subscription struct {
+	Platform  string    `json:"platform"`
+	Id        string    `json:"id"` // 剧集id 同 MediaService.Media
+	Title     string    `json:"title"`
+	AddedAt   time.Time `json:"addedAt"`
+	LastCheck time.Time `json:"lastCheck"`
+	Fetched   []string  `json:"fetched"` // 已经抓取的单集id
+}
+
+func (s *Subscription) match(platform, id string) bool {
+	return s.Platform == platform && s.Id == id
+}
+
+// Watchlist 订阅列表 每次操作都会重新读取文件 避免覆盖其他进程的修改
+type Watchlist struct {
+	path string
+	lock sync.Mutex
+}
+
+// DefaultPath 配置文件目录下的 watchlist.json
+func DefaultPath() string {
+	return filepath.Join(filepath.Dir(config.ConfPath), fileName)
+}
+
+// Interval 检查间隔
+func Interval(conf config.WatchConfig) time.Duration {
+	interval := conf.Interval
+	if interval <= 0 {
+		interval = defaultWatchIntervalM
+	}
+	return time.Duration(interval) * time.Minute
+}
+
+func NewWatchlist(path string) *Watchlist {
+	return &Watchlist{path: path}
+}
+
+// List 所有订阅
+func (w *Watchlist) List() ([]*Subscription, error) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	return w.read()
+}
+
+func (w *Watchlist) read() ([]*Subscription, error) {
+	file, err := os.ReadFile(w.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var subs []*Subscription
+	if err = json.Unmarshal(file, &subs); err != nil {
+		return nil, fmt.Errorf("invalid watchlist %s: %w", w.path, err)
+	}
+	return subs, nil
+}
+
+func (w *Watchlist) write(subs []*Subscription) error {
+	data, err := json.MarshalIndent(subs, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := w.path + ".tmp"
+	if err = os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, w.path)
+}
+
+// Add 添加订阅 已经订阅则返回错误
+func (w *Watchlist) Add(sub *Subscription) error {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	subs, err := w.read()
+	if err != nil {
+		return err
+	}
+	if slices.ContainsFunc(subs, func(s *Subscription) bool { return s.match(sub.Platform, sub.Id) }) {
+		return fmt.Errorf("%s %s already subscribed", sub.Platform, sub.Id)
+	}
+	return w.write(append(subs, sub))
+}
+
+// Remove 取消订阅 返回是否存在
+func (w *Watchlist) Remove(platform, id string) (bool, error) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	subs, err := w.read()
+	if err != nil {
+		return false, err
+	}
+	size := len(subs)
+	subs = slices.DeleteFunc(subs, func(s *Subscription) bool { return s.match(platform, id) })
+	if len(subs) == size {
+		return false, nil
+	}
+	return true, w.write(subs)
+}
+
+// update 更新单个订阅 检查期间被取消的订阅不再写入
+func (w *Watchlist) update(sub *Subscription) error {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	subs, err := w.read()
+	if err != nil {
+		return err
+	}
+	index := slices.IndexFunc(subs, func(s *Subscription) bool { return s.match(sub.Platform, sub.Id) })
+	if index < 0 {
+		return nil
+	}
+	subs[index] = sub
+	return w.write(subs)
+}
+
+// Check 检查所有订阅 返回新抓取的单集数量
+func (w *Watchlist) Check() (int, error) {
+	subs, err := w.List()
+	if err != nil {
+		return 0, err
+	}
+	var total int
+	for _, sub := range subs {
+		count, e := w.check(sub)
+		if e != nil {
+			utils.ErrorLog(subscribeC, e.Error(), "platform", sub.Platform, "id", sub.Id)
+			continue
+		}
+		total += count
+	}
+	return total, nil
+}
+
+func (w *Watchlist) check(sub *Subscription) (int, error) {
+	media, err := FetchMedia(sub.Platform, sub.Id)
+	if err != nil {
+		return 0, err
+	}
+	scraper := danmaku.GetScraper(sub.Platform)
+	if scraper == nil {
+		return 0, fmt.Errorf("platform %s is not available", sub.Platform)
+	}
+
+	var count int
+	for _, ep := range media.Episodes {
+		if slices.Contains(sub.Fetched, ep.Id) {
+			continue
+		}
+		// 与scrape命令保存的文件一致
+		files, size, e := danmaku.ScrapeEpisode(context.Background(), scraper, media, ep)
+		if e != nil {
+			// 未抓取成功 下次检查时重试
+			utils.WarnLog(subscribeC, "episode scrape failed", "platform", sub.Platform, "id", sub.Id, "ep", ep.Id, "error", e)
+			continue
+		}
+		sub.Fetched = append(sub.Fetched, ep.Id)
+		count++
+		utils.InfoLog(subscribeC, "new episode scraped", "title", media.Title, "ep", ep.EpisodeId, "size", size, "files", len(files))
+	}
+	sub.Title = media.Title
+	sub.LastCheck = time.Now()
+	utils.InfoLog(subscribeC, "subscription checked", "platform", sub.Platform, "title", sub.Title, "new", count)
+	return count, w.update(sub)
+}
+
+// FetchMedia 获取剧集以及单集信息
+func FetchMedia(platform, id string) (*danmaku.Media, error) {
+	service := danmaku.GetMediaService(platform)
+	if service == nil {
+		return nil, fmt.Errorf("platform %s is not available or does not support media info", platform)
+	}
+	return service.Media(id)
+}
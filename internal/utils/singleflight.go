@@ -0,0 +1,46 @@
+package utils
+
+import (
+	"errors"
+	"sync"
+)
+
+// Group 合并并发的相同请求 同一个key同时只会执行一次 其余调用等待并共享结果
+// 共享的结果不应该被调用方修改
+type Group[T any] struct {
+	lock  sync.Mutex
+	calls map[string]*call[T]
+}
+
+type call[T any] struct {
+	wg  sync.WaitGroup
+	val T
+	err error
+}
+
+// Do 执行fn shared 表示结果是否来自其他调用
+func (g *Group[T]) Do(key string, fn func() (T, error)) (val T, err error, shared bool) {
+	g.lock.Lock()
+	if g.calls == nil {
+		g.calls = map[string]*call[T]{}
+	}
+	if c, ok := g.calls[key]; ok {
+		g.lock.Unlock()
+		c.wg.Wait()
+		return c.val, c.err, true
+	}
+	// fn panic时等待的调用会得到该错误
+	c := &call[T]{err: errors.New("request aborted")}
+	c.wg.Add(1)
+	g.calls[key] = c
+	g.lock.Unlock()
+
+	defer func() {
+		g.lock.Lock()
+		delete(g.calls, key)
+		g.lock.Unlock()
+		c.wg.Done()
+	}()
+	c.val, c.err = fn()
+	return c.val, c.err, false
+}
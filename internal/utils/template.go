@@ -0,0 +1,145 @@
+package utils
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+/*
+	文件保存路径模板 {name} 或者 {name:02} 数字补零 / 分隔目录
+	字段值中的路径分隔符以及文件系统不允许的字符会被替换 空值留下的空括号以及多余的空格会被清理
+*/
+
+const maxSegmentBytes = 200 // 预留文件后缀
+
+// PathTemplate 解析后的路径模板
+type PathTemplate struct {
+	parts  []templatePart
+	fields []string
+}
+
+type templatePart struct {
+	literal string
+	field   string
+	width   int // 数字补零宽度
+}
+
+// ParsePathTemplate 解析模板 fields 为支持的字段
+func ParsePathTemplate(tpl string, fields []string) (*PathTemplate, error) {
+	t := &PathTemplate{}
+	rest := tpl
+	for rest != "" {
+		start := strings.IndexByte(rest, '{')
+		if start < 0 {
+			t.parts = append(t.parts, templatePart{literal: rest})
+			break
+		}
+		if start > 0 {
+			t.parts = append(t.parts, templatePart{literal: rest[:start]})
+		}
+		end := strings.IndexByte(rest[start:], '}')
+		if end < 0 {
+			return nil, fmt.Errorf("unclosed placeholder: %s", rest[start:])
+		}
+		field, format, _ := strings.Cut(rest[start+1:start+end], ":")
+		if !slices.Contains(fields, field) {
+			return nil, fmt.Errorf("unknown placeholder {%s}, supported: %s", field, strings.Join(fields, " "))
+		}
+		part := templatePart{field: field}
+		if format != "" {
+			width, err := strconv.Atoi(format)
+			if err != nil || width <= 0 || width > 9 {
+				return nil, fmt.Errorf("invalid format of {%s}: %s", field, format)
+			}
+			part.width = width
+		}
+		t.parts = append(t.parts, part)
+		t.fields = append(t.fields, field)
+		rest = rest[start+end+1:]
+	}
+	return t, nil
+}
+
+// Uses 模板是否使用了任一字段
+func (t *PathTemplate) Uses(fields ...string) bool {
+	for _, f := range fields {
+		if slices.Contains(t.fields, f) {
+			return true
+		}
+	}
+	return false
+}
+
+// Render 渲染相对路径 值为 string 或者 int 数字0在未指定补零时为空 文件名为空时返回错误
+func (t *PathTemplate) Render(values map[string]any) (string, error) {
+	var b strings.Builder
+	for _, p := range t.parts {
+		if p.field == "" {
+			b.WriteString(p.literal)
+			continue
+		}
+		switch v := values[p.field].(type) {
+		case int:
+			if p.width > 0 {
+				b.WriteString(fmt.Sprintf("%0*d", p.width, v))
+			} else if v != 0 {
+				b.WriteString(strconv.Itoa(v))
+			}
+		case string:
+			b.WriteString(SanitizeFilename(v))
+		}
+	}
+	var segments []string
+	for _, s := range strings.Split(strings.ReplaceAll(b.String(), "\\", "/"), "/") {
+		if s = cleanSegment(s); s != "" {
+			segments = append(segments, s)
+		}
+	}
+	if len(segments) == 0 {
+		return "", fmt.Errorf("empty path")
+	}
+	return strings.Join(segments, "/"), nil
+}
+
+var emptyBrackets = strings.NewReplacer("()", "", "[]", "", "（）", "", "【】", "")
+
+// cleanSegment 清理空值留下的空括号以及首尾的空格、点和连接符
+func cleanSegment(s string) string {
+	s = emptyBrackets.Replace(s)
+	s = strings.Join(strings.Fields(s), " ")
+	s = strings.Trim(s, " .-")
+	if s == "" {
+		return ""
+	}
+	if len(s) > maxSegmentBytes {
+		cut := maxSegmentBytes
+		for cut > 0 && !utf8.RuneStart(s[cut]) {
+			cut--
+		}
+		s = strings.TrimRight(s[:cut], " .")
+	}
+	return s
+}
+
+var reservedNames = []string{"CON", "PRN", "AUX", "NUL", "COM1", "COM2", "COM3", "COM4", "COM5", "COM6", "COM7",
+	"COM8", "COM9", "LPT1", "LPT2", "LPT3", "LPT4", "LPT5", "LPT6", "LPT7", "LPT8", "LPT9"}
+
+// SanitizeFilename 替换文件名中的路径分隔符、控制字符以及 windows 不允许的字符
+func SanitizeFilename(s string) string {
+	s = strings.Map(func(r rune) rune {
+		switch {
+		case r < 0x20 || r == 0x7f:
+			return -1
+		case strings.ContainsRune(`<>:"/\|?*`, r):
+			return '_'
+		}
+		return r
+	}, s)
+	if slices.Contains(reservedNames, strings.ToUpper(strings.TrimSpace(s))) {
+		s += "_"
+	}
+	return s
+}
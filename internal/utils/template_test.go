@@ -0,0 +1,113 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+)
+
+var testFields = []string{"platform", "title", "year", "season", "ep", "ep_title", "media_id", "ep_id"}
+
+func TestParsePathTemplate(t *testing.T) {
+	tests := []struct {
+		tpl     string
+		wantErr bool
+	}{
+		{tpl: "{platform}/{media_id}/{ep_id}"},
+		{tpl: "{title} ({year})/Season {season:02}/{title} S{season:02}E{ep:02}"},
+		{tpl: "plain"},
+		{tpl: "{unknown}", wantErr: true},
+		{tpl: "{title", wantErr: true},
+		{tpl: "{ep:x}", wantErr: true},
+		{tpl: "{ep:0}", wantErr: true},
+		{tpl: "{ep:10}", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.tpl, func(t *testing.T) {
+			_, err := ParsePathTemplate(tt.tpl, testFields)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestPathTemplateRender(t *testing.T) {
+	values := map[string]any{
+		"platform": "bilibili",
+		"title":    "孤独摇滚！",
+		"year":     2022,
+		"season":   1,
+		"ep":       3,
+		"ep_title": "",
+		"media_id": "39462",
+		"ep_id":    "691614",
+	}
+	tests := []struct {
+		name    string
+		tpl     string
+		values  map[string]any
+		want    string
+		wantErr bool
+	}{
+		{name: "default layout", tpl: "{platform}/{media_id}/{ep_id}", want: "bilibili/39462/691614"},
+		{name: "padding", tpl: "{title} ({year})/Season {season:02}/{title} S{season:02}E{ep:03}", want: "孤独摇滚！ (2022)/Season 01/孤独摇滚！ S01E003"},
+		{name: "empty value cleaned", tpl: "{title} - {ep_title}", want: "孤独摇滚！"},
+		{name: "empty brackets removed", tpl: "{title} [{ep_title}]", want: "孤独摇滚！"},
+		{name: "zero without padding", tpl: "{title} {ep}", values: map[string]any{"title": "a", "ep": 0}, want: "a"},
+		{name: "zero with padding", tpl: "E{ep:02}", values: map[string]any{"ep": 0}, want: "E00"},
+		{name: "separator in value", tpl: "{title}/{ep_id}", values: map[string]any{"title": "AC/DC: Live?", "ep_id": "1"}, want: "AC_DC_ Live_/1"},
+		{name: "traversal in value", tpl: "{title}/{ep_id}", values: map[string]any{"title": "..", "ep_id": "../../etc"}, want: "_.._etc"},
+		{name: "traversal in template", tpl: "../{ep_id}", values: map[string]any{"ep_id": "1"}, want: "1"},
+		{name: "backslash in template", tpl: `{platform}\{ep_id}`, want: "bilibili/691614"},
+		{name: "reserved name", tpl: "{title}", values: map[string]any{"title": "con"}, want: "con_"},
+		{name: "control chars", tpl: "{title}", values: map[string]any{"title": "a\tb\x00c"}, want: "abc"},
+		{name: "empty path", tpl: "{ep_title}", wantErr: true},
+		{name: "missing value", tpl: "{title}/{ep_title}", values: map[string]any{}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tpl, err := ParsePathTemplate(tt.tpl, testFields)
+			if err != nil {
+				t.Fatal(err)
+			}
+			v := tt.values
+			if v == nil {
+				v = values
+			}
+			got, err := tpl.Render(v)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPathTemplateLongSegment(t *testing.T) {
+	tpl, err := ParsePathTemplate("{title}", testFields)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := tpl.Render(map[string]any{"title": strings.Repeat("弹", 100)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) > maxSegmentBytes {
+		t.Errorf("segment length %d exceeds %d", len(got), maxSegmentBytes)
+	}
+	if !strings.HasPrefix(strings.Repeat("弹", 100), got) {
+		t.Errorf("segment cut in the middle of a rune: %q", got)
+	}
+}
+
+func TestPathTemplateUses(t *testing.T) {
+	tpl, err := ParsePathTemplate("{title}/{ep:02}", testFields)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !tpl.Uses("ep") || !tpl.Uses("year", "title") || tpl.Uses("year", "season") {
+		t.Errorf("Uses returned unexpected result for fields %v", tpl.fields)
+	}
+}